@@ -0,0 +1,502 @@
+package generator
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// FieldOption customises a field created by a MessageBuilder.
+type FieldOption func(*descriptorpb.FieldDescriptorProto)
+
+// WithNumber sets the number of a field, instead of the next one.
+func WithNumber(number int32) FieldOption {
+	return func(field *descriptorpb.FieldDescriptorProto) {
+		field.Number = proto.Int32(number)
+	}
+}
+
+// WithJSONName sets a custom json_name on a field.
+func WithJSONName(name string) FieldOption {
+	return func(field *descriptorpb.FieldDescriptorProto) {
+		field.JsonName = proto.String(name)
+	}
+}
+
+// WithDefault sets the default_value of a proto2 field.
+func WithDefault(value string) FieldOption {
+	return func(field *descriptorpb.FieldDescriptorProto) {
+		field.DefaultValue = proto.String(value)
+	}
+}
+
+// WithType sets the type of a field. References resolved to a message
+// or enum of the file have their type set automatically, this is only
+// needed for enums declared elsewhere and for groups.
+func WithType(t descriptorpb.FieldDescriptorProto_Type) FieldOption {
+	return func(field *descriptorpb.FieldDescriptorProto) {
+		field.Type = t.Enum()
+	}
+}
+
+// WithFieldOptions sets the options of a field.
+func WithFieldOptions(opts *descriptorpb.FieldOptions) FieldOption {
+	return func(field *descriptorpb.FieldDescriptorProto) {
+		field.Options = opts
+	}
+}
+
+// pendingRef is a type name resolved when the file is built. Scopes
+// are relative to the package, known only then.
+type pendingRef struct {
+	target *string
+	field  *descriptorpb.FieldDescriptorProto
+	scope  string
+	// local is set for names of the file already qualified within the
+	// package, which only need the package prepended.
+	local bool
+}
+
+// FileBuilder builds a FileDescriptorProto. Type names are resolved
+// following protoc's scoping rules when Build is called, and names not
+// declared in the file are taken as fully-qualified.
+type FileBuilder struct {
+	file     *descriptorpb.FileDescriptorProto
+	messages []*MessageBuilder
+	refs     []pendingRef
+}
+
+// File starts building a proto3 file.
+func File(name string) *FileBuilder {
+	return &FileBuilder{
+		file: &descriptorpb.FileDescriptorProto{
+			Name:   proto.String(name),
+			Syntax: proto.String("proto3"),
+		},
+	}
+}
+
+// Package sets the package of the file. Type names are qualified with
+// it when the file is built, so it can be set after adding types.
+func (b *FileBuilder) Package(pkg string) *FileBuilder {
+	b.file.Package = proto.String(pkg)
+	return b
+}
+
+// Syntax sets the syntax of the file, "proto2" or "proto3".
+func (b *FileBuilder) Syntax(syntax string) *FileBuilder {
+	b.file.Syntax = proto.String(syntax)
+	b.file.Edition = nil
+	return b
+}
+
+// Edition makes the file use the given edition.
+func (b *FileBuilder) Edition(edition descriptorpb.Edition) *FileBuilder {
+	b.file.Syntax = proto.String("editions")
+	b.file.Edition = edition.Enum()
+	return b
+}
+
+// Import adds dependencies to the file.
+func (b *FileBuilder) Import(names ...string) *FileBuilder {
+	b.file.Dependency = append(b.file.Dependency, names...)
+	return b
+}
+
+// PublicImport adds public dependencies to the file.
+func (b *FileBuilder) PublicImport(names ...string) *FileBuilder {
+	for _, name := range names {
+		b.file.PublicDependency = append(b.file.PublicDependency, int32(len(b.file.Dependency)))
+		b.file.Dependency = append(b.file.Dependency, name)
+	}
+	return b
+}
+
+// Options sets the options of the file.
+func (b *FileBuilder) Options(opts *descriptorpb.FileOptions) *FileBuilder {
+	b.file.Options = opts
+	return b
+}
+
+// Message adds a top-level message, calling the given functions to
+// populate it, and returns its builder.
+func (b *FileBuilder) Message(name string, fns ...func(*MessageBuilder)) *MessageBuilder {
+	msg := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+	b.file.MessageType = append(b.file.MessageType, msg)
+	return b.newMessage(msg, nil, name, fns)
+}
+
+// Enum adds a top-level enum with values numbered from 0.
+func (b *FileBuilder) Enum(name string, values ...string) *FileBuilder {
+	b.file.EnumType = append(b.file.EnumType, NewEnum(name, values...))
+	return b
+}
+
+// Service adds a service, calling the given functions to populate it,
+// and returns its builder.
+func (b *FileBuilder) Service(name string, fns ...func(*ServiceBuilder)) *ServiceBuilder {
+	svc := &descriptorpb.ServiceDescriptorProto{Name: proto.String(name)}
+	b.file.Service = append(b.file.Service, svc)
+
+	sb := &ServiceBuilder{svc: svc, file: b}
+	for _, fn := range fns {
+		fn(sb)
+	}
+	return sb
+}
+
+func (b *FileBuilder) newMessage(msg *descriptorpb.DescriptorProto, parent *MessageBuilder,
+	full string, fns []func(*MessageBuilder)) *MessageBuilder {
+	mb := &MessageBuilder{msg: msg, file: b, parent: parent, name: full}
+	b.messages = append(b.messages, mb)
+	for _, fn := range fns {
+		fn(mb)
+	}
+	return mb
+}
+
+func (b *FileBuilder) addRef(target *string, field *descriptorpb.FieldDescriptorProto, scope string) {
+	b.refs = append(b.refs, pendingRef{target: target, field: field, scope: scope})
+}
+
+func (b *FileBuilder) addLocalRef(target *string) {
+	b.refs = append(b.refs, pendingRef{target: target, local: true})
+}
+
+// Build completes the file, declaring the synthetic oneofs of proto3
+// optional fields after the real ones and resolving type names within
+// the package set at this point.
+func (b *FileBuilder) Build() *descriptorpb.FileDescriptorProto {
+	for _, mb := range b.messages {
+		mb.addSyntheticOneofs()
+	}
+
+	r := NewRegistry(b.file)
+	pkg := b.file.GetPackage()
+	for _, ref := range b.refs {
+		if ref.local {
+			*ref.target = "." + joinName(pkg, *ref.target)
+			continue
+		}
+		ref.scope = joinName(pkg, ref.scope)
+		b.resolve(r, ref)
+	}
+	b.refs = nil
+	return b.file
+}
+
+func (b *FileBuilder) resolve(r *Registry, ref pendingRef) {
+	name := *ref.target
+	if strings.HasPrefix(name, ".") {
+		return
+	}
+
+	full := "." + name
+	for scope := ref.scope; ; scope = parentScope(scope) {
+		candidate := joinName(scope, name)
+		_, isMsg := r.Message(candidate)
+		_, isEnum := r.Enum(candidate)
+		if isMsg || isEnum {
+			full = "." + candidate
+			setRefType(ref.field, isEnum)
+			break
+		}
+		if scope == "" {
+			break
+		}
+	}
+	*ref.target = full
+}
+
+func setRefType(field *descriptorpb.FieldDescriptorProto, isEnum bool) {
+	switch {
+	case field == nil:
+	case isEnum:
+		field.Type = TypeEnum.Enum()
+	case field.GetType() != TypeGroup:
+		field.Type = TypeMessage.Enum()
+	}
+}
+
+// MessageBuilder builds a message of a file. Fields are numbered
+// after the highest number used so far, skipping reserved numbers,
+// unless WithNumber is given.
+type MessageBuilder struct {
+	msg       *descriptorpb.DescriptorProto
+	file      *FileBuilder
+	parent    *MessageBuilder
+	oneof     *int32
+	name      string // within the package
+	synthetic []*descriptorpb.FieldDescriptorProto
+	reserved  map[int32]bool
+	next      int32
+}
+
+// File returns the builder of the file declaring the message.
+func (mb *MessageBuilder) File() *FileBuilder {
+	return mb.file
+}
+
+// Parent returns the builder of the enclosing message, nil for
+// top-level messages.
+func (mb *MessageBuilder) Parent() *MessageBuilder {
+	return mb.parent
+}
+
+// Build completes and returns the file declaring the message.
+func (mb *MessageBuilder) Build() *descriptorpb.FileDescriptorProto {
+	return mb.file.Build()
+}
+
+// Options sets the options of the message.
+func (mb *MessageBuilder) Options(opts *descriptorpb.MessageOptions) *MessageBuilder {
+	mb.msg.Options = opts
+	return mb
+}
+
+// Field adds a singular field of a scalar type.
+func (mb *MessageBuilder) Field(name string, t descriptorpb.FieldDescriptorProto_Type,
+	opts ...FieldOption) *MessageBuilder {
+	mb.addField(name, LabelOptional, t, opts)
+	return mb
+}
+
+// Repeated adds a repeated field of a scalar type.
+func (mb *MessageBuilder) Repeated(name string, t descriptorpb.FieldDescriptorProto_Type,
+	opts ...FieldOption) *MessageBuilder {
+	mb.addField(name, LabelRepeated, t, opts)
+	return mb
+}
+
+// Required adds a proto2 required field of a scalar type.
+func (mb *MessageBuilder) Required(name string, t descriptorpb.FieldDescriptorProto_Type,
+	opts ...FieldOption) *MessageBuilder {
+	mb.addField(name, LabelRequired, t, opts)
+	return mb
+}
+
+// Optional adds a field with explicit presence. In proto3 files it
+// gets a synthetic oneof.
+func (mb *MessageBuilder) Optional(name string, t descriptorpb.FieldDescriptorProto_Type,
+	opts ...FieldOption) *MessageBuilder {
+	field := mb.addField(name, LabelOptional, t, opts)
+	if mb.file.file.GetSyntax() == "proto3" && field.OneofIndex == nil {
+		field.Proto3Optional = proto.Bool(true)
+		mb.synthetic = append(mb.synthetic, field)
+	}
+	return mb
+}
+
+// Ref adds a singular field referring to a message or enum by name.
+func (mb *MessageBuilder) Ref(name, typeName string, opts ...FieldOption) *MessageBuilder {
+	mb.addRefField(name, LabelOptional, typeName, opts)
+	return mb
+}
+
+// RepeatedRef adds a repeated field referring to a message or enum by name.
+func (mb *MessageBuilder) RepeatedRef(name, typeName string, opts ...FieldOption) *MessageBuilder {
+	mb.addRefField(name, LabelRepeated, typeName, opts)
+	return mb
+}
+
+// Map adds a map field with scalar values, and its map entry message.
+func (mb *MessageBuilder) Map(name string, key, value descriptorpb.FieldDescriptorProto_Type,
+	opts ...FieldOption) *MessageBuilder {
+	entry := mb.addMapEntry(name, key)
+	entry.Field = append(entry.Field, newBuilderField("value", 2, LabelOptional, value))
+	mb.addMapField(name, entry, opts)
+	return mb
+}
+
+// MapRef adds a map field with message or enum values, and its map
+// entry message.
+func (mb *MessageBuilder) MapRef(name string, key descriptorpb.FieldDescriptorProto_Type, valueTypeName string,
+	opts ...FieldOption) *MessageBuilder {
+	entry := mb.addMapEntry(name, key)
+	value := newBuilderField("value", 2, LabelOptional, TypeMessage)
+	value.TypeName = proto.String(valueTypeName)
+	entry.Field = append(entry.Field, value)
+	mb.file.addRef(value.TypeName, value, mb.name)
+	mb.addMapField(name, entry, opts)
+	return mb
+}
+
+// Oneof declares a oneof, calling fn to add its fields.
+func (mb *MessageBuilder) Oneof(name string, fn func(*MessageBuilder)) *MessageBuilder {
+	index := int32(len(mb.msg.OneofDecl))
+	mb.msg.OneofDecl = append(mb.msg.OneofDecl, NewOneOf(name))
+
+	saved := mb.oneof
+	mb.oneof = &index
+	fn(mb)
+	mb.oneof = saved
+	return mb
+}
+
+// Message adds a nested message, calling the given functions to
+// populate it, and returns its builder.
+func (mb *MessageBuilder) Message(name string, fns ...func(*MessageBuilder)) *MessageBuilder {
+	msg := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+	mb.msg.NestedType = append(mb.msg.NestedType, msg)
+	return mb.file.newMessage(msg, mb, joinName(mb.name, name), fns)
+}
+
+// Enum adds a nested enum with values numbered from 0.
+func (mb *MessageBuilder) Enum(name string, values ...string) *MessageBuilder {
+	mb.msg.EnumType = append(mb.msg.EnumType, NewEnum(name, values...))
+	return mb
+}
+
+// Reserved reserves field numbers, which aren't assigned automatically.
+func (mb *MessageBuilder) Reserved(numbers ...int32) *MessageBuilder {
+	for _, n := range numbers {
+		mb.msg.ReservedRange = append(mb.msg.ReservedRange, &descriptorpb.DescriptorProto_ReservedRange{
+			Start: proto.Int32(n),
+			End:   proto.Int32(n + 1),
+		})
+		if mb.reserved == nil {
+			mb.reserved = make(map[int32]bool)
+		}
+		mb.reserved[n] = true
+	}
+	return mb
+}
+
+func (mb *MessageBuilder) addField(name string, label descriptorpb.FieldDescriptorProto_Label,
+	t descriptorpb.FieldDescriptorProto_Type, opts []FieldOption) *descriptorpb.FieldDescriptorProto {
+	field := newBuilderField(name, mb.nextNumber(), label, t)
+	if mb.oneof != nil {
+		field.OneofIndex = proto.Int32(*mb.oneof)
+	}
+	for _, opt := range opts {
+		opt(field)
+	}
+
+	mb.next = max(mb.next, field.GetNumber())
+	mb.msg.Field = append(mb.msg.Field, field)
+	return field
+}
+
+func (mb *MessageBuilder) addRefField(name string, label descriptorpb.FieldDescriptorProto_Label,
+	typeName string, opts []FieldOption) {
+	field := mb.addField(name, label, TypeMessage, nil)
+	field.TypeName = proto.String(typeName)
+	for _, opt := range opts {
+		opt(field)
+	}
+	mb.next = max(mb.next, field.GetNumber())
+	mb.file.addRef(field.TypeName, field, mb.name)
+}
+
+// nextNumber returns the first number after the highest one used
+// that isn't reserved.
+func (mb *MessageBuilder) nextNumber() int32 {
+	n := mb.next + 1
+	for mb.reserved[n] {
+		n++
+	}
+	return n
+}
+
+func (mb *MessageBuilder) addMapEntry(name string,
+	key descriptorpb.FieldDescriptorProto_Type) *descriptorpb.DescriptorProto {
+	entry := &descriptorpb.DescriptorProto{
+		Name:    proto.String(MapEntryName(name)),
+		Field:   []*descriptorpb.FieldDescriptorProto{newBuilderField("key", 1, LabelOptional, key)},
+		Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+	}
+	mb.msg.NestedType = append(mb.msg.NestedType, entry)
+	return entry
+}
+
+func (mb *MessageBuilder) addMapField(name string, entry *descriptorpb.DescriptorProto, opts []FieldOption) {
+	field := mb.addField(name, LabelRepeated, TypeMessage, opts)
+	field.TypeName = proto.String(joinName(mb.name, entry.GetName()))
+	mb.file.addLocalRef(field.TypeName)
+}
+
+// addSyntheticOneofs declares the oneofs of proto3 optional fields.
+func (mb *MessageBuilder) addSyntheticOneofs() {
+	for _, field := range mb.synthetic {
+		field.OneofIndex = proto.Int32(int32(len(mb.msg.OneofDecl)))
+		mb.msg.OneofDecl = append(mb.msg.OneofDecl, NewOneOf("_"+field.GetName()))
+	}
+	mb.synthetic = nil
+}
+
+func newBuilderField(name string, number int32, label descriptorpb.FieldDescriptorProto_Label,
+	t descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    label.Enum(),
+		Type:     t.Enum(),
+		JsonName: proto.String(JSONName(name)),
+	}
+}
+
+// MapEntryName returns the name protoc gives to the entry message of
+// a map field, its name in CamelCase followed by "Entry".
+func MapEntryName(field string) string {
+	var sb strings.Builder
+	upper := true
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		switch {
+		case c == '_':
+			upper = true
+		case upper && 'a' <= c && c <= 'z':
+			_ = sb.WriteByte(c - 'a' + 'A')
+			upper = false
+		default:
+			_ = sb.WriteByte(c)
+			upper = false
+		}
+	}
+	return sb.String() + "Entry"
+}
+
+// ServiceBuilder builds a service of a file.
+type ServiceBuilder struct {
+	svc  *descriptorpb.ServiceDescriptorProto
+	file *FileBuilder
+}
+
+// File returns the builder of the file declaring the service.
+func (sb *ServiceBuilder) File() *FileBuilder {
+	return sb.file
+}
+
+// Build completes and returns the file declaring the service.
+func (sb *ServiceBuilder) Build() *descriptorpb.FileDescriptorProto {
+	return sb.file.Build()
+}
+
+// Method adds a unary method with the given input and output types.
+func (sb *ServiceBuilder) Method(name, input, output string) *ServiceBuilder {
+	sb.addMethod(name, input, output)
+	return sb
+}
+
+// StreamingMethod adds a method streaming on the client side, the
+// server side, or both.
+func (sb *ServiceBuilder) StreamingMethod(name, input, output string, client, server bool) *ServiceBuilder {
+	m := sb.addMethod(name, input, output)
+	if client {
+		m.ClientStreaming = proto.Bool(true)
+	}
+	if server {
+		m.ServerStreaming = proto.Bool(true)
+	}
+	return sb
+}
+
+func (sb *ServiceBuilder) addMethod(name, input, output string) *descriptorpb.MethodDescriptorProto {
+	m := NewMethod(name, input, output)
+	sb.svc.Method = append(sb.svc.Method, m)
+
+	sb.file.addRef(m.InputType, nil, "")
+	sb.file.addRef(m.OutputType, nil, "")
+	return m
+}
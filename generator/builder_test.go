@@ -0,0 +1,189 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const builderTestProto = `syntax = "proto3";
+package x.v1;
+enum Status { STATUS_UNSPECIFIED = 0; STATUS_ACTIVE = 1; }
+message User {
+  string user_id = 1;
+  map<string, int32> tags = 2;
+  optional string nickname = 3;
+  oneof contact {
+    string email = 4;
+    Phone phone = 5;
+  }
+  Status status = 6;
+  map<string, Phone> phones_by_kind = 7;
+  repeated User friends = 8;
+  message Phone {
+    string number = 1;
+    Kind kind = 2;
+    enum Kind { KIND_UNSPECIFIED = 0; KIND_MOBILE = 1; }
+  }
+}
+service UserService {
+  rpc GetUser(User) returns (User);
+  rpc Watch(User) returns (stream User);
+}
+`
+
+func TestBuilderMatchesParser(t *testing.T) {
+	p := &Parser{
+		Files:              map[string]string{"x.proto": builderTestProto},
+		SkipSourceCodeInfo: true,
+	}
+	files, err := p.Parse("x.proto")
+	core.AssertMustNoError(t, err, "Parse")
+
+	b := File("x.proto").Package("x.v1").Enum("Status", "STATUS_UNSPECIFIED", "STATUS_ACTIVE")
+	b.Message("User", func(m *MessageBuilder) {
+		m.Field("user_id", TypeString).
+			Map("tags", TypeString, TypeInt32).
+			Optional("nickname", TypeString).
+			Oneof("contact", func(o *MessageBuilder) {
+				o.Field("email", TypeString).Ref("phone", "Phone")
+			}).
+			Ref("status", "Status").
+			MapRef("phones_by_kind", TypeString, "Phone").
+			RepeatedRef("friends", "User")
+	}).Message("Phone").
+		Field("number", TypeString).
+		Ref("kind", "Kind").
+		Enum("Kind", "KIND_UNSPECIFIED", "KIND_MOBILE")
+	b.Service("UserService").
+		Method("GetUser", "User", "User").
+		StreamingMethod("Watch", "User", "User", false, true)
+
+	got := b.Build()
+	if !proto.Equal(files[0], got) {
+		expected, _ := PrintFile(files[0])
+		actual, _ := PrintFile(got)
+		t.Fatalf("built file differs:\nexpected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
+
+func TestBuilderValid(t *testing.T) {
+	file := File("a.proto").Package("a").
+		Message("M").
+		Map("tags", TypeString, TypeInt32).
+		Optional("note", TypeString).
+		Oneof("kind", func(m *MessageBuilder) {
+			m.Field("x", TypeInt32).Field("y", TypeString)
+		}).
+		Optional("late", TypeInt64).
+		Build()
+
+	_, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	core.AssertMustNoError(t, err, "protodesc.NewFile")
+
+	msg := file.MessageType[0]
+	core.AssertMustEqual(t, 3, len(msg.OneofDecl), "oneofs")
+	core.AssertEqual(t, "kind", msg.OneofDecl[0].GetName(), "real oneof first")
+	core.AssertEqual(t, "_note", msg.OneofDecl[1].GetName(), "synthetic oneof")
+	core.AssertEqual(t, "_late", msg.OneofDecl[2].GetName(), "synthetic oneof")
+	core.AssertEqual(t, int32(2), msg.Field[4].GetOneofIndex(), "late oneof index")
+
+	entry := msg.NestedType[0]
+	core.AssertEqual(t, "TagsEntry", entry.GetName(), "entry name")
+	core.AssertTrue(t, entry.GetOptions().GetMapEntry(), "map_entry")
+	core.AssertEqual(t, ".a.M.TagsEntry", msg.Field[0].GetTypeName(), "map type name")
+	core.AssertTrue(t, IsMapField(msg.Field[0]), "IsMapField")
+}
+
+func TestBuilderNumbering(t *testing.T) {
+	msg := File("n.proto").Syntax("proto2").
+		Message("M").
+		Field("a", TypeInt32).
+		Field("b", TypeInt32, WithNumber(10)).
+		Field("c", TypeInt32).
+		Reserved(12).
+		Required("d", TypeInt32, WithDefault("7"), WithJSONName("dee")).
+		Optional("e", TypeInt32).
+		Build().MessageType[0]
+
+	numbers := make([]int32, len(msg.Field))
+	for i, field := range msg.Field {
+		numbers[i] = field.GetNumber()
+	}
+	core.AssertSliceEqual(t, []int32{1, 10, 11, 13, 14}, numbers, "numbers")
+	core.AssertEqual(t, "7", msg.Field[3].GetDefaultValue(), "default")
+	core.AssertEqual(t, "dee", msg.Field[3].GetJsonName(), "json_name")
+	core.AssertEqual(t, LabelRequired, msg.Field[3].GetLabel(), "required")
+	core.AssertFalse(t, msg.Field[4].GetProto3Optional(), "proto2 optional")
+	core.AssertEqual(t, 0, len(msg.OneofDecl), "no synthetic oneofs")
+}
+
+func TestBuilderReservedNumbers(t *testing.T) {
+	msg := File("r.proto").
+		Message("M").
+		Reserved(2, 3, 20).
+		Field("a", TypeInt32).
+		Field("b", TypeInt32).
+		Field("c", TypeInt32).
+		Field("d", TypeInt32, WithNumber(19)).
+		Field("e", TypeInt32).
+		Build().MessageType[0]
+
+	numbers := make([]int32, len(msg.Field))
+	for i, field := range msg.Field {
+		numbers[i] = field.GetNumber()
+	}
+	core.AssertSliceEqual(t, []int32{1, 4, 5, 19, 21}, numbers, "numbers")
+	core.AssertEqual(t, 3, len(msg.ReservedRange), "reserved ranges")
+}
+
+func TestBuilderRefs(t *testing.T) {
+	b := File("r.proto").Package("r").Import("other.proto")
+	outer := b.Message("Outer")
+	inner := outer.Message("Inner").
+		Ref("outer", "Outer").
+		Ref("ext", "other.Thing").
+		Ref("color", "other.Color", WithType(TypeEnum)).
+		Ref("abs", ".r.Outer")
+	core.AssertEqual(t, outer, inner.Parent(), "parent")
+	core.AssertEqual(t, b, inner.File(), "file")
+
+	fields := inner.Build().MessageType[0].NestedType[0].Field
+	core.AssertEqual(t, ".r.Outer", fields[0].GetTypeName(), "enclosing scope")
+	core.AssertEqual(t, TypeMessage, fields[0].GetType(), "message")
+	core.AssertEqual(t, ".other.Thing", fields[1].GetTypeName(), "external")
+	core.AssertEqual(t, TypeEnum, fields[2].GetType(), "external enum")
+	core.AssertEqual(t, ".r.Outer", fields[3].GetTypeName(), "fully-qualified")
+}
+
+func TestBuilderLatePackage(t *testing.T) {
+	addTypes := func(b *FileBuilder) *FileBuilder {
+		b.Message("M").
+			Map("tags", TypeString, TypeInt32).
+			MapRef("items", TypeString, "Item").
+			Ref("item", "Item").
+			Message("Item")
+		b.Service("S").Method("Get", "M", "M.Item")
+		return b
+	}
+
+	early := addTypes(File("p.proto").Package("p.v1")).Build()
+	late := addTypes(File("p.proto")).Package("p.v1").Build()
+	core.AssertTrue(t, proto.Equal(early, late), "package set after the types")
+
+	fields := late.MessageType[0].Field
+	core.AssertEqual(t, ".p.v1.M.TagsEntry", fields[0].GetTypeName(), "map entry")
+	core.AssertEqual(t, ".p.v1.M.Item", fields[2].GetTypeName(), "reference")
+	core.AssertEqual(t, ".p.v1.M.Item", late.Service[0].Method[0].GetOutputType(), "method")
+	_, err := protodesc.NewFile(late, protoregistry.GlobalFiles)
+	core.AssertNoError(t, err, "protodesc.NewFile")
+}
+
+func TestMapEntryName(t *testing.T) {
+	core.AssertEqual(t, "TagsEntry", MapEntryName("tags"), "simple")
+	core.AssertEqual(t, "PhonesByKindEntry", MapEntryName("phones_by_kind"), "underscores")
+	core.AssertEqual(t, "FooBarEntry", MapEntryName("fooBar"), "camel case")
+}
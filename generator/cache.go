@@ -0,0 +1,391 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const (
+	// cacheKeyVersion changes whenever the key derivation or the entry
+	// format do, invalidating older entries.
+	cacheKeyVersion = "protomcp-cache-v1"
+	// cacheStaleTemp is the age after which Evict removes the temporary
+	// files of writes that didn't complete.
+	cacheStaleTemp = time.Hour
+)
+
+// Cache stores the outputs of a FileFunc on local disk, reusing them
+// while the file, its transitive dependencies, the plugin parameter
+// and Version are unchanged.
+//
+// Entries are written atomically, so a directory can be shared by
+// concurrent runs. Using an entry marks it as recently used, and Evict
+// only removes entries, never other files in the directory.
+type Cache struct {
+	// Dir is the directory holding the entries.
+	Dir string
+	// Version identifies the plugin build. Entries made by other
+	// versions are never reused. When empty, the main module version
+	// and VCS revision of the binary are used, and nothing is cached if
+	// the build info has neither or the working tree was modified.
+	Version string
+	// MaxAge evicts the entries not used for longer. Zero keeps them
+	// regardless of their age.
+	MaxAge time.Duration
+	// MaxEntries evicts the least recently used entries beyond it. Zero
+	// doesn't limit their number.
+	MaxEntries int
+}
+
+// Plugin returns a plugin calling fn for each file to generate, as
+// FilePlugin does, reusing the cached outputs of unchanged files and
+// evicting old entries afterwards. Failing to use the cache never
+// fails generation, and a nil Cache, one without Dir or one whose
+// version is unknown doesn't cache.
+func (c *Cache) Plugin(fn FileFunc, features ...pluginpb.CodeGeneratorResponse_Feature) PluginFunc {
+	if c == nil || c.Dir == "" || c.version() == "" {
+		return FilePlugin(fn, features...)
+	}
+
+	plugin := FilePlugin(c.Wrap(fn), features...)
+	return func(req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+		resp, err := plugin(req)
+		if err == nil {
+			_ = c.Evict()
+		}
+		return resp, err
+	}
+}
+
+// Wrap returns a FileFunc reusing the cached outputs of fn, and caching
+// new ones. Files that can't be keyed are generated without the cache,
+// and fn is returned as is when the version is unknown.
+func (c *Cache) Wrap(fn FileFunc) FileFunc {
+	if c.version() == "" {
+		return fn
+	}
+
+	keys := &cacheKeys{c: c}
+	return func(req *pluginpb.CodeGeneratorRequest,
+		file *descriptorpb.FileDescriptorProto) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+		key, err := keys.key(req, file.GetName())
+		if err != nil {
+			return fn(req, file)
+		}
+		if files, ok := c.Get(key); ok {
+			return files, nil
+		}
+
+		files, err := fn(req, file)
+		if err != nil {
+			return nil, err
+		}
+		_ = c.Put(key, files)
+		return files, nil
+	}
+}
+
+// Key returns the cache key of the named file of a request, derived
+// from the canonical form of the file and its transitive dependencies,
+// the plugin parameter and the version, failing with core.ErrInvalid
+// when the version is unknown.
+func (c *Cache) Key(req *pluginpb.CodeGeneratorRequest, name string) (string, error) {
+	keys := &cacheKeys{c: c}
+	return keys.key(req, name)
+}
+
+// cacheKeys computes the keys of the files of a request, remembering
+// the hashes of the files for the following keys of the same request.
+type cacheKeys struct {
+	c *Cache
+
+	mu     sync.Mutex
+	req    *pluginpb.CodeGeneratorRequest
+	graph  *FileGraph
+	hashes map[string]string
+}
+
+func (k *cacheKeys) key(req *pluginpb.CodeGeneratorRequest, name string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	version := k.c.version()
+	if version == "" {
+		return "", core.Wrap(core.ErrInvalid, "unknown cache version")
+	}
+
+	if k.req != req {
+		g, err := NewFileGraph(req.GetProtoFile())
+		if err != nil {
+			return "", err
+		}
+		k.req, k.graph, k.hashes = req, g, make(map[string]string)
+	}
+	if _, ok := k.graph.File(name); !ok {
+		return "", core.Wrapf(core.ErrNotExists, "file %q not in request", name)
+	}
+
+	h := sha256.New()
+	for _, s := range []string{cacheKeyVersion, version, req.GetParameter(), name} {
+		writeKeyString(h, s)
+	}
+
+	names := k.graph.TransitiveImports(name)
+	sort.Strings(names)
+	for _, n := range append(names, name) {
+		sum, err := k.fileHash(n)
+		if err != nil {
+			return "", err
+		}
+		writeKeyString(h, n)
+		writeKeyString(h, sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (k *cacheKeys) fileHash(name string) (string, error) {
+	if sum, ok := k.hashes[name]; ok {
+		return sum, nil
+	}
+
+	file, _ := k.graph.File(name)
+	// comments can be part of the output, so SourceCodeInfo is kept
+	sum, err := FileHash(file, CanonicalOptions{})
+	if err != nil {
+		return "", err
+	}
+	k.hashes[name] = sum
+	return sum, nil
+}
+
+// version returns Version, or the build version of the binary when
+// empty, which is empty too when unknown.
+func (c *Cache) version() string {
+	if c.Version != "" {
+		return c.Version
+	}
+	return buildVersion()
+}
+
+// buildVersion returns the main module version and VCS revision of the
+// binary, or an empty string when it has neither or was built from a
+// modified working tree.
+var buildVersion = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	var parts []string
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		parts = append(parts, v)
+	}
+	for _, s := range info.Settings {
+		switch {
+		case s.Key == "vcs.modified" && s.Value == "true":
+			return ""
+		case s.Key == "vcs.revision":
+			parts = append(parts, s.Value)
+		}
+	}
+	return strings.Join(parts, " ")
+})
+
+// writeKeyString writes a length-prefixed string to the hash.
+func writeKeyString(h hash.Hash, s string) {
+	_, _ = h.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	_, _ = h.Write([]byte(s))
+}
+
+// path returns the location of the entry of a key.
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key[:2], key+".pb")
+}
+
+// Get returns the cached outputs of a key, marking the entry as
+// recently used. Unreadable entries are removed and reported missing.
+func (c *Cache) Get(key string) ([]*pluginpb.CodeGeneratorResponse_File, bool) {
+	if !isCacheKey(key) {
+		return nil, false
+	}
+
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	entry := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(data, entry); err != nil {
+		_ = os.Remove(path)
+		return nil, false
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return entry.File, true
+}
+
+// Put stores the outputs of a key, replacing the entry atomically.
+func (c *Cache) Put(key string, files []*pluginpb.CodeGeneratorResponse_File) error {
+	if !isCacheKey(key) {
+		return core.Wrapf(core.ErrInvalid, "invalid cache key %q", key)
+	}
+
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(&pluginpb.CodeGeneratorResponse{File: files})
+	if err != nil {
+		return core.Wrap(err, "marshal cache entry")
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// cacheEntry is an entry file found by Evict.
+type cacheEntry struct {
+	path string
+	used time.Time
+}
+
+// Evict removes the entries unused for longer than MaxAge and the least
+// recently used ones beyond MaxEntries, together with the leftovers of
+// interrupted writes. Entries removed concurrently are ignored.
+func (c *Cache) Evict() error {
+	if c.MaxAge <= 0 && c.MaxEntries <= 0 {
+		return nil
+	}
+
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	kept := entries[:0]
+	for _, e := range entries {
+		if c.MaxAge > 0 && now.Sub(e.used) > c.MaxAge {
+			err = firstError(err, removeEntry(e.path))
+		} else {
+			kept = append(kept, e)
+		}
+	}
+
+	if c.MaxEntries > 0 && len(kept) > c.MaxEntries {
+		sort.Slice(kept, func(i, j int) bool {
+			return kept[i].used.After(kept[j].used)
+		})
+		for _, e := range kept[c.MaxEntries:] {
+			err = firstError(err, removeEntry(e.path))
+		}
+	}
+	return err
+}
+
+// entries lists the entries in the cache directory, removing stale
+// temporary files on the way.
+func (c *Cache) entries() ([]cacheEntry, error) {
+	dirs, err := os.ReadDir(c.Dir)
+	switch {
+	case os.IsNotExist(err):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	var out []cacheEntry
+	for _, dir := range dirs {
+		if !dir.IsDir() || !isHex(dir.Name(), 2) {
+			continue
+		}
+
+		sub := filepath.Join(c.Dir, dir.Name())
+		files, err := os.ReadDir(sub)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			info, err := f.Info()
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+
+			path := filepath.Join(sub, f.Name())
+			key, isEntry := strings.CutSuffix(f.Name(), ".pb")
+			switch {
+			case isEntry && isCacheKey(key):
+				out = append(out, cacheEntry{path: path, used: info.ModTime()})
+			case isCacheTemp(f.Name()) && time.Since(info.ModTime()) > cacheStaleTemp:
+				_ = removeEntry(path)
+			}
+		}
+	}
+	return out, nil
+}
+
+func removeEntry(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func firstError(err, next error) error {
+	if err != nil {
+		return err
+	}
+	return next
+}
+
+// isCacheKey tells if s is a hex-encoded SHA-256.
+func isCacheKey(s string) bool {
+	return isHex(s, sha256.Size*2)
+}
+
+// isCacheTemp tells if a file name is that of an incomplete write.
+func isCacheTemp(name string) bool {
+	key, _, ok := strings.Cut(name, ".tmp-")
+	return ok && isCacheKey(key)
+}
+
+// isHex tells if s has the given length and only lower case hex digits.
+func isHex(s string, size int) bool {
+	if len(s) != size {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
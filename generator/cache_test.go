@@ -0,0 +1,228 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// countingFileFunc wraps listFileMessages counting the files generated.
+func countingFileFunc(generated map[string]int) FileFunc {
+	return func(req *pluginpb.CodeGeneratorRequest,
+		file *descriptorpb.FileDescriptorProto) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+		generated[file.GetName()]++
+		return listFileMessages(req, file)
+	}
+}
+
+// countCacheEntries counts the entry files in a cache directory.
+func countCacheEntries(t *testing.T, dir string) int {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "*", "*.pb"))
+	core.AssertMustNoError(t, err, "Glob")
+	return len(matches)
+}
+
+func TestCachePlugin(t *testing.T) {
+	c := &Cache{Dir: t.TempDir(), Version: "v1"}
+	generated := make(map[string]int)
+	plugin := c.Plugin(countingFileFunc(generated))
+
+	first, err := plugin(newFileFuncTestRequest(t, nil))
+	core.AssertMustNoError(t, err, "first run")
+	core.AssertEqual(t, 3, countCacheEntries(t, c.Dir), "entries")
+
+	second, err := plugin(newFileFuncTestRequest(t, nil))
+	core.AssertMustNoError(t, err, "second run")
+	core.AssertEqual(t, 1, generated["a.proto"], "a reused")
+	core.AssertEqual(t, 1, generated["c.proto"], "c reused")
+	core.AssertEqual(t, len(first.File), len(second.File), "outputs")
+	for i := range first.File {
+		core.AssertEqual(t, first.File[i].GetName(), second.File[i].GetName(), "name")
+		core.AssertEqual(t, first.File[i].GetContent(), second.File[i].GetContent(), "content")
+	}
+
+	// changing a.proto regenerates b.proto, which imports it
+	changed := map[string]string{"a.proto": `syntax = "proto3"; package a; message A { int64 n = 1; }`}
+	_, err = plugin(newFileFuncTestRequest(t, changed))
+	core.AssertMustNoError(t, err, "changed run")
+	core.AssertEqual(t, 2, generated["a.proto"], "a regenerated")
+	core.AssertEqual(t, 2, generated["b.proto"], "b regenerated")
+	core.AssertEqual(t, 1, generated["c.proto"], "c still reused")
+
+	// the parameter and the version are part of the key
+	_, err = plugin(newFileFuncTestRequest(t, nil, WithParameter("x=1")))
+	core.AssertMustNoError(t, err, "parameter run")
+	core.AssertEqual(t, 2, generated["c.proto"], "parameter")
+
+	c2 := &Cache{Dir: c.Dir, Version: "v2"}
+	_, err = c2.Plugin(countingFileFunc(generated))(newFileFuncTestRequest(t, nil))
+	core.AssertMustNoError(t, err, "version run")
+	core.AssertEqual(t, 3, generated["c.proto"], "version")
+}
+
+// setBuildVersion replaces the build version of the binary during a test.
+func setBuildVersion(t *testing.T, version string) {
+	saved := buildVersion
+	buildVersion = func() string { return version }
+	t.Cleanup(func() { buildVersion = saved })
+}
+
+func TestCachePluginDisabled(t *testing.T) {
+	setBuildVersion(t, "")
+	generated := make(map[string]int)
+	for _, c := range []*Cache{nil, {}, {Dir: t.TempDir()}} {
+		plugin := c.Plugin(countingFileFunc(generated))
+		for range 2 {
+			_, err := plugin(newFileFuncTestRequest(t, nil))
+			core.AssertMustNoError(t, err, "run")
+		}
+	}
+	core.AssertEqual(t, 6, generated["a.proto"], "not cached")
+
+	c := &Cache{Dir: t.TempDir()}
+	fn := c.Wrap(countingFileFunc(generated))
+	for range 2 {
+		_, err := fn(newFileFuncTestRequest(t, nil), File("a.proto").Build())
+		core.AssertMustNoError(t, err, "Wrap")
+	}
+	core.AssertEqual(t, 8, generated["a.proto"], "Wrap not cached")
+	core.AssertEqual(t, 0, countCacheEntries(t, c.Dir), "no entries")
+
+	_, err := c.Key(newFileFuncTestRequest(t, nil), "a.proto")
+	core.AssertErrorIs(t, err, core.ErrInvalid, "unknown version")
+}
+
+func TestCacheBuildVersion(t *testing.T) {
+	c := &Cache{Dir: t.TempDir()}
+	req := newFileFuncTestRequest(t, nil)
+
+	setBuildVersion(t, "v1.0.0 abc123")
+	key, err := c.Key(req, "a.proto")
+	core.AssertMustNoError(t, err, "Key")
+	explicit, err := (&Cache{Version: "v1.0.0 abc123"}).Key(req, "a.proto")
+	core.AssertMustNoError(t, err, "Key explicit")
+	core.AssertEqual(t, explicit, key, "build version used")
+
+	generated := make(map[string]int)
+	plugin := c.Plugin(countingFileFunc(generated))
+	for range 2 {
+		_, err := plugin(req)
+		core.AssertMustNoError(t, err, "run")
+	}
+	core.AssertEqual(t, 1, generated["a.proto"], "cached")
+
+	setBuildVersion(t, "v1.0.1 def456")
+	_, err = c.Plugin(countingFileFunc(generated))(req)
+	core.AssertMustNoError(t, err, "rebuilt run")
+	core.AssertEqual(t, 2, generated["a.proto"], "rebuilt plugin")
+}
+
+func TestCacheKey(t *testing.T) {
+	c := &Cache{Version: "v1"}
+	req := newFileFuncTestRequest(t, nil)
+
+	a, err := c.Key(req, "a.proto")
+	core.AssertMustNoError(t, err, "Key a")
+	core.AssertTrue(t, isCacheKey(a), "key format")
+
+	comment := map[string]string{"c.proto": "syntax = \"proto3\";\n// C\npackage c;\nmessage C {}\n"}
+	again, err := c.Key(newFileFuncTestRequest(t, comment), "a.proto")
+	core.AssertMustNoError(t, err, "Key again")
+	core.AssertEqual(t, a, again, "unrelated change")
+
+	cKey, err := c.Key(req, "c.proto")
+	core.AssertMustNoError(t, err, "Key c")
+	commented, err := c.Key(newFileFuncTestRequest(t, comment), "c.proto")
+	core.AssertMustNoError(t, err, "Key commented")
+	core.AssertTrue(t, cKey != commented, "comments are keyed")
+
+	_, err = c.Key(req, "missing.proto")
+	core.AssertErrorIs(t, err, core.ErrNotExists, "unknown file")
+}
+
+func TestCacheGetPut(t *testing.T) {
+	c := &Cache{Dir: t.TempDir(), Version: "v1"}
+	key, err := c.Key(newFileFuncTestRequest(t, nil), "a.proto")
+	core.AssertMustNoError(t, err, "Key")
+
+	_, ok := c.Get(key)
+	core.AssertFalse(t, ok, "empty cache")
+
+	files, _ := listFileMessages(nil, File("x.proto").Message("X").File().Build())
+	core.AssertMustNoError(t, c.Put(key, files), "Put")
+	got, ok := c.Get(key)
+	core.AssertMustTrue(t, ok, "hit")
+	core.AssertEqual(t, "X\n", got[0].GetContent(), "content")
+
+	// corrupted entries are dropped
+	path := c.path(key)
+	core.AssertMustNoError(t, os.WriteFile(path, []byte{0xff}, 0o644), "corrupt")
+	_, ok = c.Get(key)
+	core.AssertFalse(t, ok, "corrupted")
+	_, err = os.Stat(path)
+	core.AssertTrue(t, os.IsNotExist(err), "corrupted removed")
+
+	core.AssertErrorIs(t, c.Put("../escape", files), core.ErrInvalid, "invalid key")
+	_, ok = c.Get("../escape")
+	core.AssertFalse(t, ok, "invalid key")
+}
+
+func TestCacheEvict(t *testing.T) {
+	c := &Cache{Dir: t.TempDir()}
+	files, _ := listFileMessages(nil, File("x.proto").Message("X").File().Build())
+
+	keys := []string{
+		"1111111111111111111111111111111111111111111111111111111111111111",
+		"2222222222222222222222222222222222222222222222222222222222222222",
+		"3333333333333333333333333333333333333333333333333333333333333333",
+	}
+	now := time.Now()
+	for i, key := range keys {
+		core.AssertMustNoError(t, c.Put(key, files), "Put")
+		used := now.Add(-time.Duration(len(keys)-i) * time.Hour)
+		core.AssertMustNoError(t, os.Chtimes(c.path(key), used, used), "Chtimes")
+	}
+
+	// unrelated files and fresh temporary files are kept
+	other := filepath.Join(c.Dir, "11", "notes.txt")
+	core.AssertMustNoError(t, os.WriteFile(other, nil, 0o644), "other")
+	fresh := filepath.Join(c.Dir, "11", keys[0]+".tmp-1")
+	core.AssertMustNoError(t, os.WriteFile(fresh, nil, 0o644), "fresh temp")
+	stale := filepath.Join(c.Dir, "22", keys[1]+".tmp-2")
+	core.AssertMustNoError(t, os.WriteFile(stale, nil, 0o644), "stale temp")
+	old := now.Add(-2 * cacheStaleTemp)
+	core.AssertMustNoError(t, os.Chtimes(stale, old, old), "Chtimes")
+
+	core.AssertNoError(t, c.Evict(), "no limits")
+	core.AssertEqual(t, 3, countCacheEntries(t, c.Dir), "no limits")
+
+	c.MaxAge = 150 * time.Minute
+	core.AssertNoError(t, c.Evict(), "MaxAge")
+	_, ok := c.Get(keys[0])
+	core.AssertFalse(t, ok, "oldest evicted")
+	core.AssertEqual(t, 2, countCacheEntries(t, c.Dir), "MaxAge")
+
+	// Get marks the entry as recently used
+	_, ok = c.Get(keys[1])
+	core.AssertTrue(t, ok, "hit")
+	c.MaxEntries = 1
+	core.AssertNoError(t, c.Evict(), "MaxEntries")
+	_, ok = c.Get(keys[1])
+	core.AssertTrue(t, ok, "recently used kept")
+	_, ok = c.Get(keys[2])
+	core.AssertFalse(t, ok, "least recently used evicted")
+
+	for path, exists := range map[string]bool{other: true, fresh: true, stale: false} {
+		_, err := os.Stat(path)
+		core.AssertEqual(t, exists, err == nil, path)
+	}
+
+	missing := &Cache{Dir: filepath.Join(c.Dir, "missing"), MaxEntries: 1}
+	core.AssertNoError(t, missing.Evict(), "missing dir")
+}
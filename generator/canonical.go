@@ -0,0 +1,231 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// CanonicalOptions controls how descriptors are canonicalised.
+type CanonicalOptions struct {
+	// StripSourceInfo removes the SourceCodeInfo, so that comments and
+	// formatting don't change the canonical form.
+	StripSourceInfo bool
+}
+
+// CanonicalFile returns a canonical copy of a file descriptor, leaving
+// the original untouched. Descriptors differing only in details that
+// don't change their meaning have the same canonical form:
+//
+//   - fields and extensions without json_name get the default one.
+//   - unknown fields, such as unresolved custom options, are ordered
+//     by field number.
+//   - when there is no SourceCodeInfo, whose paths refer to element
+//     indices, imports, reserved ranges and names, and extension ranges
+//     are sorted.
+func CanonicalFile(file *descriptorpb.FileDescriptorProto, opts CanonicalOptions) *descriptorpb.FileDescriptorProto {
+	if file == nil {
+		return nil
+	}
+
+	out := cloneFile(file)
+	if opts.StripSourceInfo {
+		out.SourceCodeInfo = nil
+	}
+
+	sortable := out.SourceCodeInfo == nil
+	if sortable {
+		sortDependencies(out)
+	}
+	fillJSONNames(out.Extension)
+	for _, msg := range out.MessageType {
+		canonicalMessage(msg, sortable)
+	}
+	for _, enum := range out.EnumType {
+		canonicalEnum(enum, sortable)
+	}
+	sortUnknown(out.ProtoReflect())
+	return out
+}
+
+// FileHash returns the hex-encoded SHA-256 of the canonical form of a
+// file descriptor.
+func FileHash(file *descriptorpb.FileDescriptorProto, opts CanonicalOptions) (string, error) {
+	if file == nil {
+		return "", core.Wrap(core.ErrInvalid, "nil file descriptor")
+	}
+
+	h := sha256.New()
+	if err := writeCanonical(h.Write, file, opts); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Hash returns the hex-encoded SHA-256 of the canonical forms of the
+// named file and all the files it depends on, directly or indirectly,
+// changing whenever any of them does.
+func (g *FileGraph) Hash(name string, opts CanonicalOptions) (string, error) {
+	file, ok := g.File(name)
+	if !ok {
+		return "", core.Wrapf(core.ErrNotExists, "file %q not found", name)
+	}
+
+	// sorted by name, as the topological order depends on that of the
+	// files given to the graph
+	deps := g.TransitiveImports(name)
+	sort.Strings(deps)
+
+	h := sha256.New()
+	for _, dep := range deps {
+		f, _ := g.File(dep)
+		if err := writeCanonical(h.Write, f, opts); err != nil {
+			return "", err
+		}
+	}
+	if err := writeCanonical(h.Write, file, opts); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeCanonical writes the deterministic encoding of the canonical
+// form of a file, prefixed by its length.
+func writeCanonical(write func([]byte) (int, error), file *descriptorpb.FileDescriptorProto,
+	opts CanonicalOptions) error {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(CanonicalFile(file, opts))
+	if err != nil {
+		return core.Wrapf(err, "file %q", file.GetName())
+	}
+
+	_, _ = write(binary.AppendUvarint(nil, uint64(len(b))))
+	_, _ = write(b)
+	return nil
+}
+
+// sortDependencies sorts the imports of a file, renumbering its public
+// and weak imports.
+func sortDependencies(file *descriptorpb.FileDescriptorProto) {
+	deps := file.Dependency
+	order := make([]int, len(deps))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return deps[order[i]] < deps[order[j]]
+	})
+
+	index := make(map[int32]int32, len(order))
+	sorted := make([]string, len(order))
+	for i, from := range order {
+		index[int32(from)] = int32(i)
+		sorted[i] = deps[from]
+	}
+	file.Dependency = sorted
+
+	for _, list := range []*[]int32{&file.PublicDependency, &file.WeakDependency} {
+		for i, v := range *list {
+			if n, ok := index[v]; ok {
+				(*list)[i] = n
+			}
+		}
+		sort.Slice(*list, func(i, j int) bool { return (*list)[i] < (*list)[j] })
+	}
+}
+
+func canonicalMessage(msg *descriptorpb.DescriptorProto, sortable bool) {
+	fillJSONNames(msg.Field)
+	fillJSONNames(msg.Extension)
+	if sortable {
+		sort.SliceStable(msg.ReservedRange, func(i, j int) bool {
+			return msg.ReservedRange[i].GetStart() < msg.ReservedRange[j].GetStart()
+		})
+		sort.SliceStable(msg.ExtensionRange, func(i, j int) bool {
+			return msg.ExtensionRange[i].GetStart() < msg.ExtensionRange[j].GetStart()
+		})
+		sort.Strings(msg.ReservedName)
+	}
+
+	for _, nested := range msg.NestedType {
+		canonicalMessage(nested, sortable)
+	}
+	for _, enum := range msg.EnumType {
+		canonicalEnum(enum, sortable)
+	}
+}
+
+func canonicalEnum(enum *descriptorpb.EnumDescriptorProto, sortable bool) {
+	if sortable {
+		sort.SliceStable(enum.ReservedRange, func(i, j int) bool {
+			return enum.ReservedRange[i].GetStart() < enum.ReservedRange[j].GetStart()
+		})
+		sort.Strings(enum.ReservedName)
+	}
+}
+
+// fillJSONNames sets the default json_name of the fields without one,
+// as protoc does.
+func fillJSONNames(fields []*descriptorpb.FieldDescriptorProto) {
+	for _, field := range fields {
+		if field.JsonName == nil {
+			field.JsonName = proto.String(JSONName(field.GetName()))
+		}
+	}
+}
+
+// sortUnknown orders the unknown fields of a message and the messages
+// within it by field number, keeping the order of repeated values.
+func sortUnknown(m protoreflect.Message) {
+	if raw := m.GetUnknown(); len(raw) > 0 {
+		m.SetUnknown(sortedUnknown(raw))
+	}
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.Message() == nil, fd.IsMap():
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				sortUnknown(list.Get(i).Message())
+			}
+		default:
+			sortUnknown(v.Message())
+		}
+		return true
+	})
+}
+
+// sortedUnknown returns the unknown fields ordered by number, or
+// unchanged if they can't be parsed.
+func sortedUnknown(raw protoreflect.RawFields) protoreflect.RawFields {
+	type record struct {
+		num  protowire.Number
+		data []byte
+	}
+
+	var records []record
+	for b := []byte(raw); len(b) > 0; {
+		num, _, n := protowire.ConsumeField(b)
+		if n < 0 {
+			return raw
+		}
+		records = append(records, record{num: num, data: b[:n]})
+		b = b[n:]
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].num < records[j].num
+	})
+	out := make(protoreflect.RawFields, 0, len(raw))
+	for _, r := range records {
+		out = append(out, r.data...)
+	}
+	return out
+}
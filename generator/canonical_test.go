@@ -0,0 +1,191 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const canonicalProto = `syntax = "proto2";
+
+package canon;
+
+import "google/protobuf/duration.proto";
+import "google/protobuf/any.proto";
+
+// Item is documented.
+message Item {
+  optional string item_id = 1;
+  optional google.protobuf.Duration ttl = 2;
+  optional google.protobuf.Any extra = 3;
+  reserved 10 to 12, 5;
+  reserved "old", "legacy";
+  extensions 200 to 300, 100 to 150;
+}
+
+enum Kind {
+  KIND_NONE = 0;
+  reserved 7, 3;
+  reserved "B", "A";
+}
+`
+
+// reorderedCanonicalProto declares the same as canonicalProto in a
+// different order and without comments.
+const reorderedCanonicalProto = `syntax = "proto2";
+package canon;
+import "google/protobuf/any.proto";
+import "google/protobuf/duration.proto";
+message Item {
+  optional string item_id = 1;
+  optional google.protobuf.Duration ttl = 2;
+  optional google.protobuf.Any extra = 3;
+  reserved 5, 10 to 12;
+  reserved "legacy", "old";
+  extensions 100 to 150, 200 to 300;
+}
+enum Kind {
+  KIND_NONE = 0;
+  reserved 3, 7;
+  reserved "A", "B";
+}
+`
+
+func parseCanonicalTest(t *testing.T, src string) *descriptorpb.FileDescriptorProto {
+	t.Helper()
+	file, err := ParseProto("canon.proto", src)
+	core.AssertMustNoError(t, err, "ParseProto")
+	return file
+}
+
+func TestFileHash(t *testing.T) {
+	a := parseCanonicalTest(t, canonicalProto)
+	b := parseCanonicalTest(t, reorderedCanonicalProto)
+	strip := CanonicalOptions{StripSourceInfo: true}
+
+	ha, err := FileHash(a, strip)
+	core.AssertMustNoError(t, err, "FileHash a")
+	hb, err := FileHash(b, strip)
+	core.AssertMustNoError(t, err, "FileHash b")
+	core.AssertEqual(t, ha, hb, "stripped hashes")
+	core.AssertEqual(t, 64, len(ha), "hex sha256")
+
+	ka, err := FileHash(a, CanonicalOptions{})
+	core.AssertMustNoError(t, err, "FileHash keep")
+	core.AssertTrue(t, ka != ha, "source info hashed")
+
+	c := parseCanonicalTest(t, canonicalProto)
+	c.MessageType[0].Field[0].Name = proto.String("item_key")
+	hc, err := FileHash(c, strip)
+	core.AssertMustNoError(t, err, "FileHash c")
+	core.AssertTrue(t, hc != ha, "content change")
+
+	_, err = FileHash(nil, strip)
+	core.AssertErrorIs(t, err, core.ErrInvalid, "nil file")
+}
+
+func TestCanonicalFile(t *testing.T) {
+	file := parseCanonicalTest(t, canonicalProto)
+	file.PublicDependency = []int32{1}
+	orig := proto.Clone(file)
+
+	out := CanonicalFile(file, CanonicalOptions{StripSourceInfo: true})
+	core.AssertTrue(t, proto.Equal(orig, file), "original untouched")
+	core.AssertNil(t, out.SourceCodeInfo, "source info")
+	core.AssertSliceEqual(t, []string{"google/protobuf/any.proto", "google/protobuf/duration.proto"},
+		out.Dependency, "dependencies")
+	core.AssertSliceEqual(t, []int32{0}, out.PublicDependency, "public dependency renumbered")
+
+	msg := out.MessageType[0]
+	core.AssertEqual(t, int32(5), msg.ReservedRange[0].GetStart(), "reserved ranges")
+	core.AssertSliceEqual(t, []string{"legacy", "old"}, msg.ReservedName, "reserved names")
+	core.AssertEqual(t, int32(100), msg.ExtensionRange[0].GetStart(), "extension ranges")
+	core.AssertEqual(t, int32(3), out.EnumType[0].ReservedRange[0].GetStart(), "enum reserved ranges")
+	core.AssertSliceEqual(t, []string{"A", "B"}, out.EnumType[0].ReservedName, "enum reserved names")
+
+	// with source info, element indices are kept
+	kept := CanonicalFile(file, CanonicalOptions{})
+	core.AssertNotNil(t, kept.SourceCodeInfo, "source info kept")
+	core.AssertEqual(t, "google/protobuf/duration.proto", kept.Dependency[0], "dependencies kept")
+	core.AssertEqual(t, int32(10), kept.MessageType[0].ReservedRange[0].GetStart(), "ranges kept")
+
+	core.AssertNil(t, CanonicalFile(nil, CanonicalOptions{}), "nil file")
+}
+
+func TestCanonicalFileJSONNames(t *testing.T) {
+	file := File("names.proto").Package("names").Build()
+	file.MessageType = []*descriptorpb.DescriptorProto{
+		NewMessage("M", NewField("user_id", 1, TypeString)),
+	}
+	named := proto.Clone(file).(*descriptorpb.FileDescriptorProto)
+	named.MessageType[0].Field[0].JsonName = proto.String("userId")
+
+	out := CanonicalFile(file, CanonicalOptions{})
+	core.AssertEqual(t, "userId", out.MessageType[0].Field[0].GetJsonName(), "json_name")
+	core.AssertNil(t, file.MessageType[0].Field[0].JsonName, "original untouched")
+
+	h1, err := FileHash(file, CanonicalOptions{})
+	core.AssertMustNoError(t, err, "FileHash")
+	h2, err := FileHash(named, CanonicalOptions{})
+	core.AssertMustNoError(t, err, "FileHash named")
+	core.AssertEqual(t, h1, h2, "default json_name")
+}
+
+func TestCanonicalFileUnknownOptions(t *testing.T) {
+	var first, second []byte
+	first = protowire.AppendTag(first, 50001, protowire.VarintType)
+	first = protowire.AppendVarint(first, 1)
+	second = protowire.AppendTag(second, 50002, protowire.BytesType)
+	second = protowire.AppendString(second, "x")
+
+	a := File("opts.proto").Package("opts").Build()
+	a.Options = &descriptorpb.FileOptions{}
+	a.Options.ProtoReflect().SetUnknown(append(append([]byte{}, first...), second...))
+	b := File("opts.proto").Package("opts").Build()
+	b.Options = &descriptorpb.FileOptions{}
+	b.Options.ProtoReflect().SetUnknown(append(append([]byte{}, second...), first...))
+
+	ha, err := FileHash(a, CanonicalOptions{})
+	core.AssertMustNoError(t, err, "FileHash a")
+	hb, err := FileHash(b, CanonicalOptions{})
+	core.AssertMustNoError(t, err, "FileHash b")
+	core.AssertEqual(t, ha, hb, "option order")
+}
+
+func TestFileGraphHash(t *testing.T) {
+	files := func(common string) []*descriptorpb.FileDescriptorProto {
+		p := &Parser{Files: map[string]string{
+			"common.proto": common,
+			"api.proto": `syntax = "proto3";
+package api;
+import "common.proto";
+message Request { common.Page page = 1; }
+`,
+		}}
+		out, err := p.ParseWithImports("api.proto")
+		core.AssertMustNoError(t, err, "Parse")
+		return out
+	}
+	hash := func(files []*descriptorpb.FileDescriptorProto) string {
+		g, err := NewFileGraph(files)
+		core.AssertMustNoError(t, err, "NewFileGraph")
+		h, err := g.Hash("api.proto", CanonicalOptions{StripSourceInfo: true})
+		core.AssertMustNoError(t, err, "Hash")
+		return h
+	}
+
+	v1 := files(`syntax = "proto3"; package common; message Page { int32 size = 1; }`)
+	v1b := files("syntax = \"proto3\";\n// pages\npackage common;\nmessage Page {\n  int32 size = 1;\n}\n")
+	v2 := files(`syntax = "proto3"; package common; message Page { int64 size = 1; }`)
+
+	core.AssertEqual(t, hash(v1), hash(v1b), "formatting")
+	core.AssertTrue(t, hash(v1) != hash(v2), "dependency change")
+
+	g, err := NewFileGraph(v1)
+	core.AssertMustNoError(t, err, "NewFileGraph")
+	_, err = g.Hash("missing.proto", CanonicalOptions{})
+	core.AssertErrorIs(t, err, core.ErrNotExists, "unknown file")
+}
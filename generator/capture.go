@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const (
+	// CaptureEnv is the environment variable naming the directory
+	// where WithCapture dumps the requests received.
+	CaptureEnv = "PROTOMCP_CAPTURE"
+	// CaptureParameter is the plugin parameter naming the directory
+	// where WithCapture dumps the requests received, as in
+	// --example_opt=capture=/tmp/requests.
+	CaptureParameter = "capture"
+)
+
+// WithCapture wraps a plugin so the requests it receives are dumped
+// when enabled by CaptureParameter or CaptureEnv, the parameter taking
+// precedence. The capture parameter is removed before calling the
+// plugin, and failing to write the dump fails the request.
+func WithCapture(fn PluginFunc) PluginFunc {
+	return func(req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+		dir, param := captureDir(req.GetParameter())
+		if dir == "" {
+			return fn(req)
+		}
+
+		if _, err := CaptureRequest(req, dir); err != nil {
+			return nil, err
+		}
+
+		req = cloneRequest(req)
+		req.Parameter = nil
+		if param != "" {
+			req.Parameter = proto.String(param)
+		}
+		return fn(req)
+	}
+}
+
+// captureDir returns the capture directory and the parameter without
+// the capture option.
+func captureDir(parameter string) (string, string) {
+	var dir string
+	var rest []string
+	for _, opt := range strings.Split(parameter, ",") {
+		key, value, _ := strings.Cut(opt, "=")
+		switch {
+		case key == CaptureParameter:
+			dir = value
+		case opt != "":
+			rest = append(rest, opt)
+		}
+	}
+
+	if dir == "" {
+		dir = os.Getenv(CaptureEnv)
+	}
+	return dir, strings.Join(rest, ",")
+}
+
+// CaptureRequest writes a request to dir in binary form, as
+// request-<hash>.pb, and in text form next to it as request-<hash>.txtpb.
+// It returns the path of the binary dump, the one to replay as
+// extensions in options only survive there.
+func CaptureRequest(req *pluginpb.CodeGeneratorRequest, dir string) (string, error) {
+	bin, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", core.Wrap(err, "marshal request")
+	}
+	text, err := prototext.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(req)
+	if err != nil {
+		return "", core.Wrap(err, "marshal request")
+	}
+
+	sum := sha256.Sum256(bin)
+	base := filepath.Join(dir, "request-"+hex.EncodeToString(sum[:4]))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".pb", bin, 0o644); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".txtpb", text, 0o644); err != nil {
+		return "", err
+	}
+	return base + ".pb", nil
+}
+
+// LoadRequest reads a request dumped by CaptureRequest, in binary form
+// unless the file has a .txtpb, .textproto or .txt extension.
+func LoadRequest(path string) (*pluginpb.CodeGeneratorRequest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &pluginpb.CodeGeneratorRequest{}
+	switch filepath.Ext(path) {
+	case ".txtpb", ".textproto", ".txt":
+		err = prototext.Unmarshal(data, req)
+	default:
+		err = proto.Unmarshal(data, req)
+	}
+	if err != nil {
+		return nil, core.Wrapf(err, "%s: invalid request", path)
+	}
+	return req, nil
+}
+
+// ReplayRequest loads a dumped request and runs a plugin against it,
+// returning the response for inspection.
+func ReplayRequest(path string, fn PluginFunc) (*pluginpb.CodeGeneratorResponse, error) {
+	req, err := LoadRequest(path)
+	if err != nil {
+		return nil, err
+	}
+	return fn(req)
+}
+
+func cloneRequest(req *pluginpb.CodeGeneratorRequest) *pluginpb.CodeGeneratorRequest {
+	out, _ := proto.Clone(req).(*pluginpb.CodeGeneratorRequest)
+	return out
+}
@@ -0,0 +1,109 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+type captureDirTestCase struct {
+	name      string
+	parameter string
+	env       string
+	dir       string
+	rest      string
+}
+
+var _ core.TestCase = captureDirTestCase{}
+
+func newCaptureDirTestCase(name, parameter, env, dir, rest string) captureDirTestCase {
+	return captureDirTestCase{name: name, parameter: parameter, env: env, dir: dir, rest: rest}
+}
+
+func (tc captureDirTestCase) Name() string {
+	return tc.name
+}
+
+func (tc captureDirTestCase) Test(t *testing.T) {
+	t.Helper()
+	t.Setenv(CaptureEnv, tc.env)
+	dir, rest := captureDir(tc.parameter)
+	core.AssertEqual(t, tc.dir, dir, "dir")
+	core.AssertEqual(t, tc.rest, rest, "parameter")
+}
+
+func captureDirTestCases() []captureDirTestCase {
+	return []captureDirTestCase{
+		newCaptureDirTestCase("disabled", "a=1,b", "", "", "a=1,b"),
+		newCaptureDirTestCase("parameter", "a=1,capture=/tmp/x,b", "", "/tmp/x", "a=1,b"),
+		newCaptureDirTestCase("only parameter", "capture=/tmp/x", "", "/tmp/x", ""),
+		newCaptureDirTestCase("environment", "a=1", "/tmp/env", "/tmp/env", "a=1"),
+		newCaptureDirTestCase("parameter wins", "capture=/tmp/x", "/tmp/env", "/tmp/x", ""),
+	}
+}
+
+func TestCaptureDir(t *testing.T) {
+	core.RunTestCases(t, captureDirTestCases())
+}
+
+func TestCaptureReplay(t *testing.T) {
+	dir := t.TempDir()
+	req := newGoldenTestRequest(t, "A", "B")
+
+	path, err := CaptureRequest(req, dir)
+	core.AssertMustNoError(t, err, "CaptureRequest")
+	core.AssertTrue(t, strings.HasSuffix(path, ".pb"), "binary dump")
+
+	for _, name := range []string{path, strings.TrimSuffix(path, ".pb") + ".txtpb"} {
+		loaded, err := LoadRequest(name)
+		core.AssertMustNoError(t, err, "LoadRequest")
+		core.AssertTrue(t, proto.Equal(req, loaded), "%s round-trip", filepath.Base(name))
+	}
+
+	resp, err := ReplayRequest(path, listMessagesPlugin)
+	core.AssertMustNoError(t, err, "ReplayRequest")
+	core.AssertEqual(t, "A\nB\n", resp.GetFile()[0].GetContent(), "content")
+}
+
+func TestLoadRequestInvalid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bad.txtpb")
+	core.AssertMustNoError(t, os.WriteFile(path, []byte("nonsense {"), 0o644), "write")
+
+	_, err := LoadRequest(path)
+	core.AssertError(t, err, "invalid text")
+
+	_, err = LoadRequest(filepath.Join(dir, "missing.pb"))
+	core.AssertError(t, err, "missing file")
+}
+
+func TestWithCapture(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(CaptureEnv, "")
+
+	var got string
+	plugin := WithCapture(func(req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+		got = req.GetParameter()
+		return listMessagesPlugin(req)
+	})
+
+	req := newGoldenTestRequest(t, "A")
+	req.Parameter = proto.String("paths=source_relative,capture=" + dir)
+	_, err := plugin(req)
+	core.AssertMustNoError(t, err, "plugin")
+	core.AssertEqual(t, "paths=source_relative", got, "parameter")
+	core.AssertEqual(t, "paths=source_relative,capture="+dir, req.GetParameter(), "request unchanged")
+
+	dumps, err := filepath.Glob(filepath.Join(dir, "request-*.pb"))
+	core.AssertMustNoError(t, err, "glob")
+	core.AssertMustEqual(t, 1, len(dumps), "dumps")
+
+	loaded, err := LoadRequest(dumps[0])
+	core.AssertMustNoError(t, err, "LoadRequest")
+	core.AssertTrue(t, proto.Equal(req, loaded), "captured as received")
+}
@@ -0,0 +1,281 @@
+package generator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// DefaultValue is the parsed default_value of a proto2 field.
+type DefaultValue struct {
+	// Value is int64 for signed integers, uint64 for unsigned ones,
+	// float64, bool, string, []byte, or the int32 number of enum values.
+	Value any
+	// EnumName is the name of the value of enum fields.
+	EnumName string
+	// Type is the type of the field.
+	Type descriptorpb.FieldDescriptorProto_Type
+}
+
+// ParseDefault parses the default_value of a field into a typed value,
+// returning false when the field has no default. Enum defaults are
+// resolved using the registry.
+func ParseDefault(field *descriptorpb.FieldDescriptorProto, r *Registry) (DefaultValue, bool, error) {
+	if field == nil || field.DefaultValue == nil {
+		return DefaultValue{}, false, nil
+	}
+
+	s := field.GetDefaultValue()
+	dv := DefaultValue{Type: field.GetType()}
+	v, err := parseDefault(field, s, r, &dv)
+	if err != nil {
+		return DefaultValue{}, false, core.Wrapf(err, "field %q: invalid default %q", field.GetName(), s)
+	}
+	dv.Value = v
+	return dv, true, nil
+}
+
+func parseDefault(field *descriptorpb.FieldDescriptorProto, s string, r *Registry, dv *DefaultValue) (any, error) {
+	switch field.GetType() {
+	case TypeInt32, TypeSInt32, TypeSFixed32:
+		return strconv.ParseInt(s, 10, 32)
+	case TypeInt64, TypeSInt64, TypeSFixed64:
+		return strconv.ParseInt(s, 10, 64)
+	case TypeUInt32, TypeFixed32:
+		return strconv.ParseUint(s, 10, 32)
+	case TypeUInt64, TypeFixed64:
+		return strconv.ParseUint(s, 10, 64)
+	case TypeFloat:
+		return parseDefaultFloat(s, 32)
+	case TypeDouble:
+		return parseDefaultFloat(s, 64)
+	case TypeBool:
+		return parseDefaultBool(s)
+	case TypeString:
+		return s, nil
+	case TypeBytes:
+		return cUnescape(s)
+	case TypeEnum:
+		return parseDefaultEnum(field, s, r, dv)
+	default:
+		return nil, core.Wrapf(core.ErrInvalid, "%v fields can't have defaults", field.GetType())
+	}
+}
+
+func parseDefaultFloat(s string, bitSize int) (float64, error) {
+	switch s {
+	case "inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan":
+		return math.NaN(), nil
+	default:
+		return strconv.ParseFloat(s, bitSize)
+	}
+}
+
+func parseDefaultBool(s string) (bool, error) {
+	switch s {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	default:
+		return false, core.Wrap(core.ErrInvalid, "not a bool")
+	}
+}
+
+func parseDefaultEnum(field *descriptorpb.FieldDescriptorProto, s string, r *Registry,
+	dv *DefaultValue) (int32, error) {
+	if r == nil {
+		return 0, core.Wrap(core.ErrInvalid, "no registry to resolve enum")
+	}
+	enum, ok := r.Enum(field.GetTypeName())
+	if !ok {
+		return 0, core.Wrapf(core.ErrNotExists, "enum %q not found", field.GetTypeName())
+	}
+	for _, v := range enum.Value {
+		if v.GetName() == s {
+			dv.EnumName = s
+			return v.GetNumber(), nil
+		}
+	}
+	return 0, core.Wrapf(core.ErrNotExists, "enum %q has no value %q", field.GetTypeName(), s)
+}
+
+// cUnescape decodes the C escapes protoc uses for bytes defaults.
+func cUnescape(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			out = append(out, s[i])
+			continue
+		}
+
+		i++
+		if i == len(s) {
+			return nil, core.Wrap(core.ErrInvalid, "trailing backslash")
+		}
+		c, n, err := cUnescapeSequence(s[i:])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+		i += n - 1
+	}
+	return out, nil
+}
+
+// cUnescapeSequence decodes an escape without its backslash, returning
+// the byte and the length consumed.
+func cUnescapeSequence(s string) (byte, int, error) {
+	if r, ok := simpleEscapes[s[0]]; ok {
+		return r, 1, nil
+	}
+
+	base, start, maxDigits := 8, 0, 3
+	if s[0] == 'x' || s[0] == 'X' {
+		base, start, maxDigits = 16, 1, 2
+	}
+
+	var v uint32
+	n := start
+	for n < len(s) && n-start < maxDigits {
+		d, ok := digitValue(s[n], base)
+		if !ok {
+			break
+		}
+		v = v*uint32(base) + d
+		n++
+	}
+	if n == start || v > math.MaxUint8 {
+		return 0, 0, core.Wrapf(core.ErrInvalid, "invalid escape \\%s", s[:max(n, 1)])
+	}
+	return byte(v), n, nil
+}
+
+//
+// Literals
+//
+
+// GoLiteral renders the value as a Go expression. Enum values are
+// rendered as their numbers, and non-finite floats use the math package.
+func (dv DefaultValue) GoLiteral() string {
+	switch v := dv.Value.(type) {
+	case float64:
+		if s, ok := nonFiniteLiteral(v, "math.Inf(1)", "math.Inf(-1)", "math.NaN()"); ok {
+			return s
+		}
+		return dv.formatFloat(v)
+	case string:
+		return strconv.Quote(v)
+	case []byte:
+		return "[]byte(" + strconv.Quote(string(v)) + ")"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// TSLiteral renders the value as a TypeScript expression. 64-bit
+// integers are bigint literals, and enum values their numbers.
+func (dv DefaultValue) TSLiteral() string {
+	switch v := dv.Value.(type) {
+	case int64:
+		return dv.tsInteger(strconv.FormatInt(v, 10))
+	case uint64:
+		return dv.tsInteger(strconv.FormatUint(v, 10))
+	case float64:
+		if s, ok := nonFiniteLiteral(v, "Infinity", "-Infinity", "NaN"); ok {
+			return s
+		}
+		return dv.formatFloat(v)
+	case string:
+		return jsonString(v)
+	case []byte:
+		return "new Uint8Array([" + joinBytes(v) + "])"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (dv DefaultValue) tsInteger(s string) string {
+	if dv.is64Bit() {
+		return s + "n"
+	}
+	return s
+}
+
+// JSONLiteral renders the value following the proto3 JSON mapping:
+// 64-bit integers and non-finite floats are strings, bytes are base64
+// and enum values their names.
+func (dv DefaultValue) JSONLiteral() string {
+	switch v := dv.Value.(type) {
+	case int64, uint64:
+		if dv.is64Bit() {
+			return `"` + fmt.Sprint(v) + `"`
+		}
+		return fmt.Sprint(v)
+	case float64:
+		if s, ok := nonFiniteLiteral(v, `"Infinity"`, `"-Infinity"`, `"NaN"`); ok {
+			return s
+		}
+		return dv.formatFloat(v)
+	case string:
+		return jsonString(v)
+	case []byte:
+		return `"` + base64.StdEncoding.EncodeToString(v) + `"`
+	case int32:
+		return jsonString(dv.EnumName)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (dv DefaultValue) is64Bit() bool {
+	switch dv.Type {
+	case TypeInt64, TypeSInt64, TypeSFixed64, TypeUInt64, TypeFixed64:
+		return true
+	default:
+		return false
+	}
+}
+
+func (dv DefaultValue) formatFloat(f float64) string {
+	if dv.Type == TypeFloat {
+		return strconv.FormatFloat(f, 'g', -1, 32)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func nonFiniteLiteral(f float64, inf, negInf, nan string) (string, bool) {
+	switch {
+	case math.IsInf(f, 1):
+		return inf, true
+	case math.IsInf(f, -1):
+		return negInf, true
+	case math.IsNaN(f):
+		return nan, true
+	default:
+		return "", false
+	}
+}
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+func joinBytes(b []byte) string {
+	parts := make([]string, len(b))
+	for i, c := range b {
+		parts[i] = strconv.Itoa(int(c))
+	}
+	return strings.Join(parts, ", ")
+}
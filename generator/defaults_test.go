@@ -0,0 +1,137 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type defaultLiteralTestCase struct {
+	name   string
+	value  string
+	goLit  string
+	tsLit  string
+	jsLit  string
+	fieldT descriptorpb.FieldDescriptorProto_Type
+}
+
+func newDefaultLiteralTestCase(name string, fieldT descriptorpb.FieldDescriptorProto_Type,
+	value, goLit, tsLit, jsLit string) defaultLiteralTestCase {
+	return defaultLiteralTestCase{
+		name:   name,
+		value:  value,
+		goLit:  goLit,
+		tsLit:  tsLit,
+		jsLit:  jsLit,
+		fieldT: fieldT,
+	}
+}
+
+func (tc defaultLiteralTestCase) Name() string {
+	return tc.name
+}
+
+func (tc defaultLiteralTestCase) Test(t *testing.T) {
+	t.Helper()
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:         proto.String("f"),
+		Type:         tc.fieldT.Enum(),
+		DefaultValue: proto.String(tc.value),
+	}
+
+	dv, ok, err := ParseDefault(field, nil)
+	core.AssertMustNoError(t, err, "ParseDefault")
+	core.AssertMustTrue(t, ok, "has default")
+	core.AssertEqual(t, tc.goLit, dv.GoLiteral(), "Go")
+	core.AssertEqual(t, tc.tsLit, dv.TSLiteral(), "TypeScript")
+	core.AssertEqual(t, tc.jsLit, dv.JSONLiteral(), "JSON")
+}
+
+var _ core.TestCase = defaultLiteralTestCase{}
+
+func defaultLiteralTestCases() []defaultLiteralTestCase {
+	return []defaultLiteralTestCase{
+		newDefaultLiteralTestCase("int32", TypeInt32, "-42", "-42", "-42", "-42"),
+		newDefaultLiteralTestCase("sint64", TypeSInt64, "-9000000000",
+			"-9000000000", "-9000000000n", `"-9000000000"`),
+		newDefaultLiteralTestCase("uint64", TypeUInt64, "18446744073709551615",
+			"18446744073709551615", "18446744073709551615n", `"18446744073709551615"`),
+		newDefaultLiteralTestCase("fixed32", TypeFixed32, "7", "7", "7", "7"),
+		newDefaultLiteralTestCase("float", TypeFloat, "1.1", "1.1", "1.1", "1.1"),
+		newDefaultLiteralTestCase("double exponent", TypeDouble, "1e+100", "1e+100", "1e+100", "1e+100"),
+		newDefaultLiteralTestCase("inf", TypeDouble, "inf", "math.Inf(1)", "Infinity", `"Infinity"`),
+		newDefaultLiteralTestCase("-inf", TypeFloat, "-inf", "math.Inf(-1)", "-Infinity", `"-Infinity"`),
+		newDefaultLiteralTestCase("nan", TypeDouble, "nan", "math.NaN()", "NaN", `"NaN"`),
+		newDefaultLiteralTestCase("bool", TypeBool, "true", "true", "true", "true"),
+		newDefaultLiteralTestCase("string", TypeString, `say "hi"`,
+			`"say \"hi\""`, `"say \"hi\""`, `"say \"hi\""`),
+		newDefaultLiteralTestCase("bytes", TypeBytes, `a\000\n\'\x7f`,
+			`[]byte("a\x00\n'\x7f")`, "new Uint8Array([97, 0, 10, 39, 127])", `"YQAKJ38="`),
+		newDefaultLiteralTestCase("empty bytes", TypeBytes, "", `[]byte("")`, "new Uint8Array([])", `""`),
+	}
+}
+
+func TestParseDefault(t *testing.T) {
+	core.RunTestCases(t, defaultLiteralTestCases())
+}
+
+func TestParseDefaultEnum(t *testing.T) {
+	file, err := ParseProto("defaults.proto", `syntax = "proto2";
+package d;
+enum Color { RED = 1; GREEN = 2; }
+message M { optional Color c = 1 [default = GREEN]; }
+`)
+	core.AssertMustNoError(t, err, "ParseProto")
+
+	field := file.MessageType[0].Field[0]
+	dv, ok, err := ParseDefault(field, NewRegistry(file))
+	core.AssertMustNoError(t, err, "ParseDefault")
+	core.AssertMustTrue(t, ok, "has default")
+	core.AssertEqual[any](t, int32(2), dv.Value, "number")
+	core.AssertEqual(t, "GREEN", dv.EnumName, "name")
+	core.AssertEqual(t, "2", dv.GoLiteral(), "Go")
+	core.AssertEqual(t, "2", dv.TSLiteral(), "TypeScript")
+	core.AssertEqual(t, `"GREEN"`, dv.JSONLiteral(), "JSON")
+
+	_, _, err = ParseDefault(field, nil)
+	core.AssertErrorIs(t, err, core.ErrInvalid, "no registry")
+
+	field.DefaultValue = proto.String("BLUE")
+	_, _, err = ParseDefault(field, NewRegistry(file))
+	core.AssertErrorIs(t, err, core.ErrNotExists, "unknown value")
+}
+
+func TestParseDefaultNone(t *testing.T) {
+	_, ok, err := ParseDefault(&descriptorpb.FieldDescriptorProto{Type: TypeInt32.Enum()}, nil)
+	core.AssertNoError(t, err, "no default")
+	core.AssertFalse(t, ok, "no default")
+}
+
+func TestParseDefaultErrors(t *testing.T) {
+	for _, tc := range []struct {
+		value  string
+		fieldT descriptorpb.FieldDescriptorProto_Type
+	}{
+		{"abc", TypeInt32},
+		{"2147483648", TypeInt32},
+		{"-1", TypeUInt64},
+		{"1.5", TypeInt64},
+		{"yes", TypeBool},
+		{"1e400", TypeDouble},
+		{`\x`, TypeBytes},
+		{`\777`, TypeBytes},
+		{`abc\`, TypeBytes},
+		{`\q`, TypeBytes},
+		{"{}", TypeMessage},
+	} {
+		field := &descriptorpb.FieldDescriptorProto{
+			Name:         proto.String("f"),
+			Type:         tc.fieldT.Enum(),
+			DefaultValue: proto.String(tc.value),
+		}
+		_, _, err := ParseDefault(field, nil)
+		core.AssertError(t, err, tc.value)
+	}
+}
@@ -0,0 +1,435 @@
+package generator
+
+import (
+	"sort"
+	"strings"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// FileGraph is the import graph of a complete set of files, such as
+// the proto_file list of a CodeGeneratorRequest.
+type FileGraph struct {
+	registry   *Registry
+	dependents map[string][]string
+	index      map[string]int
+	files      []*descriptorpb.FileDescriptorProto
+}
+
+// NewFileGraph builds the import graph of the given files. Every
+// imported file must be part of the set, and imports can't form cycles.
+func NewFileGraph(files []*descriptorpb.FileDescriptorProto) (*FileGraph, error) {
+	g := &FileGraph{
+		registry:   NewRegistry(),
+		dependents: make(map[string][]string),
+		index:      make(map[string]int),
+	}
+
+	for _, file := range files {
+		switch {
+		case file == nil:
+			return nil, core.Wrap(core.ErrInvalid, "nil file")
+		case file.GetName() == "":
+			return nil, core.Wrap(core.ErrInvalid, "file without name")
+		}
+		if _, dup := g.registry.File(file.GetName()); dup {
+			return nil, core.Wrapf(core.ErrInvalid, "duplicate file %q", file.GetName())
+		}
+		g.registry.addFile(file)
+	}
+
+	if err := g.sort(files); err != nil {
+		return nil, err
+	}
+
+	for _, file := range g.files {
+		for _, dep := range file.Dependency {
+			g.dependents[dep] = append(g.dependents[dep], file.GetName())
+		}
+	}
+	return g, nil
+}
+
+// sort orders the files topologically, keeping the given order
+// whenever possible.
+func (g *FileGraph) sort(files []*descriptorpb.FileDescriptorProto) error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var stack []string
+
+	var visit func(file *descriptorpb.FileDescriptorProto) error
+	visit = func(file *descriptorpb.FileDescriptorProto) error {
+		name := file.GetName()
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return core.Wrapf(core.ErrInvalid, "import cycle: %s", importCycle(stack, name))
+		}
+
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range file.Dependency {
+			depFile, ok := g.registry.File(dep)
+			if !ok {
+				return core.Wrapf(core.ErrNotExists, "%q imports unknown file %q", name, dep)
+			}
+			if err := visit(depFile); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+
+		g.index[name] = len(g.files)
+		g.files = append(g.files, file)
+		return nil
+	}
+
+	for _, file := range files {
+		if err := visit(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func importCycle(stack []string, name string) string {
+	for i, s := range stack {
+		if s == name {
+			return strings.Join(append(core.SliceCopy(stack[i:]), name), " -> ")
+		}
+	}
+	return name
+}
+
+// Files returns the files in topological order, dependencies first.
+func (g *FileGraph) Files() []*descriptorpb.FileDescriptorProto {
+	return core.SliceCopy(g.files)
+}
+
+// File returns the file with the given name.
+func (g *FileGraph) File(name string) (*descriptorpb.FileDescriptorProto, bool) {
+	return g.registry.File(name)
+}
+
+// Registry returns the index of the types declared by the files.
+func (g *FileGraph) Registry() *Registry {
+	return g.registry
+}
+
+// Imports returns the files directly imported by the named file.
+func (g *FileGraph) Imports(name string) []string {
+	file, ok := g.File(name)
+	if !ok {
+		return nil
+	}
+	return core.SliceCopy(file.Dependency)
+}
+
+// PublicImports returns the files publicly imported by the named file.
+func (g *FileGraph) PublicImports(name string) []string {
+	file, ok := g.File(name)
+	if !ok {
+		return nil
+	}
+	return importsByIndex(file, file.PublicDependency)
+}
+
+// WeakImports returns the files weakly imported by the named file.
+func (g *FileGraph) WeakImports(name string) []string {
+	file, ok := g.File(name)
+	if !ok {
+		return nil
+	}
+	return importsByIndex(file, file.WeakDependency)
+}
+
+func importsByIndex(file *descriptorpb.FileDescriptorProto, indices []int32) []string {
+	out := make([]string, 0, len(indices))
+	for _, i := range indices {
+		if int(i) < len(file.Dependency) {
+			out = append(out, file.Dependency[i])
+		}
+	}
+	return out
+}
+
+// Dependents returns the files directly importing the named file,
+// in topological order.
+func (g *FileGraph) Dependents(name string) []string {
+	return core.SliceCopy(g.dependents[name])
+}
+
+// TransitiveImports returns every file the named file depends on,
+// directly or indirectly, in topological order.
+func (g *FileGraph) TransitiveImports(name string) []string {
+	return g.closure(name, g.Imports)
+}
+
+// TransitiveDependents returns every file depending on the named file,
+// directly or indirectly, in topological order.
+func (g *FileGraph) TransitiveDependents(name string) []string {
+	return g.closure(name, g.Dependents)
+}
+
+// VisibleImports returns the files whose declarations the named file
+// can use: its direct imports and, recursively, their public imports.
+func (g *FileGraph) VisibleImports(name string) []string {
+	seen := make(map[string]bool)
+	for _, dep := range g.Imports(name) {
+		g.addExported(dep, seen)
+	}
+	return g.sorted(seen)
+}
+
+// addExported adds a file and the files it re-exports to the set.
+func (g *FileGraph) addExported(name string, seen map[string]bool) {
+	if seen[name] {
+		return
+	}
+	seen[name] = true
+	for _, dep := range g.PublicImports(name) {
+		g.addExported(dep, seen)
+	}
+}
+
+// DependsOn tells if the named file imports dep, directly or indirectly.
+func (g *FileGraph) DependsOn(name, dep string) bool {
+	return core.SliceContains(g.TransitiveImports(name), dep)
+}
+
+func (g *FileGraph) closure(name string, next func(string) []string) []string {
+	seen := make(map[string]bool)
+	var visit func(string)
+	visit = func(s string) {
+		for _, n := range next(s) {
+			if !seen[n] {
+				seen[n] = true
+				visit(n)
+			}
+		}
+	}
+	visit(name)
+	delete(seen, name)
+	return g.sorted(seen)
+}
+
+// sorted returns the names in the set in topological order.
+func (g *FileGraph) sorted(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for name := range set {
+		out = append(out, name)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return g.index[out[i]] < g.index[out[j]]
+	})
+	return out
+}
+
+//
+// Import checks
+//
+
+// ImportReport describes the import problems of a file.
+type ImportReport struct {
+	// File is the name of the file checked.
+	File string
+	// Unused lists non-public imports none of whose declarations are used.
+	Unused []string
+	// Missing lists references to declarations the file can't see.
+	Missing []MissingImport
+}
+
+// IsEmpty tells if the report found no problems.
+func (r ImportReport) IsEmpty() bool {
+	return len(r.Unused) == 0 && len(r.Missing) == 0
+}
+
+// MissingImport is a reference to a declaration in a file that isn't
+// imported, directly or through public imports.
+type MissingImport struct {
+	// Name is the fully-qualified name referenced, with leading dot.
+	Name string
+	// File is the file declaring it, empty when unknown.
+	File string
+}
+
+// CheckImports reports the unused and missing imports of the named file,
+// based on the resolved type names, extendees and method types it
+// references, and the extensions used in its options. Options stored
+// as unknown fields can't be attributed and are ignored.
+func (g *FileGraph) CheckImports(name string) ImportReport {
+	report := ImportReport{File: name}
+	file, ok := g.File(name)
+	if !ok {
+		return report
+	}
+
+	visible := make(map[string]bool)
+	for _, dep := range g.VisibleImports(name) {
+		visible[dep] = true
+	}
+
+	used := make(map[string]bool)
+	for _, ref := range fileReferences(file) {
+		owner, known := g.registry.FileOf(ref)
+		switch {
+		case owner == name:
+		case known && visible[owner]:
+			used[owner] = true
+		default:
+			report.Missing = append(report.Missing, MissingImport{Name: ref, File: owner})
+		}
+	}
+
+	report.Unused = g.unusedImports(file, used)
+	return report
+}
+
+func (g *FileGraph) unusedImports(file *descriptorpb.FileDescriptorProto, used map[string]bool) []string {
+	public := importsByIndex(file, file.PublicDependency)
+
+	var out []string
+	for _, dep := range file.Dependency {
+		if core.SliceContains(public, dep) {
+			continue
+		}
+
+		exported := make(map[string]bool)
+		g.addExported(dep, exported)
+		if !anyUsed(exported, used) {
+			out = append(out, dep)
+		}
+	}
+	return out
+}
+
+func anyUsed(files, used map[string]bool) bool {
+	for name := range files {
+		if used[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportReports checks every file and returns the reports with
+// problems, in topological order.
+func (g *FileGraph) ImportReports() []ImportReport {
+	var out []ImportReport
+	for _, file := range g.files {
+		if r := g.CheckImports(file.GetName()); !r.IsEmpty() {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+//
+// References
+//
+
+// fileReferences returns the distinct fully-qualified names referenced
+// by a file, with leading dot, in order of appearance.
+func fileReferences(file *descriptorpb.FileDescriptorProto) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name == "" {
+			return
+		}
+		if !strings.HasPrefix(name, ".") {
+			name = "." + name
+		}
+		if !seen[name] {
+			seen[name] = true
+			refs = append(refs, name)
+		}
+	}
+
+	addOptionReferences(file.GetOptions(), add)
+	addFieldReferences(file.Extension, add)
+	addMessageReferences(file.MessageType, add)
+	addEnumReferences(file.EnumType, add)
+	for _, svc := range file.Service {
+		addOptionReferences(svc.GetOptions(), add)
+		for _, method := range svc.Method {
+			add(method.GetInputType())
+			add(method.GetOutputType())
+			addOptionReferences(method.GetOptions(), add)
+		}
+	}
+	return refs
+}
+
+func addMessageReferences(msgs []*descriptorpb.DescriptorProto, add func(string)) {
+	for _, msg := range msgs {
+		addOptionReferences(msg.GetOptions(), add)
+		addFieldReferences(msg.Field, add)
+		addFieldReferences(msg.Extension, add)
+		for _, oneof := range msg.OneofDecl {
+			addOptionReferences(oneof.GetOptions(), add)
+		}
+		for _, r := range msg.ExtensionRange {
+			addOptionReferences(r.GetOptions(), add)
+		}
+		addMessageReferences(msg.NestedType, add)
+		addEnumReferences(msg.EnumType, add)
+	}
+}
+
+func addEnumReferences(enums []*descriptorpb.EnumDescriptorProto, add func(string)) {
+	for _, enum := range enums {
+		addOptionReferences(enum.GetOptions(), add)
+		for _, v := range enum.Value {
+			addOptionReferences(v.GetOptions(), add)
+		}
+	}
+}
+
+func addFieldReferences(fields []*descriptorpb.FieldDescriptorProto, add func(string)) {
+	for _, field := range fields {
+		add(field.GetTypeName())
+		add(field.GetExtendee())
+		addOptionReferences(field.GetOptions(), add)
+	}
+}
+
+// addOptionReferences adds the extensions set on an options message,
+// including those nested in message values.
+func addOptionReferences(opts proto.Message, add func(string)) {
+	if opts == nil {
+		return
+	}
+	if m := opts.ProtoReflect(); m.IsValid() {
+		addExtensionReferences(m, add)
+	}
+}
+
+func addExtensionReferences(m protoreflect.Message, add func(string)) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.IsExtension() {
+			add(string(fd.FullName()))
+		}
+		if fd.Message() == nil || fd.IsMap() {
+			return true
+		}
+
+		if fd.IsList() {
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				addExtensionReferences(list.Get(i).Message(), add)
+			}
+		} else {
+			addExtensionReferences(v.Message(), add)
+		}
+		return true
+	})
+}
@@ -0,0 +1,207 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Compile-time verification that test case types implement TestCase interface
+var _ core.TestCase = fileGraphQueryTestCase{}
+var _ core.TestCase = newFileGraphErrorTestCase{}
+
+func newTestFileGraph(t *testing.T) *FileGraph {
+	t.Helper()
+	p := &Parser{Files: map[string]string{
+		"base.proto": `syntax = "proto3";
+package base;
+message Base {}
+`,
+		"reexport.proto": `syntax = "proto3";
+import public "base.proto";
+`,
+		"opts.proto": `syntax = "proto3";
+package opts;
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.MessageOptions { string label = 50000; }
+`,
+		"unused.proto": `syntax = "proto3";
+package unused;
+message Unused {}
+`,
+		"main.proto": `syntax = "proto3";
+package app;
+import "reexport.proto";
+import "opts.proto";
+import "unused.proto";
+message Main {
+  option (opts.label) = "main";
+  base.Base base = 1;
+}
+`,
+	}}
+
+	files, err := p.ParseWithImports("main.proto")
+	core.AssertMustNoError(t, err, "ParseWithImports")
+
+	// reverse the order to check sorting
+	for i, j := 0, len(files)-1; i < j; i, j = i+1, j-1 {
+		files[i], files[j] = files[j], files[i]
+	}
+
+	g, err := NewFileGraph(files)
+	core.AssertMustNoError(t, err, "NewFileGraph")
+	return g
+}
+
+type fileGraphQueryTestCase struct {
+	query    func(*FileGraph, string) []string
+	name     string
+	file     string
+	expected []string
+}
+
+func newFileGraphQueryTestCase(name, file string, query func(*FileGraph, string) []string,
+	expected ...string) fileGraphQueryTestCase {
+	return fileGraphQueryTestCase{
+		name:     name,
+		file:     file,
+		query:    query,
+		expected: expected,
+	}
+}
+
+func (tc fileGraphQueryTestCase) Name() string {
+	return tc.name
+}
+
+func (tc fileGraphQueryTestCase) Test(t *testing.T) {
+	t.Helper()
+	g := newTestFileGraph(t)
+	got := tc.query(g, tc.file)
+	if len(tc.expected) == 0 {
+		core.AssertEqual(t, 0, len(got), "result")
+		return
+	}
+	core.AssertSliceEqual(t, tc.expected, got, "result")
+}
+
+func TestFileGraphQueries(t *testing.T) {
+	testCases := []fileGraphQueryTestCase{
+		newFileGraphQueryTestCase("imports", "main.proto", (*FileGraph).Imports,
+			"reexport.proto", "opts.proto", "unused.proto"),
+		newFileGraphQueryTestCase("public imports", "reexport.proto", (*FileGraph).PublicImports,
+			"base.proto"),
+		newFileGraphQueryTestCase("weak imports", "main.proto", (*FileGraph).WeakImports),
+		newFileGraphQueryTestCase("dependents", "base.proto", (*FileGraph).Dependents,
+			"reexport.proto"),
+		newFileGraphQueryTestCase("transitive imports", "main.proto", (*FileGraph).TransitiveImports,
+			"base.proto", "reexport.proto", "google/protobuf/descriptor.proto", "opts.proto", "unused.proto"),
+		newFileGraphQueryTestCase("transitive dependents", "base.proto", (*FileGraph).TransitiveDependents,
+			"reexport.proto", "main.proto"),
+		newFileGraphQueryTestCase("visible imports", "main.proto", (*FileGraph).VisibleImports,
+			"base.proto", "reexport.proto", "opts.proto", "unused.proto"),
+		newFileGraphQueryTestCase("unknown file", "missing.proto", (*FileGraph).Imports),
+	}
+
+	core.RunTestCases(t, testCases)
+}
+
+func TestFileGraphOrder(t *testing.T) {
+	g := newTestFileGraph(t)
+
+	seen := make(map[string]bool)
+	for _, file := range g.Files() {
+		for _, dep := range file.Dependency {
+			core.AssertTrue(t, seen[dep], "%s before %s", dep, file.GetName())
+		}
+		seen[file.GetName()] = true
+	}
+	core.AssertEqual(t, 6, len(seen), "files")
+
+	core.AssertTrue(t, g.DependsOn("main.proto", "base.proto"), "main depends on base")
+	core.AssertFalse(t, g.DependsOn("base.proto", "main.proto"), "base depends on main")
+}
+
+func TestFileGraphCheckImports(t *testing.T) {
+	g := newTestFileGraph(t)
+
+	report := g.CheckImports("main.proto")
+	core.AssertSliceEqual(t, []string{"unused.proto"}, report.Unused, "unused")
+	core.AssertEqual(t, 0, len(report.Missing), "missing")
+
+	reports := g.ImportReports()
+	core.AssertEqual(t, 1, len(reports), "reports")
+	core.AssertEqual(t, "main.proto", reports[0].File, "report file")
+}
+
+func TestFileGraphMissingImports(t *testing.T) {
+	base := NewFileWithTypes("base.proto", "base",
+		[]*descriptorpb.DescriptorProto{NewMessage("Base")}, nil, nil)
+	middle := NewFile("middle.proto", "middle")
+	middle.Dependency = []string{"base.proto"}
+	main := NewFileWithTypes("main.proto", "app",
+		[]*descriptorpb.DescriptorProto{NewMessage("Main",
+			NewMessageField("base", 1, ".base.Base"),
+			NewMessageField("other", 2, ".other.Other"))}, nil, nil)
+	main.Dependency = []string{"middle.proto"}
+
+	g, err := NewFileGraph([]*descriptorpb.FileDescriptorProto{base, middle, main})
+	core.AssertMustNoError(t, err, "NewFileGraph")
+
+	report := g.CheckImports("main.proto")
+	core.AssertSliceEqual(t, []MissingImport{
+		{Name: ".base.Base", File: "base.proto"},
+		{Name: ".other.Other"},
+	}, report.Missing, "missing")
+	core.AssertSliceEqual(t, []string{"middle.proto"}, report.Unused, "unused")
+	core.AssertFalse(t, report.IsEmpty(), "IsEmpty")
+}
+
+type newFileGraphErrorTestCase struct {
+	target error
+	name   string
+	files  []*descriptorpb.FileDescriptorProto
+}
+
+func newNewFileGraphErrorTestCase(name string, target error,
+	files ...*descriptorpb.FileDescriptorProto) newFileGraphErrorTestCase {
+	return newFileGraphErrorTestCase{
+		name:   name,
+		target: target,
+		files:  files,
+	}
+}
+
+func (tc newFileGraphErrorTestCase) Name() string {
+	return tc.name
+}
+
+func (tc newFileGraphErrorTestCase) Test(t *testing.T) {
+	t.Helper()
+	g, err := NewFileGraph(tc.files)
+	core.AssertErrorIs(t, err, tc.target, "NewFileGraph")
+	core.AssertNil(t, g, "graph")
+}
+
+func newFileWithImports(name string, deps ...string) *descriptorpb.FileDescriptorProto {
+	file := NewFile(name, "")
+	file.Dependency = deps
+	return file
+}
+
+func TestNewFileGraphErrors(t *testing.T) {
+	testCases := []newFileGraphErrorTestCase{
+		newNewFileGraphErrorTestCase("nil file", core.ErrInvalid, nil),
+		newNewFileGraphErrorTestCase("no name", core.ErrInvalid, &descriptorpb.FileDescriptorProto{}),
+		newNewFileGraphErrorTestCase("duplicate", core.ErrInvalid,
+			newFileWithImports("a.proto"), newFileWithImports("a.proto")),
+		newNewFileGraphErrorTestCase("unknown import", core.ErrNotExists,
+			newFileWithImports("a.proto", "b.proto")),
+		newNewFileGraphErrorTestCase("cycle", core.ErrInvalid,
+			newFileWithImports("a.proto", "b.proto"), newFileWithImports("b.proto", "a.proto")),
+	}
+
+	core.RunTestCases(t, testCases)
+}
@@ -0,0 +1,197 @@
+package generator
+
+import (
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// IsDeprecated checks if any kind of descriptor has the deprecated
+// option set.
+// Returns false for nil descriptors and those without options.
+func IsDeprecated(desc proto.Message) bool {
+	switch d := desc.(type) {
+	case *descriptorpb.FileDescriptorProto:
+		return d.GetOptions().GetDeprecated()
+	case *descriptorpb.DescriptorProto:
+		return d.GetOptions().GetDeprecated()
+	case *descriptorpb.FieldDescriptorProto:
+		return d.GetOptions().GetDeprecated()
+	case *descriptorpb.EnumDescriptorProto:
+		return d.GetOptions().GetDeprecated()
+	case *descriptorpb.EnumValueDescriptorProto:
+		return d.GetOptions().GetDeprecated()
+	case *descriptorpb.ServiceDescriptorProto:
+		return d.GetOptions().GetDeprecated()
+	case *descriptorpb.MethodDescriptorProto:
+		return d.GetOptions().GetDeprecated()
+	default:
+		return false
+	}
+}
+
+// IsDeprecatedFile checks if the descriptor is a deprecated file.
+// Returns true if the descriptor is a FileDescriptorProto with deprecated set, false otherwise.
+func IsDeprecatedFile(desc proto.Message) bool {
+	file, ok := AsFileType(desc)
+	return ok && IsDeprecated(file)
+}
+
+// IsDeprecatedMessage checks if the descriptor is a deprecated message.
+// Returns true if the descriptor is a DescriptorProto with deprecated set, false otherwise.
+func IsDeprecatedMessage(desc proto.Message) bool {
+	msg, ok := AsMessage(desc)
+	return ok && IsDeprecated(msg)
+}
+
+// IsDeprecatedField checks if the descriptor is a deprecated field or extension.
+// Returns true if the descriptor is a FieldDescriptorProto with deprecated set, false otherwise.
+func IsDeprecatedField(desc proto.Message) bool {
+	field, ok := AsFieldType(desc)
+	return ok && IsDeprecated(field)
+}
+
+// IsDeprecatedEnum checks if the descriptor is a deprecated enum.
+// Returns true if the descriptor is an EnumDescriptorProto with deprecated set, false otherwise.
+func IsDeprecatedEnum(desc proto.Message) bool {
+	enum, ok := AsEnumType(desc)
+	return ok && IsDeprecated(enum)
+}
+
+// IsDeprecatedEnumValue checks if the descriptor is a deprecated enum value.
+// Returns true if the descriptor is an EnumValueDescriptorProto with deprecated set, false otherwise.
+func IsDeprecatedEnumValue(desc proto.Message) bool {
+	value, ok := desc.(*descriptorpb.EnumValueDescriptorProto)
+	return ok && IsDeprecated(value)
+}
+
+// IsDeprecatedService checks if the descriptor is a deprecated service.
+// Returns true if the descriptor is a ServiceDescriptorProto with deprecated set, false otherwise.
+func IsDeprecatedService(desc proto.Message) bool {
+	svc, ok := AsServiceType(desc)
+	return ok && IsDeprecated(svc)
+}
+
+// IsDeprecatedMethod checks if the descriptor is a deprecated method.
+// Returns true if the descriptor is a MethodDescriptorProto with deprecated set, false otherwise.
+func IsDeprecatedMethod(desc proto.Message) bool {
+	method, ok := AsMethodType(desc)
+	return ok && IsDeprecated(method)
+}
+
+// DeprecatedElement is a deprecated descriptor reachable from a service.
+type DeprecatedElement struct {
+	// Descriptor is the deprecated descriptor.
+	Descriptor proto.Message
+	// FullName is its fully-qualified name without leading dot, or the
+	// file name for files.
+	FullName string
+	// Kind is the kind of the descriptor.
+	Kind SelectorKind
+	// Via is the full name of the first method reaching the element,
+	// empty for the service, its file and its methods.
+	Via string
+}
+
+// String describes the element for warnings.
+func (e DeprecatedElement) String() string {
+	s := string(e.Kind) + " " + e.FullName + " is deprecated"
+	if e.Via != "" {
+		s += " (used by " + e.Via + ")"
+	}
+	return s
+}
+
+// DeprecationReport lists the deprecated elements reachable from a
+// service: the service, its methods, the messages they take and return,
+// and recursively the fields, messages, enums and enum values these
+// use, together with the files declaring them. Each element is listed
+// once, in the order it's reached.
+func DeprecationReport(r *Registry, service string) ([]DeprecatedElement, error) {
+	svc, ok := r.Service(service)
+	if !ok {
+		return nil, core.Wrapf(core.ErrNotExists, "service %q not found", service)
+	}
+
+	w := &deprecationWalker{r: r, seen: make(map[string]bool)}
+	name := registryKey(service)
+	w.fileOf(name)
+	w.add(svc, name, KindService)
+
+	for _, method := range svc.Method {
+		methodName := joinName(name, method.GetName())
+		w.via = ""
+		w.add(method, methodName, KindMethod)
+		w.via = methodName
+		w.message(method.GetInputType())
+		w.message(method.GetOutputType())
+	}
+	return w.out, nil
+}
+
+type deprecationWalker struct {
+	r    *Registry
+	seen map[string]bool
+	via  string
+	out  []DeprecatedElement
+}
+
+func (w *deprecationWalker) add(desc proto.Message, name string, kind SelectorKind) {
+	if IsDeprecated(desc) {
+		w.out = append(w.out, DeprecatedElement{Descriptor: desc, FullName: name, Kind: kind, Via: w.via})
+	}
+}
+
+// visit tells if a type is seen for the first time, marking it.
+func (w *deprecationWalker) visit(key string) bool {
+	if w.seen[key] {
+		return false
+	}
+	w.seen[key] = true
+	return true
+}
+
+func (w *deprecationWalker) fileOf(name string) {
+	fileName, ok := w.r.FileOf(name)
+	if !ok || !w.visit("file:"+fileName) {
+		return
+	}
+	if file, ok := w.r.File(fileName); ok {
+		w.add(file, fileName, KindFile)
+	}
+}
+
+func (w *deprecationWalker) message(typeName string) {
+	name := registryKey(typeName)
+	msg, ok := w.r.Message(name)
+	if !ok || !w.visit(name) {
+		return
+	}
+
+	w.fileOf(name)
+	w.add(msg, name, KindMessage)
+	for _, field := range msg.Field {
+		w.add(field, joinName(name, field.GetName()), KindField)
+		switch field.GetType() {
+		case TypeMessage, TypeGroup:
+			w.message(field.GetTypeName())
+		case TypeEnum:
+			w.enum(field.GetTypeName())
+		}
+	}
+}
+
+func (w *deprecationWalker) enum(typeName string) {
+	name := registryKey(typeName)
+	enum, ok := w.r.Enum(name)
+	if !ok || !w.visit(name) {
+		return
+	}
+
+	w.fileOf(name)
+	w.add(enum, name, KindEnum)
+	for _, value := range enum.Value {
+		// enum values are scoped as siblings of their enum
+		w.add(value, joinName(parentScope(name), value.GetName()), KindValue)
+	}
+}
@@ -0,0 +1,144 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func deprecatedTestFile() *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("old.proto"),
+		Options: &descriptorpb.FileOptions{Deprecated: proto.Bool(true)},
+	}
+}
+
+func isDeprecatedTestCases() []boolCheckTestCase {
+	deprecatedMessage := NewMessage("Old")
+	deprecatedMessage.Options = &descriptorpb.MessageOptions{Deprecated: proto.Bool(true)}
+	deprecatedField := NewField("old", 1, TypeString)
+	deprecatedField.Options = &descriptorpb.FieldOptions{Deprecated: proto.Bool(true)}
+	deprecatedEnum := NewEnum("Old", "OLD_UNSPECIFIED")
+	deprecatedEnum.Options = &descriptorpb.EnumOptions{Deprecated: proto.Bool(true)}
+	deprecatedValue := NewEnumValue("OLD_VALUE", 1)
+	deprecatedValue.Options = &descriptorpb.EnumValueOptions{Deprecated: proto.Bool(true)}
+	deprecatedService := NewService("Old")
+	deprecatedService.Options = &descriptorpb.ServiceOptions{Deprecated: proto.Bool(true)}
+	deprecatedMethod := NewMethod("Old", ".a.In", ".a.Out")
+	deprecatedMethod.Options = &descriptorpb.MethodOptions{Deprecated: proto.Bool(true)}
+	notDeprecated := NewField("current", 2, TypeString)
+	notDeprecated.Options = &descriptorpb.FieldOptions{Deprecated: proto.Bool(false)}
+
+	return []boolCheckTestCase{
+		newBoolCheckTestCase("file", deprecatedTestFile(), true, IsDeprecatedFile, "IsDeprecatedFile"),
+		newBoolCheckTestCase("message", deprecatedMessage, true, IsDeprecatedMessage, "IsDeprecatedMessage"),
+		newBoolCheckTestCase("field", deprecatedField, true, IsDeprecatedField, "IsDeprecatedField"),
+		newBoolCheckTestCase("enum", deprecatedEnum, true, IsDeprecatedEnum, "IsDeprecatedEnum"),
+		newBoolCheckTestCase("enum value", deprecatedValue, true, IsDeprecatedEnumValue, "IsDeprecatedEnumValue"),
+		newBoolCheckTestCase("service", deprecatedService, true, IsDeprecatedService, "IsDeprecatedService"),
+		newBoolCheckTestCase("method", deprecatedMethod, true, IsDeprecatedMethod, "IsDeprecatedMethod"),
+		newBoolCheckTestCase("any", deprecatedMethod, true, IsDeprecated, "IsDeprecated"),
+		newBoolCheckTestCase("wrong kind", deprecatedMethod, false, IsDeprecatedMessage, "IsDeprecatedMessage"),
+		newBoolCheckTestCase("explicit false", notDeprecated, false, IsDeprecatedField, "IsDeprecatedField"),
+		newBoolCheckTestCase("no options", NewField("f", 1, TypeString), false, IsDeprecatedField,
+			"IsDeprecatedField"),
+		newBoolCheckTestCase("nil", nil, false, IsDeprecated, "IsDeprecated"),
+		newBoolCheckTestCase("oneof", NewOneOf("choice"), false, IsDeprecated, "IsDeprecated"),
+	}
+}
+
+func TestIsDeprecated(t *testing.T) {
+	core.RunTestCases(t, isDeprecatedTestCases())
+}
+
+const deprecationTypesProto = `syntax = "proto3";
+package types;
+
+option deprecated = true;
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_LEGACY = 1 [deprecated = true];
+}
+`
+
+const deprecationAPIProto = `syntax = "proto3";
+package api;
+
+import "types.proto";
+
+message Request {
+  string id = 1;
+  string old_id = 2 [deprecated = true];
+  Filter filter = 3;
+  map<string, Filter> filters = 4;
+}
+
+message Filter {
+  option deprecated = true;
+  types.Status status = 1;
+  Request parent = 2 [deprecated = true];
+}
+
+message Response {
+  string id = 1;
+}
+
+message Unused {
+  option deprecated = true;
+}
+
+service Store {
+  rpc Get(Request) returns (Response);
+  rpc Delete(Request) returns (Response) {
+    option deprecated = true;
+  }
+}
+`
+
+func TestDeprecationReport(t *testing.T) {
+	p := &Parser{Files: map[string]string{
+		"types.proto": deprecationTypesProto,
+		"api.proto":   deprecationAPIProto,
+	}}
+	files, err := p.ParseWithImports("api.proto")
+	core.AssertMustNoError(t, err, "Parse")
+
+	report, err := DeprecationReport(NewRegistry(files...), ".api.Store")
+	core.AssertMustNoError(t, err, "DeprecationReport")
+
+	var got []string
+	for _, e := range report {
+		got = append(got, e.String())
+	}
+	core.AssertSliceEqual(t, []string{
+		"field api.Request.old_id is deprecated (used by api.Store.Get)",
+		"message api.Filter is deprecated (used by api.Store.Get)",
+		"file types.proto is deprecated (used by api.Store.Get)",
+		"value types.STATUS_LEGACY is deprecated (used by api.Store.Get)",
+		"field api.Filter.parent is deprecated (used by api.Store.Get)",
+		"method api.Store.Delete is deprecated",
+	}, got, "report")
+	core.AssertEqual(t, KindMethod, report[5].Kind, "method kind")
+	core.AssertEqual(t, "", report[5].Via, "method via")
+
+	_, err = DeprecationReport(NewRegistry(files...), "api.Missing")
+	core.AssertErrorIs(t, err, core.ErrNotExists, "unknown service")
+}
+
+func TestDeprecationReportService(t *testing.T) {
+	file := File("svc.proto").Package("svc").Options(&descriptorpb.FileOptions{Deprecated: proto.Bool(true)})
+	file.Message("Empty")
+	file.Service("Old").Method("Call", "Empty", "Empty")
+	fd := file.Build()
+	fd.Service[0].Options = &descriptorpb.ServiceOptions{Deprecated: proto.Bool(true)}
+
+	report, err := DeprecationReport(NewRegistry(fd), "svc.Old")
+	core.AssertMustNoError(t, err, "DeprecationReport")
+	core.AssertMustEqual(t, 2, len(report), "report")
+	core.AssertEqual(t, "file svc.proto is deprecated", report[0].String(), "file")
+	core.AssertEqual(t, "service svc.Old is deprecated", report[1].String(), "service")
+	core.AssertTrue(t, report[1].Descriptor == fd.Service[0], "descriptor")
+}
@@ -403,6 +403,30 @@ func IsEnumField(field proto.Message) bool {
 	return ok
 }
 
+// AsExtensionField checks if the field is an extension and returns it as a field descriptor.
+// Extensions are fields with an Extendee, the message they extend.
+// Returns the field descriptor and true if it's an extension, nil and false otherwise.
+func AsExtensionField(field proto.Message) (*descriptorpb.FieldDescriptorProto, bool) {
+	fieldDesc, ok := AsFieldType(field)
+	switch {
+	case !ok:
+		// Wrong type
+		return nil, false
+	case !isPointerNonZero(fieldDesc.Extendee):
+		// Regular field
+		return nil, false
+	default:
+		return fieldDesc, true
+	}
+}
+
+// IsExtensionField checks if the field is an extension.
+// Returns true if the field has an Extendee set, false otherwise.
+func IsExtensionField(field proto.Message) bool {
+	_, ok := AsExtensionField(field)
+	return ok
+}
+
 func isPointerEqual[T comparable](p *T, v T) bool {
 	return p != nil && *p == v
 }
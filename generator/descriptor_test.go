@@ -151,6 +151,11 @@ func newIsGroupFieldTestCase(name string, field proto.Message, expected bool) bo
 	return newBoolCheckTestCase(name, field, expected, IsGroupField, "IsGroupField")
 }
 
+// newIsExtensionFieldTestCase creates a test case for IsExtensionField function
+func newIsExtensionFieldTestCase(name string, field proto.Message, expected bool) boolCheckTestCase {
+	return newBoolCheckTestCase(name, field, expected, IsExtensionField, "IsExtensionField")
+}
+
 // Test functions
 
 func TestIsMessage(t *testing.T) {
@@ -594,3 +599,23 @@ func isEnumFieldTestCases() []boolCheckTestCase {
 func TestIsEnumField(t *testing.T) {
 	core.RunTestCases(t, isEnumFieldTestCases())
 }
+
+func isExtensionFieldTestCases() []boolCheckTestCase {
+	extension := NewExtensionField("ext", 100, TypeString, ".example.Base")
+	emptyExtendee := NewField("field", 1, TypeString)
+	emptyExtendee.Extendee = proto.String("")
+
+	return []boolCheckTestCase{
+		newIsExtensionFieldTestCase("extension", extension, true),
+		newIsExtensionFieldTestCase("message extension",
+			NewMessageExtensionField("ext", 101, ".example.Value", ".example.Base"), true),
+		newIsExtensionFieldTestCase("regular field", NewField("field", 1, TypeString), false),
+		newIsExtensionFieldTestCase("empty extendee", emptyExtendee, false),
+		newIsExtensionFieldTestCase("nil field", nil, false),
+		newIsExtensionFieldTestCase("wrong type", &descriptorpb.DescriptorProto{}, false),
+	}
+}
+
+func TestIsExtensionField(t *testing.T) {
+	core.RunTestCases(t, isExtensionFieldTestCases())
+}
@@ -0,0 +1,214 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines around each change.
+	diffContext = 3
+	// diffMaxEdits is the largest number of removed and added lines
+	// searched for the shortest edit script.
+	diffMaxEdits = 1000
+)
+
+// diffOp is a line of an edit script, kept (' '), removed ('-') or
+// added ('+'). a and b are the indices of the line in the old and
+// new texts before the operation.
+type diffOp struct {
+	text string
+	a, b int
+	kind byte
+}
+
+// UnifiedDiff returns the differences between two texts in unified
+// format, with three lines of context, or an empty string if they're
+// equal.
+func UnifiedDiff(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range diffHunks(ops) {
+		writeHunk(&sb, ops[h[0]:h[1]])
+	}
+	return sb.String()
+}
+
+// splitLines splits a text keeping the line endings, so a last line
+// without newline differs from one with it.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes an edit script using the longest common
+// subsequence of the lines between the common prefix and suffix.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{text: a[i], a: i, b: i, kind: ' '})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix, prefix)...)
+	for i := suffix; i > 0; i-- {
+		ops = append(ops, diffOp{text: a[len(a)-i], a: len(a) - i, b: len(b) - i, kind: ' '})
+	}
+	return ops
+}
+
+// diffMiddle computes an edit script with Myers' algorithm. Texts
+// further apart than diffMaxEdits are reported as replaced as a whole,
+// bounding the time and memory used.
+func diffMiddle(a, b []string, offA, offB int) []diffOp {
+	trace, ok := diffTrace(a, b)
+	if !ok {
+		return diffReplace(a, b, offA, offB)
+	}
+
+	// walk the trace back from the end, collecting the script reversed
+	var ops []diffOp
+	snake := func(x, y, toX, toY int) {
+		for ; x > toX && y > toY; x, y = x-1, y-1 {
+			ops = append(ops, diffOp{text: a[x-1], a: offA + x - 1, b: offB + y - 1, kind: ' '})
+		}
+	}
+
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		v, k := trace[d-1], x-y
+		prevK := k - 1
+		if k == -d || (k != d && v[k-1+d-1] < v[k+1+d-1]) {
+			prevK = k + 1
+		}
+		prevX := v[prevK+d-1]
+		prevY := prevX - prevK
+
+		op := diffOp{a: offA + prevX, b: offB + prevY}
+		if prevK == k+1 {
+			snake(x, y, prevX, prevY+1)
+			op.kind, op.text = '+', b[prevY]
+		} else {
+			snake(x, y, prevX+1, prevY)
+			op.kind, op.text = '-', a[prevX]
+		}
+		ops = append(ops, op)
+		x, y = prevX, prevY
+	}
+	snake(x, y, 0, 0)
+
+	slices.Reverse(ops)
+	return ops
+}
+
+// diffTrace runs the forward pass of Myers' algorithm, returning for
+// each edit distance d the furthest x reached on each diagonal k, at
+// index k+d, or false if the texts are more than diffMaxEdits apart.
+func diffTrace(a, b []string) ([][]int, bool) {
+	var trace [][]int
+	for d := 0; d <= min(len(a)+len(b), diffMaxEdits); d++ {
+		v := make([]int, 2*d+1)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			switch {
+			case d == 0:
+			case k == -d || (k != d && trace[d-1][k-1+d-1] < trace[d-1][k+1+d-1]):
+				x = trace[d-1][k+1+d-1]
+			default:
+				x = trace[d-1][k-1+d-1] + 1
+			}
+			y := x - k
+			for x < len(a) && y < len(b) && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[k+d] = x
+
+			if x >= len(a) && y >= len(b) {
+				return append(trace, v), true
+			}
+		}
+		trace = append(trace, v)
+	}
+	return nil, false
+}
+
+// diffReplace returns an edit script removing all the lines of a and
+// adding those of b.
+func diffReplace(a, b []string, offA, offB int) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i, line := range a {
+		ops = append(ops, diffOp{text: line, a: offA + i, b: offB, kind: '-'})
+	}
+	for j, line := range b {
+		ops = append(ops, diffOp{text: line, a: offA + len(a), b: offB + j, kind: '+'})
+	}
+	return ops
+}
+
+// diffHunks groups the changes of an edit script into ranges of
+// operations, merging those whose context overlaps.
+func diffHunks(ops []diffOp) [][2]int {
+	var hunks [][2]int
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+
+		start, end := max(i-diffContext, 0), min(i+1+diffContext, len(ops))
+		if n := len(hunks); n > 0 && start <= hunks[n-1][1] {
+			hunks[n-1][1] = end
+		} else {
+			hunks = append(hunks, [2]int{start, end})
+		}
+	}
+	return hunks
+}
+
+func writeHunk(sb *strings.Builder, ops []diffOp) {
+	var lenA, lenB int
+	for _, op := range ops {
+		if op.kind != '+' {
+			lenA++
+		}
+		if op.kind != '-' {
+			lenB++
+		}
+	}
+
+	_, _ = fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(ops[0].a, lenA), hunkRange(ops[0].b, lenB))
+	for _, op := range ops {
+		_ = sb.WriteByte(op.kind)
+		_, _ = sb.WriteString(op.text)
+		if !strings.HasSuffix(op.text, "\n") {
+			_, _ = sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
@@ -0,0 +1,108 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"darvaza.org/core"
+)
+
+type unifiedDiffTestCase struct {
+	name     string
+	oldText  string
+	newText  string
+	expected string
+}
+
+func (tc unifiedDiffTestCase) Name() string {
+	return tc.name
+}
+
+func (tc unifiedDiffTestCase) Test(t *testing.T) {
+	t.Helper()
+	core.AssertEqual(t, tc.expected, UnifiedDiff("a", "b", tc.oldText, tc.newText), "diff")
+}
+
+var _ core.TestCase = unifiedDiffTestCase{}
+
+func diffTestLines(n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = string(rune('a' + i))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	long := diffTestLines(12)
+	core.RunTestCases(t, []unifiedDiffTestCase{
+		{"equal", "x\n", "x\n", ""},
+		{"change", "a\nb\nc\n", "a\nB\nc\n",
+			"--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+		{"insert at start", "b\n", "a\nb\n",
+			"--- a\n+++ b\n@@ -1 +1,2 @@\n+a\n b\n"},
+		{"from empty", "", "a\n",
+			"--- a\n+++ b\n@@ -0,0 +1 @@\n+a\n"},
+		{"no newline", "a\n", "a",
+			"--- a\n+++ b\n@@ -1 +1 @@\n-a\n+a\n\\ No newline at end of file\n"},
+		{"two hunks", long, strings.Replace(strings.Replace(long, "b\n", "B\n", 1), "k\n", "K\n", 1),
+			"--- a\n+++ b\n@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+				"@@ -8,5 +8,5 @@\n h\n i\n j\n-k\n+K\n l\n"},
+		{"merged hunks", long, strings.Replace(strings.Replace(long, "b\n", "B\n", 1), "h\n", "H\n", 1),
+			"--- a\n+++ b\n@@ -1,11 +1,11 @@\n a\n-b\n+B\n c\n d\n e\n f\n g\n-h\n+H\n i\n j\n k\n"},
+		{"removal", "a\nb\nc\n", "a\nc\n",
+			"--- a\n+++ b\n@@ -1,3 +1,2 @@\n a\n-b\n c\n"},
+	})
+}
+
+func TestDiffLines(t *testing.T) {
+	for _, tc := range []struct{ a, b string }{
+		{"abcabba", "cbabac"},
+		{"xaxbxcx", "abc"},
+		{"", "abc"},
+		{"abc", ""},
+	} {
+		a, b := strings.Split(tc.a, ""), strings.Split(tc.b, "")
+		gotA, gotB := []string{}, []string{}
+		kept := 0
+		for _, op := range diffLines(a, b) {
+			if op.kind != '+' {
+				core.AssertEqual(t, len(gotA), op.a, "%s: old index", tc.a)
+				gotA = append(gotA, op.text)
+			}
+			if op.kind != '-' {
+				core.AssertEqual(t, len(gotB), op.b, "%s: new index", tc.b)
+				gotB = append(gotB, op.text)
+			}
+			if op.kind == ' ' {
+				kept++
+			}
+		}
+		core.AssertSliceEqual(t, a, gotA, "%s: old text", tc.a)
+		core.AssertSliceEqual(t, b, gotB, "%s: new text", tc.b)
+		if tc.a == "abcabba" {
+			core.AssertEqual(t, 4, kept, "longest common subsequence")
+		}
+	}
+}
+
+func TestUnifiedDiffLarge(t *testing.T) {
+	const n = 20000
+	var oldText, newText strings.Builder
+	for i := range n {
+		_, _ = fmt.Fprintf(&oldText, "old %d\n", i)
+		_, _ = fmt.Fprintf(&newText, "new %d\n", i)
+	}
+
+	// too far apart for the shortest script, replaced as a whole
+	diff := UnifiedDiff("a", "b", oldText.String(), newText.String())
+	core.AssertTrue(t, strings.HasPrefix(diff, "--- a\n+++ b\n@@ -1,20000 +1,20000 @@\n-old 0\n"), "single hunk")
+	core.AssertEqual(t, 2*n+3, strings.Count(diff, "\n"), "lines")
+
+	// few changes in a long text keep the shortest script
+	changed := strings.Replace(oldText.String(), "old 10000\n", "new 10000\n", 1)
+	diff = UnifiedDiff("a", "b", oldText.String(), changed)
+	core.AssertContains(t, diff, "@@ -9998,7 +9998,7 @@\n old 9997\n old 9998\n old 9999\n-old 10000\n+new 10000\n",
+		"single change")
+}
@@ -22,6 +22,19 @@
 //   - AsOneOfField, IsOneOfField - for oneof fields
 //   - AsOptionalField, IsOptionalField - for optional fields
 //   - AsRequiredField, IsRequiredField - for required fields
+//   - AsExtensionField, IsExtensionField - for extensions (Extendee set)
+//
+// protoreflect counterparts:
+//   - AsReflectMessage, AsReflectField, AsReflectEnum, AsReflectService,
+//     AsReflectMethod, AsReflectFile - for protoreflect.Descriptor values
+//   - AsReflectRepeatedField, AsReflectMapField, AsReflectOneOfField,
+//     AsReflectOptionalField, AsReflectRequiredField, AsReflectScalarField,
+//     AsReflectMessageField, AsReflectGroupField, AsReflectEnumField - field
+//     classification
+//   - AsReflectStreamingMethod - for client or server streaming methods
+//   - IsReflect... - boolean forms of each of the above
+//   - NewReflectFile, NewReflectFiles - validated conversion from descriptorpb
+//   - FindReflectDescriptor, ReflectToProto - lookup and conversion back
 //
 // Test utilities for creating descriptor objects:
 //   - NewField - create optional field with scalar type.
@@ -43,12 +56,150 @@
 //   - NewFile - create file descriptor.
 //   - NewFileWithTypes - create file with messages, enums, and services.
 //   - NewOneOf - create oneof descriptor.
+//   - NewExtensionField, NewMessageExtensionField - create extension fields.
+//   - NewExtensionRange - create extension range.
+//   - NewMessageWithExtensionRanges - create extendable message.
+//   - NewReservedRange, NewEnumReservedRange - create reserved ranges.
+//   - NewMessageWithReserved - create message with reserved numbers and names.
 //   - Type constants (TypeString, TypeInt32, etc.) for field types.
 //
-// Future releases will add:
-//   - Descriptor traversal utilities.
-//   - Path construction and naming helpers.
-//   - Visitor patterns for walking descriptor trees.
-//   - Code generation output management.
-//   - Context management for build environments.
+// Fluent descriptor builder:
+//   - File, FileBuilder, MessageBuilder, ServiceBuilder - build complete
+//     files with map entries, oneofs, proto3 optional synthetic oneofs,
+//     nested types and fully-qualified type names.
+//   - FieldOption - WithNumber, WithJSONName, WithDefault, WithType and
+//     WithFieldOptions customise fields.
+//   - MapEntryName - name protoc gives to map entry messages.
+//
+// Plugin requests:
+//   - NewCodeGeneratorRequest - build the CodeGeneratorRequest protoc would
+//     send, with dependencies and well-known types in topological order.
+//   - RequestOption - WithParameter, WithCompilerVersion and WithDependencies.
+//
+// Golden-file testing:
+//   - PluginFunc - a plugin run in-process.
+//   - NewGoldenTestCase, GoldenTestCase - core.TestCase comparing the files
+//     a plugin generates with testdata golden files.
+//   - AssertGolden - compare a response with golden files, or rewrite them
+//     when GoldenUpdateEnv is set.
+//   - AssertGoldenDir - AssertGolden for a directory owned by the response,
+//     also reporting or removing golden files no longer generated.
+//   - UnifiedDiff - differences between two texts in unified format.
+//
+// Plugin binaries:
+//   - PluginRunner, PluginResult - run a plugin binary as protoc does, with
+//     a timeout, returning its decoded response, stderr and exit code.
+//   - BuildPlugin, LocatePlugin - compile a plugin main or find it in PATH.
+//
+// Request capture:
+//   - WithCapture - dump the requests a plugin receives when enabled by
+//     CaptureParameter or CaptureEnv.
+//   - CaptureRequest, LoadRequest, ReplayRequest - write a request in binary
+//     and text form, read it back and run a plugin against it.
+//
+// Source printing:
+//   - PrintFile - render a FileDescriptorProto back into .proto source text,
+//     given its imports to shorten type names safely.
+//   - FileSyntax - syntax of a file, defaulting to proto2.
+//
+// Source parsing:
+//   - ParseProto - compile a single .proto source into a FileDescriptorProto.
+//   - Parser - compile sources from an in-memory map, resolving imports
+//     and the bundled well-known types, with SourceCodeInfo.
+//   - ParseError - parse and validation errors with file positions.
+//   - IsWellKnownFile, WellKnownFile - access the bundled well-known files.
+//
+// Dependency analysis:
+//   - NewRegistry, Registry - index declarations by fully-qualified name.
+//   - NewFileGraph, FileGraph - import graph with topological ordering,
+//     transitive closures, reverse dependencies and public re-exports.
+//   - ImportReport - unused and missing imports of a file.
+//   - NewMessageGraph, MessageGraph - recursive message detection, with
+//     the fields closing each cycle and whether it can be broken.
+//
+// Descriptor selectors:
+//   - CompileSelector, MustCompileSelector, Selector - CSS-like queries such
+//     as "service[name=Foo] > method:streaming" over descriptors.
+//   - Select, SelectorMatch - matched descriptors with their file, full
+//     name, kind and SourceCodeInfo path.
+//
+// Extensions:
+//   - NewExtensionIndex, ExtensionIndex, ExtensionInfo - extensions across
+//     files by extendee, number and full name, top-level and nested.
+//   - InExtensionRange - whether a number is in the extension ranges.
+//
+// Field numbers:
+//   - CheckFieldNumber, NumberUse - whether a number is free, used by a
+//     field, reserved, in an extension range or the implementation range.
+//   - IsReservedFieldNumber, IsReservedFieldName, IsFieldNameFree,
+//     HighestFieldNumber - reservation checks.
+//   - NewFieldNumberAllocator, FieldNumberAllocator, NextFieldNumber -
+//     allocate numbers for new fields.
+//
+// Deprecation:
+//   - IsDeprecated, IsDeprecatedFile, IsDeprecatedMessage, IsDeprecatedField,
+//     IsDeprecatedEnum, IsDeprecatedEnumValue, IsDeprecatedService,
+//     IsDeprecatedMethod - deprecated option checks.
+//   - DeprecationReport, DeprecatedElement - deprecated elements reachable
+//     from a service's methods.
+//
+// Method routing:
+//   - ServiceFullName, MethodPath - gRPC-style /pkg.Service/Method paths.
+//   - MethodHash - stable 32-bit FNV-1a identifier of a method path.
+//   - MethodRoutes, CheckMethodRoutes, RequestMethodRoutes, MethodRoute -
+//     routes of every method, failing on hash collisions.
+//
+// API reference:
+//   - RenderMarkdown - Markdown tables of services, messages and enums,
+//     with comments, deprecation markers and links between types.
+//
+// Example values:
+//   - NewExampleGenerator, ExampleGenerator - deterministic sample values
+//     of messages as protojson-compatible JSON, with depth and repeated
+//     bounds.
+//
+// TypeScript:
+//   - TSIdentifier, IsTSReserved, TSTypeName, TSFieldName - identifiers,
+//     reserved word escaping and nested type names.
+//   - TSOptions - scalar and field type mapping with int64 as bigint or
+//     string, enum declaration styles and relative module imports.
+//
+// Canonical descriptors:
+//   - CanonicalFile, CanonicalOptions - canonical copies of descriptors,
+//     optionally without SourceCodeInfo.
+//   - FileHash, FileGraph.Hash - stable content hashes of a file, alone
+//     or with its transitive dependencies.
+//
+// Per-file generation:
+//   - FileFunc, FilePlugin - plugins generating each file independently.
+//   - Cache - on-disk cache of the outputs of unchanged files, keyed by
+//     their canonical hash, the plugin parameter and version, with age
+//     and size-based eviction.
+//   - ParallelFilePlugin, GenerateFiles, ContextFileFunc - bounded
+//     concurrent generation with the outputs and first error sequential
+//     generation would give.
+//
+// Enum analysis:
+//   - NewEnumInfo, EnumInfo - aliases, zero and default values, reserved
+//     names and ranges, open or closed semantics and value prefixes.
+//   - EnumPrefix, UpperSnakeCase - the conventional value prefix of an enum.
+//   - EnumFeatures - resolved editions features of an enum.
+//
+// Wire format:
+//   - FileEdition, EditionDefaults, FileFeatures, FieldFeatures - resolve
+//     editions features, translating proto2 and proto3 equivalents.
+//   - NewFieldWire, FieldWire - wire type, tag bytes, packed and
+//     delimited encoding of a field, for hand-written encoders.
+//   - Encoding, TypeEncoding, IsPackable - varint, zigzag, fixed and
+//     length-prefixed categories of field types.
+//
+// Default values:
+//   - ParseDefault, DefaultValue - parse proto2 default_value strings into
+//     typed values, and render them as Go, TypeScript or JSON literals.
+//
+// JSON names:
+//   - JSONName, FieldJSONName, HasCustomJSONName - protoc's lowerCamelCase
+//     JSON names and custom json_name detection.
+//   - CheckJSONNames, CheckFileJSONNames - per-message JSON name conflicts,
+//     honouring LEGACY_BEST_EFFORT and legacy_json_field_conflicts.
 package generator
@@ -0,0 +1,201 @@
+package generator
+
+import (
+	"strings"
+	"unicode"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// EnumRange is an inclusive range of reserved enum numbers.
+type EnumRange struct {
+	Start int32
+	End   int32
+}
+
+// Contains tells if the number is within the range.
+func (r EnumRange) Contains(n int32) bool {
+	return n >= r.Start && n <= r.End
+}
+
+// EnumInfo describes the properties of an enum generators care about.
+type EnumInfo struct {
+	// Enum is the analysed enum.
+	Enum *descriptorpb.EnumDescriptorProto
+	// Zero is the first value numbered zero, nil if there is none.
+	Zero *descriptorpb.EnumValueDescriptorProto
+	// Default is the first value declared, the default of proto2
+	// fields without an explicit default.
+	Default *descriptorpb.EnumValueDescriptorProto
+	// Aliases lists, for each number with several names, the names in
+	// declaration order, the first being the canonical one.
+	Aliases map[int32][]string
+	// Prefix is the UPPER_SNAKE form of the enum name followed by an
+	// underscore, as conventionally prepended to the value names.
+	Prefix string
+	// ReservedNames are the value names reserved by the enum.
+	ReservedNames []string
+	// ReservedRanges are the value numbers reserved by the enum.
+	ReservedRanges []EnumRange
+	// AllowAlias tells if the allow_alias option is set.
+	AllowAlias bool
+	// Closed tells if unknown values are rejected, as in proto2 and the
+	// CLOSED enum_type feature, instead of kept as in proto3.
+	Closed bool
+	// Prefixed tells if every value starts with Prefix and removing it
+	// leaves distinct names that don't start with a digit.
+	Prefixed bool
+}
+
+// NewEnumInfo analyses an enum declared in the given file, which
+// determines whether it's open or closed.
+func NewEnumInfo(file *descriptorpb.FileDescriptorProto,
+	enum *descriptorpb.EnumDescriptorProto) (*EnumInfo, error) {
+	if enum == nil || !IsEnumType(enum) {
+		return nil, core.Wrap(core.ErrInvalid, "invalid enum")
+	}
+
+	info := &EnumInfo{
+		Enum:          enum,
+		Prefix:        EnumPrefix(enum.GetName()),
+		ReservedNames: core.SliceCopy(enum.ReservedName),
+		AllowAlias:    enum.GetOptions().GetAllowAlias(),
+		Closed:        EnumFeatures(file, enum).GetEnumType() == descriptorpb.FeatureSet_CLOSED,
+	}
+	for _, r := range enum.ReservedRange {
+		info.ReservedRanges = append(info.ReservedRanges, EnumRange{Start: r.GetStart(), End: r.GetEnd()})
+	}
+	if len(enum.Value) > 0 {
+		info.Default = enum.Value[0]
+	}
+
+	info.collectValues()
+	info.Prefixed = info.isPrefixed()
+	return info, nil
+}
+
+func (info *EnumInfo) collectValues() {
+	names := make(map[int32][]string)
+	var order []int32
+	for _, v := range info.Enum.Value {
+		n := v.GetNumber()
+		if n == 0 && info.Zero == nil {
+			info.Zero = v
+		}
+		if _, ok := names[n]; !ok {
+			order = append(order, n)
+		}
+		names[n] = append(names[n], v.GetName())
+	}
+
+	for _, n := range order {
+		if len(names[n]) > 1 {
+			if info.Aliases == nil {
+				info.Aliases = make(map[int32][]string)
+			}
+			info.Aliases[n] = names[n]
+		}
+	}
+}
+
+func (info *EnumInfo) isPrefixed() bool {
+	if len(info.Enum.Value) == 0 {
+		return false
+	}
+
+	seen := make(map[string]bool)
+	for _, v := range info.Enum.Value {
+		rest, ok := strings.CutPrefix(v.GetName(), info.Prefix)
+		if !ok || rest == "" || seen[rest] || (rest[0] >= '0' && rest[0] <= '9') {
+			return false
+		}
+		seen[rest] = true
+	}
+	return true
+}
+
+// Open tells if unknown values are kept, the opposite of Closed.
+func (info *EnumInfo) Open() bool {
+	return !info.Closed
+}
+
+// Canonical returns the first value declared with the given number.
+func (info *EnumInfo) Canonical(n int32) (*descriptorpb.EnumValueDescriptorProto, bool) {
+	for _, v := range info.Enum.Value {
+		if v.GetNumber() == n {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// IsAlias tells if the named value shares its number with a value
+// declared before it.
+func (info *EnumInfo) IsAlias(name string) bool {
+	for _, names := range info.Aliases {
+		if core.SliceContains(names[1:], name) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsReserved tells if the number is reserved by the enum.
+func (info *EnumInfo) IsReserved(n int32) bool {
+	for _, r := range info.ReservedRanges {
+		if r.Contains(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsReservedName tells if the value name is reserved by the enum.
+func (info *EnumInfo) IsReservedName(name string) bool {
+	return core.SliceContains(info.ReservedNames, name)
+}
+
+// TrimPrefix returns the name of a value without Prefix when every
+// value can have it removed, or the name unchanged otherwise.
+func (info *EnumInfo) TrimPrefix(name string) string {
+	if info.Prefixed {
+		return strings.TrimPrefix(name, info.Prefix)
+	}
+	return name
+}
+
+// EnumPrefix returns the prefix conventionally prepended to the values
+// of an enum, its name in UPPER_SNAKE case followed by an underscore,
+// as in FOO_BAR_ for FooBar and HTTP_METHOD_ for HTTPMethod.
+func EnumPrefix(name string) string {
+	return UpperSnakeCase(name) + "_"
+}
+
+// UpperSnakeCase converts a CamelCase name into UPPER_SNAKE case,
+// keeping acronyms together.
+func UpperSnakeCase(name string) string {
+	runes := []rune(name)
+	var sb strings.Builder
+	for i, r := range runes {
+		if i > 0 && isWordStart(runes, i) {
+			_ = sb.WriteByte('_')
+		}
+		_, _ = sb.WriteRune(unicode.ToUpper(r))
+	}
+	return sb.String()
+}
+
+// isWordStart tells if an upper case letter begins a new word, after
+// a lower case letter or digit, or ending an acronym.
+func isWordStart(runes []rune, i int) bool {
+	r, prev := runes[i], runes[i-1]
+	switch {
+	case !unicode.IsUpper(r), prev == '_':
+		return false
+	case unicode.IsLower(prev), unicode.IsDigit(prev):
+		return true
+	default:
+		return i+1 < len(runes) && unicode.IsLower(runes[i+1])
+	}
+}
@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+)
+
+type upperSnakeCaseTestCase struct {
+	name string
+	want string
+}
+
+var _ core.TestCase = upperSnakeCaseTestCase{}
+
+func (tc upperSnakeCaseTestCase) Name() string {
+	return tc.name
+}
+
+func (tc upperSnakeCaseTestCase) Test(t *testing.T) {
+	t.Helper()
+	core.AssertEqual(t, tc.want, UpperSnakeCase(tc.name), "UpperSnakeCase")
+	core.AssertEqual(t, tc.want+"_", EnumPrefix(tc.name), "EnumPrefix")
+}
+
+func upperSnakeCaseTestCases() []upperSnakeCaseTestCase {
+	return []upperSnakeCaseTestCase{
+		{"Color", "COLOR"},
+		{"FooBar", "FOO_BAR"},
+		{"HTTPMethod", "HTTP_METHOD"},
+		{"IPv4Kind", "I_PV4_KIND"},
+		{"Foo2Bar", "FOO2_BAR"},
+		{"snake_case", "SNAKE_CASE"},
+		{"ALREADY_UPPER", "ALREADY_UPPER"},
+	}
+}
+
+func TestUpperSnakeCase(t *testing.T) {
+	core.RunTestCases(t, upperSnakeCaseTestCases())
+}
+
+func TestEnumInfo(t *testing.T) {
+	file, err := ParseProto("enum.proto", `syntax = "proto3";
+package test;
+
+enum HTTPMethod {
+  option allow_alias = true;
+  HTTP_METHOD_UNSPECIFIED = 0;
+  HTTP_METHOD_GET = 1;
+  HTTP_METHOD_FETCH = 1;
+  HTTP_METHOD_POST = 2;
+  reserved 5 to 9, 20;
+  reserved "HTTP_METHOD_PUT";
+}
+`)
+	core.AssertMustNoError(t, err, "ParseProto")
+
+	info, err := NewEnumInfo(file, file.EnumType[0])
+	core.AssertMustNoError(t, err, "NewEnumInfo")
+
+	core.AssertTrue(t, info.AllowAlias, "AllowAlias")
+	core.AssertTrue(t, info.Open(), "open")
+	core.AssertEqual(t, "HTTP_METHOD_", info.Prefix, "Prefix")
+	core.AssertTrue(t, info.Prefixed, "Prefixed")
+	core.AssertEqual(t, "GET", info.TrimPrefix("HTTP_METHOD_GET"), "TrimPrefix")
+	core.AssertEqual(t, "HTTP_METHOD_UNSPECIFIED", info.Zero.GetName(), "Zero")
+	core.AssertEqual(t, "HTTP_METHOD_UNSPECIFIED", info.Default.GetName(), "Default")
+
+	core.AssertEqual(t, 1, len(info.Aliases), "aliases")
+	core.AssertSliceEqual(t, []string{"HTTP_METHOD_GET", "HTTP_METHOD_FETCH"}, info.Aliases[1], "aliases of 1")
+	core.AssertTrue(t, info.IsAlias("HTTP_METHOD_FETCH"), "FETCH is alias")
+	core.AssertFalse(t, info.IsAlias("HTTP_METHOD_GET"), "GET is canonical")
+	canonical, ok := info.Canonical(1)
+	core.AssertTrue(t, ok, "Canonical")
+	core.AssertEqual(t, "HTTP_METHOD_GET", canonical.GetName(), "canonical name")
+
+	core.AssertTrue(t, info.IsReserved(7), "7 reserved")
+	core.AssertTrue(t, info.IsReserved(20), "20 reserved")
+	core.AssertFalse(t, info.IsReserved(10), "10 not reserved")
+	core.AssertTrue(t, info.IsReservedName("HTTP_METHOD_PUT"), "reserved name")
+}
+
+func TestEnumInfoClosed(t *testing.T) {
+	file, err := ParseProto("closed.proto", `edition = "2023";
+package test;
+
+message Outer {
+  option features.enum_type = CLOSED;
+  enum Level {
+    LOW = 1;
+    HIGH = 2;
+  }
+}
+
+enum Status {
+  STATUS_OK = 0;
+  STATUS_1 = 1;
+}
+`)
+	core.AssertMustNoError(t, err, "ParseProto")
+
+	level, err := NewEnumInfo(file, file.MessageType[0].EnumType[0])
+	core.AssertMustNoError(t, err, "NewEnumInfo")
+	core.AssertTrue(t, level.Closed, "inherited CLOSED")
+	core.AssertNil(t, level.Zero, "no zero value")
+	core.AssertEqual(t, "LOW", level.Default.GetName(), "Default")
+	core.AssertFalse(t, level.Prefixed, "unprefixed values")
+	core.AssertEqual(t, "LOW", level.TrimPrefix("LOW"), "TrimPrefix unchanged")
+
+	status, err := NewEnumInfo(file, file.EnumType[0])
+	core.AssertMustNoError(t, err, "NewEnumInfo")
+	core.AssertFalse(t, status.Closed, "edition default OPEN")
+	core.AssertFalse(t, status.Prefixed, "digit after prefix")
+	core.AssertEqual(t, 0, len(status.Aliases), "no aliases")
+}
+
+func TestEnumInfoProto2(t *testing.T) {
+	file := File("p2.proto").Syntax("proto2").Enum("Kind", "A", "B").Build()
+	info, err := NewEnumInfo(file, file.EnumType[0])
+	core.AssertMustNoError(t, err, "NewEnumInfo")
+	core.AssertTrue(t, info.Closed, "proto2 enums are closed")
+
+	_, err = NewEnumInfo(file, nil)
+	core.AssertErrorIs(t, err, core.ErrInvalid, "nil enum")
+}
@@ -0,0 +1,372 @@
+package generator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Defaults of a new ExampleGenerator.
+const (
+	DefaultExampleMaxDepth    = 3
+	DefaultExampleMinRepeated = 1
+	DefaultExampleMaxRepeated = 3
+)
+
+// exampleEpoch is the earliest example Timestamp, 2024-01-01T00:00:00Z.
+const exampleEpoch = 1704067200
+
+// ExampleGenerator produces example values of messages as
+// protojson-compatible JSON, for documentation and fuzzing.
+//
+// Values are pseudo-random but deterministic, the same Seed always
+// giving the same value for a message. Every field is set except
+// extensions, one member of each oneof is picked, and enums take one of
+// their non-zero values when they have any. Well-known types use their
+// special JSON forms, and are only resolved from the files when they
+// don't have one.
+type ExampleGenerator struct {
+	// Seed initialises the pseudo-random source.
+	Seed uint64
+	// MaxDepth is how deep message fields are nested. Message fields
+	// beyond it are omitted, or left empty when repeated, except the
+	// required ones, below which only required message fields are set.
+	MaxDepth int
+	// MinRepeated and MaxRepeated bound the number of elements of
+	// repeated and map fields. Map keys are distinct, so maps have
+	// fewer entries when their key type has fewer values, as bool keys
+	// do.
+	MinRepeated int
+	MaxRepeated int
+
+	r   *Registry
+	rnd *rand.Rand
+	// expanding holds the messages beyond MaxDepth being generated, to
+	// detect cycles of required fields.
+	expanding map[string]bool
+}
+
+// NewExampleGenerator creates a generator for the messages of the given
+// files, which must also define the types they reference.
+func NewExampleGenerator(files ...*descriptorpb.FileDescriptorProto) *ExampleGenerator {
+	return &ExampleGenerator{
+		MaxDepth:    DefaultExampleMaxDepth,
+		MinRepeated: DefaultExampleMinRepeated,
+		MaxRepeated: DefaultExampleMaxRepeated,
+		r:           NewRegistry(files...),
+	}
+}
+
+// Value returns an example of the named message, with or without
+// leading dot, as the value encoding/json would decode its JSON into:
+// objects as map[string]any, arrays as []any and numbers as float64.
+func (g *ExampleGenerator) Value(message string) (any, error) {
+	if g.MinRepeated < 0 || g.MinRepeated > g.MaxRepeated {
+		return nil, core.Wrapf(core.ErrInvalid, "invalid repeated bounds %d..%d", g.MinRepeated, g.MaxRepeated)
+	}
+
+	g.rnd = rand.New(rand.NewPCG(g.Seed, 0))
+	g.expanding = make(map[string]bool)
+	return g.message(registryKey(message), 0)
+}
+
+// JSON returns an example of the named message as indented JSON.
+func (g *ExampleGenerator) JSON(message string) ([]byte, error) {
+	v, err := g.Value(message)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
+func (g *ExampleGenerator) message(name string, depth int) (any, error) {
+	if v, ok := g.wellKnown(name); ok {
+		return v, nil
+	}
+
+	msg, ok := g.r.Message(name)
+	if !ok {
+		return nil, core.Wrapf(core.ErrNotExists, "message %q not found", name)
+	}
+	if depth > g.MaxDepth {
+		// only reached through required fields, which have no finite
+		// value when they form a cycle
+		if g.expanding[name] {
+			return nil, core.Wrapf(core.ErrInvalid, "required fields of %q form a cycle", name)
+		}
+		g.expanding[name] = true
+		defer delete(g.expanding, name)
+	}
+
+	out := make(map[string]any)
+	picked := g.pickOneofs(name, msg, depth)
+	for i, field := range msg.Field {
+		if isRealOneofMember(field) && picked[field.GetOneofIndex()] != i {
+			continue
+		}
+		if g.isMessage(name, field) && depth >= g.MaxDepth &&
+			field.GetLabel() != LabelRepeated && !g.isRequired(name, field) {
+			continue
+		}
+
+		v, err := g.field(name, field, depth)
+		if err != nil {
+			return nil, err
+		}
+		out[FieldJSONName(field)] = v
+	}
+	return out, nil
+}
+
+// pickOneofs chooses the member set in each oneof, preferring those
+// not omitted by the depth limit. Oneofs without usable members are
+// left out.
+func (g *ExampleGenerator) pickOneofs(scope string, msg *descriptorpb.DescriptorProto, depth int) map[int32]int {
+	members := make(map[int32][]int)
+	var order []int32
+	for i, field := range msg.Field {
+		if !isRealOneofMember(field) {
+			continue
+		}
+		if g.isMessage(scope, field) && depth >= g.MaxDepth {
+			continue
+		}
+		index := field.GetOneofIndex()
+		if _, ok := members[index]; !ok {
+			order = append(order, index)
+		}
+		members[index] = append(members[index], i)
+	}
+
+	picked := make(map[int32]int, len(order))
+	for _, index := range order {
+		fields := members[index]
+		picked[index] = fields[g.rnd.IntN(len(fields))]
+	}
+	for i := range msg.OneofDecl {
+		if _, ok := picked[int32(i)]; !ok {
+			picked[int32(i)] = -1
+		}
+	}
+	return picked
+}
+
+func (g *ExampleGenerator) field(scope string, field *descriptorpb.FieldDescriptorProto, depth int) (any, error) {
+	if entry := g.mapEntry(scope, field); entry != nil {
+		return g.mapValue(resolveMessageName(g.r, scope, field.GetTypeName()), entry, depth)
+	}
+	if field.GetLabel() != LabelRepeated {
+		return g.single(scope, field, depth)
+	}
+
+	n := g.repeated()
+	if g.isMessage(scope, field) && depth >= g.MaxDepth {
+		n = 0
+	}
+	out := make([]any, 0, n)
+	for range n {
+		v, err := g.single(scope, field, depth)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (g *ExampleGenerator) mapValue(scope string, entry *descriptorpb.DescriptorProto, depth int) (any, error) {
+	out := make(map[string]any)
+	if len(entry.Field) != 2 || (g.isMessage(scope, entry.Field[1]) && depth >= g.MaxDepth) {
+		return out, nil
+	}
+
+	n := min(g.repeated(), exampleKeyDomain(entry.Field[0].GetType()))
+	for len(out) < n {
+		key := fmt.Sprint(g.scalar(entry.Field[0]))
+		if _, ok := out[key]; ok {
+			continue
+		}
+		v, err := g.single(scope, entry.Field[1], depth)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = v
+	}
+	return out, nil
+}
+
+// exampleKeyDomain returns how many distinct examples scalar gives for
+// a map key type.
+func exampleKeyDomain(t descriptorpb.FieldDescriptorProto_Type) int {
+	switch t {
+	case TypeBool:
+		return 2
+	case TypeInt64, TypeUInt64, TypeSInt64, TypeFixed64, TypeSFixed64:
+		return 1000000
+	default:
+		return 1000
+	}
+}
+
+// repeated returns the number of elements of a repeated field.
+func (g *ExampleGenerator) repeated() int {
+	return g.MinRepeated + g.rnd.IntN(g.MaxRepeated-g.MinRepeated+1)
+}
+
+func (g *ExampleGenerator) single(scope string, field *descriptorpb.FieldDescriptorProto, depth int) (any, error) {
+	switch field.GetType() {
+	case TypeMessage, TypeGroup:
+		return g.message(resolveMessageName(g.r, scope, field.GetTypeName()), depth+1)
+	case TypeEnum:
+		return g.enum(field.GetTypeName())
+	default:
+		return g.scalar(field), nil
+	}
+}
+
+// scalar returns an example of a scalar field in its JSON form, 64-bit
+// integers as strings and bytes in base64.
+func (g *ExampleGenerator) scalar(field *descriptorpb.FieldDescriptorProto) any {
+	switch field.GetType() {
+	case TypeString:
+		return field.GetName() + "-" + strconv.Itoa(g.rnd.IntN(1000))
+	case TypeBytes:
+		b := make([]byte, 4)
+		for i := range b {
+			b[i] = byte(g.rnd.Uint32())
+		}
+		return base64.StdEncoding.EncodeToString(b)
+	case TypeBool:
+		return g.rnd.IntN(2) == 1
+	case TypeDouble, TypeFloat:
+		return float64(g.rnd.IntN(100000)) / 100
+	case TypeInt64, TypeUInt64, TypeSInt64, TypeFixed64, TypeSFixed64:
+		return strconv.Itoa(g.rnd.IntN(1000000))
+	default:
+		return float64(g.rnd.IntN(1000))
+	}
+}
+
+func (g *ExampleGenerator) enum(typeName string) (any, error) {
+	enum, ok := g.r.Enum(typeName)
+	if !ok {
+		return nil, core.Wrapf(core.ErrNotExists, "enum %q not found", registryKey(typeName))
+	}
+	if len(enum.Value) == 0 {
+		return nil, core.Wrapf(core.ErrInvalid, "enum %q has no values", registryKey(typeName))
+	}
+
+	var candidates []string
+	for _, v := range enum.Value {
+		if v.GetNumber() != 0 {
+			candidates = append(candidates, v.GetName())
+		}
+	}
+	if len(candidates) == 0 {
+		return enum.Value[0].GetName(), nil
+	}
+	return candidates[g.rnd.IntN(len(candidates))], nil
+}
+
+// wellKnown returns an example of the well-known types with a special
+// JSON form.
+func (g *ExampleGenerator) wellKnown(name string) (any, bool) {
+	if !isExampleLeaf(name) {
+		return nil, false
+	}
+
+	switch name {
+	case "google.protobuf.Timestamp":
+		t := time.Unix(exampleEpoch+g.rnd.Int64N(365*24*3600), 0).UTC()
+		return t.Format(time.RFC3339), true
+	case "google.protobuf.Duration":
+		return strconv.Itoa(1+g.rnd.IntN(3600)) + "s", true
+	case "google.protobuf.Empty":
+		return map[string]any{}, true
+	case "google.protobuf.FieldMask":
+		return "name", true
+	case "google.protobuf.Struct":
+		return map[string]any{"key": "value"}, true
+	case "google.protobuf.Value":
+		return "value", true
+	case "google.protobuf.ListValue":
+		return []any{"value"}, true
+	case "google.protobuf.Any":
+		return map[string]any{"@type": "type.googleapis.com/google.protobuf.Empty"}, true
+
+	default:
+		t := exampleWrappers[name]
+		return g.scalar(&descriptorpb.FieldDescriptorProto{Name: proto.String("value"), Type: t.Enum()}), true
+	}
+}
+
+// isExampleLeaf tells if a message is a well-known type with a special
+// JSON form, not nested as an object of fields.
+func isExampleLeaf(name string) bool {
+	switch name {
+	case "google.protobuf.Timestamp", "google.protobuf.Duration",
+		"google.protobuf.Empty", "google.protobuf.FieldMask",
+		"google.protobuf.Struct", "google.protobuf.Value",
+		"google.protobuf.ListValue", "google.protobuf.Any":
+		return true
+	}
+	_, ok := exampleWrappers[name]
+	return ok
+}
+
+// exampleWrappers maps the wrapper types to the type of their value.
+var exampleWrappers = map[string]descriptorpb.FieldDescriptorProto_Type{
+	"google.protobuf.DoubleValue": TypeDouble,
+	"google.protobuf.FloatValue":  TypeFloat,
+	"google.protobuf.Int64Value":  TypeInt64,
+	"google.protobuf.UInt64Value": TypeUInt64,
+	"google.protobuf.Int32Value":  TypeInt32,
+	"google.protobuf.UInt32Value": TypeUInt32,
+	"google.protobuf.BoolValue":   TypeBool,
+	"google.protobuf.StringValue": TypeString,
+	"google.protobuf.BytesValue":  TypeBytes,
+}
+
+// isRequired tells if a field of a message is required, in proto2 or
+// with LEGACY_REQUIRED presence in editions.
+func (g *ExampleGenerator) isRequired(scope string, field *descriptorpb.FieldDescriptorProto) bool {
+	if field.GetLabel() == LabelRequired {
+		return true
+	}
+	name, ok := g.r.FileOf(scope)
+	if !ok {
+		return false
+	}
+	file, _ := g.r.File(name)
+	return FieldFeatures(file, field).GetFieldPresence() == descriptorpb.FeatureSet_LEGACY_REQUIRED
+}
+
+func (g *ExampleGenerator) isMessage(scope string, field *descriptorpb.FieldDescriptorProto) bool {
+	switch field.GetType() {
+	case TypeMessage, TypeGroup:
+		return !isExampleLeaf(resolveMessageName(g.r, scope, field.GetTypeName()))
+	default:
+		return false
+	}
+}
+
+// mapEntry returns the map-entry message a field refers to, if it is
+// a map field.
+func (g *ExampleGenerator) mapEntry(scope string,
+	field *descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	if field.GetLabel() != LabelRepeated || field.GetType() != TypeMessage {
+		return nil
+	}
+	entry, ok := g.r.Message(resolveMessageName(g.r, scope, field.GetTypeName()))
+	if !ok || !entry.GetOptions().GetMapEntry() {
+		return nil
+	}
+	return entry
+}
@@ -0,0 +1,282 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	// resolves the examples of google.protobuf.Any
+	_ "google.golang.org/protobuf/types/known/emptypb"
+)
+
+const exampleTestProto = `syntax = "proto3";
+
+package ex;
+
+import "google/protobuf/any.proto";
+import "google/protobuf/duration.proto";
+import "google/protobuf/struct.proto";
+import "google/protobuf/timestamp.proto";
+import "google/protobuf/wrappers.proto";
+
+enum Color {
+  COLOR_UNSPECIFIED = 0;
+  COLOR_RED = 1;
+  COLOR_BLUE = 2;
+}
+
+message Scalars {
+  double d = 1;
+  float f = 2;
+  int64 i64 = 3;
+  uint64 u64 = 4;
+  sint32 s32 = 5;
+  fixed64 f64 = 6;
+  bool flag = 7;
+  string text = 8 [json_name = "label"];
+  bytes data = 9;
+  optional int32 maybe = 10;
+  Color color = 11;
+}
+
+message Node {
+  string name = 1;
+  Node parent = 2;
+  repeated Node children = 3;
+  map<string, Node> named = 4;
+  map<int32, Color> colors = 5;
+  repeated Scalars scalars = 6;
+  oneof choice {
+    string text = 7;
+    Node link = 8;
+    int64 number = 9;
+  }
+  google.protobuf.Timestamp created = 10;
+  google.protobuf.Duration ttl = 11;
+  google.protobuf.StringValue alias = 12;
+  google.protobuf.Struct meta = 13;
+  google.protobuf.Any any = 14;
+}
+`
+
+func newExampleTestFiles(t *testing.T) []*descriptorpb.FileDescriptorProto {
+	t.Helper()
+	p := &Parser{Files: map[string]string{"ex.proto": exampleTestProto}}
+	files, err := p.ParseWithImports("ex.proto")
+	core.AssertMustNoError(t, err, "Parse")
+	return files
+}
+
+// unmarshalExample checks the JSON decodes into the message with protojson.
+func unmarshalExample(t *testing.T, files []*descriptorpb.FileDescriptorProto,
+	name string, data []byte) protoreflect.Message {
+	t.Helper()
+	reg, err := NewReflectFiles(files...)
+	core.AssertMustNoError(t, err, "NewReflectFiles")
+	desc, err := reg.FindDescriptorByName(protoreflect.FullName(name))
+	core.AssertMustNoError(t, err, "FindDescriptorByName")
+
+	msg := dynamicpb.NewMessage(desc.(protoreflect.MessageDescriptor))
+	err = protojson.Unmarshal(data, msg)
+	core.AssertMustNoError(t, err, "protojson.Unmarshal")
+	return msg
+}
+
+func TestExampleGeneratorJSON(t *testing.T) {
+	files := newExampleTestFiles(t)
+
+	for _, name := range []string{"ex.Scalars", "ex.Node", ".google.protobuf.Timestamp"} {
+		g := NewExampleGenerator(files...)
+		data, err := g.JSON(name)
+		core.AssertMustNoError(t, err, name)
+		unmarshalExample(t, files, registryKey(name), data)
+	}
+}
+
+func TestExampleGeneratorDeterministic(t *testing.T) {
+	files := newExampleTestFiles(t)
+	g := NewExampleGenerator(files...)
+
+	first, err := g.JSON("ex.Node")
+	core.AssertMustNoError(t, err, "first")
+	second, err := g.JSON("ex.Node")
+	core.AssertMustNoError(t, err, "second")
+	core.AssertEqual(t, string(first), string(second), "same seed")
+
+	g.Seed = 42
+	other, err := g.JSON("ex.Node")
+	core.AssertMustNoError(t, err, "other")
+	core.AssertTrue(t, string(first) != string(other), "different seed")
+}
+
+func TestExampleGeneratorScalars(t *testing.T) {
+	g := NewExampleGenerator(newExampleTestFiles(t)...)
+	v, err := g.Value("ex.Scalars")
+	core.AssertMustNoError(t, err, "Value")
+
+	obj, ok := v.(map[string]any)
+	core.AssertMustTrue(t, ok, "object")
+	core.AssertEqual(t, 11, len(obj), "fields")
+
+	for _, name := range []string{"i64", "u64", "f64", "label", "data"} {
+		_, ok := obj[name].(string)
+		core.AssertTrue(t, ok, name+" as string")
+	}
+	for _, name := range []string{"d", "f", "s32", "maybe"} {
+		_, ok := obj[name].(float64)
+		core.AssertTrue(t, ok, name+" as float64")
+	}
+	_, ok = obj["text"]
+	core.AssertFalse(t, ok, "json_name used")
+	core.AssertTrue(t, obj["color"] == "COLOR_RED" || obj["color"] == "COLOR_BLUE", "non-zero enum")
+}
+
+func TestExampleGeneratorDepth(t *testing.T) {
+	g := NewExampleGenerator(newExampleTestFiles(t)...)
+	g.MaxDepth = 0
+	v, err := g.Value("ex.Node")
+	core.AssertMustNoError(t, err, "Value")
+
+	obj := v.(map[string]any)
+	_, ok := obj["parent"]
+	core.AssertFalse(t, ok, "parent omitted")
+	core.AssertEqual(t, 0, len(obj["children"].([]any)), "children")
+	core.AssertEqual(t, 0, len(obj["named"].(map[string]any)), "named")
+	core.AssertTrue(t, len(obj["colors"].(map[string]any)) > 0, "colors")
+	_, ok = obj["link"]
+	core.AssertFalse(t, ok, "oneof message omitted")
+	_, ok = obj["created"].(string)
+	core.AssertTrue(t, ok, "well-known type kept")
+
+	g.MaxDepth = 2
+	v, err = g.Value("ex.Node")
+	core.AssertMustNoError(t, err, "Value")
+	depth := 0
+	for node, ok := v.(map[string]any); ok; node, ok = node["parent"].(map[string]any) {
+		depth++
+	}
+	core.AssertEqual(t, 3, depth, "nesting")
+}
+
+func TestExampleGeneratorRequired(t *testing.T) {
+	p := &Parser{Files: map[string]string{
+		"req.proto": `syntax = "proto2";
+package req;
+message Node {
+  required Info info = 1;
+  optional Node next = 2;
+}
+message Info {
+  required int32 id = 1;
+  optional Node owner = 2;
+}
+`,
+		"legacy.proto": `edition = "2023";
+package legacy;
+message Node {
+  Node next = 1 [features.field_presence = LEGACY_REQUIRED];
+}
+message Tree {
+  Tree child = 1;
+  Leaf leaf = 2 [features.field_presence = LEGACY_REQUIRED];
+}
+message Leaf {
+  int32 id = 1 [features.field_presence = LEGACY_REQUIRED];
+}
+`,
+	}}
+	files, err := p.Parse("req.proto", "legacy.proto")
+	core.AssertMustNoError(t, err, "Parse")
+
+	for _, name := range []string{"req.Node", "legacy.Tree"} {
+		g := NewExampleGenerator(files...)
+		g.MaxDepth = 0
+		data, err := g.JSON(name)
+		core.AssertMustNoError(t, err, name)
+		// protojson rejects missing required fields
+		unmarshalExample(t, files, name, data)
+	}
+
+	g := NewExampleGenerator(files...)
+	g.MaxDepth = 0
+	v, err := g.Value("req.Node")
+	core.AssertMustNoError(t, err, "Value")
+	info, ok := v.(map[string]any)["info"].(map[string]any)
+	core.AssertMustTrue(t, ok, "required beyond the depth")
+	_, ok = info["owner"]
+	core.AssertFalse(t, ok, "optional beyond the depth")
+
+	_, err = g.Value("legacy.Node")
+	core.AssertErrorIs(t, err, core.ErrInvalid, "required cycle")
+}
+
+func TestExampleGeneratorOneof(t *testing.T) {
+	g := NewExampleGenerator(newExampleTestFiles(t)...)
+	for seed := range uint64(20) {
+		g.Seed = seed
+		v, err := g.Value("ex.Node")
+		core.AssertMustNoError(t, err, "Value")
+
+		obj := v.(map[string]any)
+		set := 0
+		for _, name := range []string{"text", "link", "number"} {
+			if _, ok := obj[name]; ok {
+				set++
+			}
+		}
+		core.AssertEqual(t, 1, set, "oneof members")
+	}
+}
+
+func TestExampleGeneratorRepeated(t *testing.T) {
+	g := NewExampleGenerator(newExampleTestFiles(t)...)
+	g.MinRepeated, g.MaxRepeated = 2, 2
+	g.MaxDepth = 1
+	v, err := g.Value("ex.Node")
+	core.AssertMustNoError(t, err, "Value")
+
+	obj := v.(map[string]any)
+	core.AssertEqual(t, 2, len(obj["children"].([]any)), "children")
+	core.AssertEqual(t, 2, len(obj["scalars"].([]any)), "scalars")
+
+	g.MinRepeated, g.MaxRepeated = 3, 1
+	_, err = g.Value("ex.Node")
+	core.AssertErrorIs(t, err, core.ErrInvalid, "bounds")
+}
+
+func TestExampleGeneratorMapKeys(t *testing.T) {
+	file, err := ParseProto("keys.proto", `syntax = "proto3";
+package keys;
+message M {
+  map<bool, int32> flags = 1;
+  map<int32, string> ids = 2;
+}
+`)
+	core.AssertMustNoError(t, err, "ParseProto")
+
+	g := NewExampleGenerator(file)
+	g.MinRepeated, g.MaxRepeated = 40, 40
+	v, err := g.Value("keys.M")
+	core.AssertMustNoError(t, err, "Value")
+
+	obj := v.(map[string]any)
+	core.AssertEqual(t, 2, len(obj["flags"].(map[string]any)), "bool keys")
+	core.AssertEqual(t, 40, len(obj["ids"].(map[string]any)), "distinct keys")
+}
+
+func TestExampleGeneratorErrors(t *testing.T) {
+	g := NewExampleGenerator(newExampleTestFiles(t)...)
+	_, err := g.Value("ex.Missing")
+	core.AssertErrorIs(t, err, core.ErrNotExists, "unknown message")
+
+	file := File("broken.proto").Package("broken").Build()
+	file.MessageType = []*descriptorpb.DescriptorProto{
+		NewMessage("M", NewEnumField("e", 1, ".broken.Missing")),
+	}
+	_, err = NewExampleGenerator(file).Value("broken.M")
+	core.AssertErrorIs(t, err, core.ErrNotExists, "unknown enum")
+}
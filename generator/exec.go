@@ -0,0 +1,154 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// DefaultPluginTimeout is how long a plugin may run when the
+// PluginRunner doesn't say otherwise.
+const DefaultPluginTimeout = 30 * time.Second
+
+// pluginWaitDelay is how long Run waits for a plugin's output to be
+// closed once it has exited or been killed, so a leftover child
+// holding the pipes can't block it.
+const pluginWaitDelay = time.Second
+
+// PluginResult is the outcome of running a plugin binary.
+type PluginResult struct {
+	// Response is the decoded response, nil if the plugin failed.
+	Response *pluginpb.CodeGeneratorResponse
+	// Stderr is what the plugin wrote to its standard error.
+	Stderr string
+	// ExitCode is the exit status of the plugin, -1 if it didn't exit.
+	ExitCode int
+}
+
+// PluginRunner invokes a plugin binary the way protoc does, writing
+// the serialized request to its standard input and reading the
+// response from its standard output.
+type PluginRunner struct {
+	// Path is the plugin binary.
+	Path string
+	// Dir is the working directory, the current one if empty.
+	Dir string
+	// Args are passed to the plugin, protoc passes none.
+	Args []string
+	// Env is added to the environment of the current process.
+	Env []string
+	// Timeout limits how long the plugin may run, DefaultPluginTimeout
+	// if zero.
+	Timeout time.Duration
+}
+
+// Run executes the plugin with the given request. The result is
+// returned together with any error, so the standard error and exit
+// code of failed runs can be inspected.
+func (r *PluginRunner) Run(ctx context.Context, req *pluginpb.CodeGeneratorRequest) (*PluginResult, error) {
+	in, err := proto.Marshal(req)
+	if err != nil {
+		return nil, core.Wrap(err, "marshal request")
+	}
+
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultPluginTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.Path, r.Args...)
+	cmd.Dir = r.Dir
+	cmd.Env = append(os.Environ(), r.Env...)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = pluginWaitDelay
+
+	err = cmd.Run()
+	res := &PluginResult{Stderr: stderr.String(), ExitCode: -1}
+	if cmd.ProcessState != nil {
+		res.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return res, core.Wrapf(ctx.Err(), "plugin %q timed out after %v", r.Path, timeout)
+	case err != nil:
+		return res, core.Wrapf(err, "plugin %q failed: %s", r.Path, strings.TrimSpace(res.Stderr))
+	}
+
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(stdout.Bytes(), resp); err != nil {
+		return res, core.Wrapf(err, "plugin %q: invalid response", r.Path)
+	}
+	res.Response = resp
+	return res, nil
+}
+
+// PluginFunc returns a function running the plugin binary, for use
+// with the golden-file harness.
+func (r *PluginRunner) PluginFunc() PluginFunc {
+	return func(req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+		res, err := r.Run(context.Background(), req)
+		if err != nil {
+			return nil, err
+		}
+		return res.Response, nil
+	}
+}
+
+// BuildPlugin compiles the main package pkg into dir using the go
+// command, and returns the path of the binary. pkg is resolved
+// relative to the current directory.
+func BuildPlugin(ctx context.Context, pkg, dir string) (string, error) {
+	name := filepath.Base(strings.TrimSuffix(pkg, "/"))
+	if name == "." || name == string(filepath.Separator) {
+		return "", core.Wrapf(core.ErrInvalid, "invalid package %q", pkg)
+	}
+
+	out := filepath.Join(dir, name)
+	if runtime.GOOS == "windows" {
+		out += ".exe"
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "go", "build", "-o", out, pkg)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", core.Wrapf(err, "go build %s: %s", pkg, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// LocatePlugin finds a plugin binary in the PATH, trying the
+// protoc-gen- prefix when name doesn't have it, as protoc does.
+func LocatePlugin(name string) (string, error) {
+	candidates := []string{name}
+	if !strings.HasPrefix(name, "protoc-gen-") {
+		candidates = append(candidates, "protoc-gen-"+name)
+	}
+
+	for _, candidate := range candidates {
+		path, err := exec.LookPath(candidate)
+		switch {
+		case err == nil:
+			return path, nil
+		case !errors.Is(err, exec.ErrNotFound):
+			return "", err
+		}
+	}
+	return "", core.Wrapf(core.ErrNotExists, "plugin %q not found", name)
+}
@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// buildTestPlugin compiles testdata/echo-plugin once per test.
+func buildTestPlugin(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("building plugins is slow")
+	}
+
+	path, err := BuildPlugin(context.Background(), "./testdata/echo-plugin", t.TempDir())
+	core.AssertMustNoError(t, err, "BuildPlugin")
+	return path
+}
+
+func newExecTestRequest(t *testing.T, parameter string) *pluginpb.CodeGeneratorRequest {
+	t.Helper()
+	file := File("a.proto").Package("a").Message("A").File().Message("B").Build()
+	req, err := NewCodeGeneratorRequest([]*descriptorpb.FileDescriptorProto{file},
+		WithParameter(parameter))
+	core.AssertMustNoError(t, err, "NewCodeGeneratorRequest")
+	return req
+}
+
+func TestPluginRunner(t *testing.T) {
+	r := &PluginRunner{Path: buildTestPlugin(t), Timeout: 5 * time.Second}
+	ctx := context.Background()
+
+	res, err := r.Run(ctx, newExecTestRequest(t, ""))
+	core.AssertMustNoError(t, err, "Run")
+	core.AssertEqual(t, 0, res.ExitCode, "exit code")
+	core.AssertEqual(t, "noise\n", res.Stderr, "stderr")
+	core.AssertMustEqual(t, 1, len(res.Response.File), "files")
+	core.AssertEqual(t, "A\nB\n", res.Response.File[0].GetContent(), "content")
+
+	res, err = r.Run(ctx, newExecTestRequest(t, "fail"))
+	core.AssertError(t, err, "fail")
+	core.AssertContains(t, err.Error(), "boom", "error includes stderr")
+	core.AssertEqual(t, 3, res.ExitCode, "exit code")
+	core.AssertNil(t, res.Response, "no response")
+
+	_, err = r.Run(ctx, newExecTestRequest(t, "garbage"))
+	core.AssertContains(t, err.Error(), "invalid response", "garbage")
+
+	r.Timeout = 200 * time.Millisecond
+	_, err = r.Run(ctx, newExecTestRequest(t, "sleep"))
+	core.AssertErrorIs(t, err, context.DeadlineExceeded, "timeout")
+}
+
+func TestPluginRunnerLeftoverChild(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+
+	for _, tc := range []struct {
+		name    string
+		script  string
+		timeout time.Duration
+	}{
+		{"exited", "sleep 30 &\nexit 0\n", 5 * time.Second},
+		{"killed", "sleep 30 &\nsleep 30\n", 200 * time.Millisecond},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "protoc-gen-leftover")
+			err := os.WriteFile(path, []byte("#!/bin/sh\n"+tc.script), 0o755)
+			core.AssertMustNoError(t, err, "WriteFile")
+
+			r := &PluginRunner{Path: path, Timeout: tc.timeout}
+			start := time.Now()
+			_, err = r.Run(context.Background(), newExecTestRequest(t, ""))
+			core.AssertError(t, err, "Run")
+			core.AssertTrue(t, time.Since(start) < 10*time.Second, "not blocked by the child")
+		})
+	}
+}
+
+func TestPluginRunnerGolden(t *testing.T) {
+	r := &PluginRunner{Path: buildTestPlugin(t)}
+	dir := t.TempDir()
+	core.AssertMustNoError(t, os.WriteFile(filepath.Join(dir, "a.txt.golden"), []byte("A\nB\n"), 0o644),
+		"golden")
+
+	core.RunTestCases(t, []GoldenTestCase{
+		NewGoldenTestCase("echo", dir, r.PluginFunc(), newExecTestRequest(t, "")),
+	})
+}
+
+func TestPluginRunnerMissing(t *testing.T) {
+	r := &PluginRunner{Path: filepath.Join(t.TempDir(), "missing")}
+	res, err := r.Run(context.Background(), &pluginpb.CodeGeneratorRequest{})
+	core.AssertError(t, err, "missing binary")
+	core.AssertEqual(t, -1, res.ExitCode, "exit code")
+}
+
+func TestLocatePlugin(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "protoc-gen-fake")
+	core.AssertMustNoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755), "WriteFile")
+	t.Setenv("PATH", dir)
+
+	found, err := LocatePlugin("fake")
+	core.AssertMustNoError(t, err, "LocatePlugin")
+	core.AssertEqual(t, path, found, "prefixed")
+
+	found, err = LocatePlugin("protoc-gen-fake")
+	core.AssertMustNoError(t, err, "LocatePlugin")
+	core.AssertEqual(t, path, found, "full name")
+
+	_, err = LocatePlugin("other")
+	core.AssertErrorIs(t, err, core.ErrNotExists, "missing")
+}
@@ -0,0 +1,152 @@
+package generator
+
+import (
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ExtensionInfo is an extension declaration found by ExtensionIndex.
+type ExtensionInfo struct {
+	// Field is the extension field.
+	Field *descriptorpb.FieldDescriptorProto
+	// File is the file declaring the extension.
+	File *descriptorpb.FileDescriptorProto
+	// FullName is the fully-qualified name of the extension, without
+	// leading dot.
+	FullName string
+	// Scope is the full name of the message the extension is declared
+	// in, empty for top-level extensions.
+	Scope string
+	// Extendee is the full name of the extended message, without leading
+	// dot.
+	Extendee string
+}
+
+// ExtensionIndex indexes the extensions declared across files by the
+// message they extend.
+type ExtensionIndex struct {
+	byExtendee map[string][]ExtensionInfo
+	byName     map[string]ExtensionInfo
+}
+
+// NewExtensionIndex collects the top-level and nested extensions of
+// the given files. Relative extendee names are resolved with protoc
+// scoping rules among the messages of the files.
+func NewExtensionIndex(files ...*descriptorpb.FileDescriptorProto) *ExtensionIndex {
+	x := &ExtensionIndex{
+		byExtendee: make(map[string][]ExtensionInfo),
+		byName:     make(map[string]ExtensionInfo),
+	}
+
+	r := NewRegistry(files...)
+	for _, file := range files {
+		x.addExtensions(r, file, "", file.Extension)
+		for _, msg := range file.MessageType {
+			x.addMessage(r, file, file.GetPackage(), msg)
+		}
+	}
+	return x
+}
+
+func (x *ExtensionIndex) addMessage(r *Registry, file *descriptorpb.FileDescriptorProto,
+	scope string, msg *descriptorpb.DescriptorProto) {
+	name := joinName(scope, msg.GetName())
+	x.addExtensions(r, file, name, msg.Extension)
+	for _, nested := range msg.NestedType {
+		x.addMessage(r, file, name, nested)
+	}
+}
+
+func (x *ExtensionIndex) addExtensions(r *Registry, file *descriptorpb.FileDescriptorProto,
+	scope string, fields []*descriptorpb.FieldDescriptorProto) {
+	lookup := scope
+	if lookup == "" {
+		lookup = file.GetPackage()
+	}
+
+	for _, field := range fields {
+		info := ExtensionInfo{
+			Field:    field,
+			File:     file,
+			FullName: joinName(lookup, field.GetName()),
+			Scope:    scope,
+			Extendee: resolveMessageName(r, lookup, field.GetExtendee()),
+		}
+		x.byExtendee[info.Extendee] = append(x.byExtendee[info.Extendee], info)
+		x.byName[info.FullName] = info
+	}
+}
+
+// resolveMessageName resolves a message name relative to a scope,
+// returning relative names that can't be resolved unchanged.
+func resolveMessageName(r *Registry, scope, name string) string {
+	return resolveName(scope, name, func(candidate string) bool {
+		_, ok := r.Message(candidate)
+		return ok
+	})
+}
+
+// resolveEnumName resolves an enum name relative to a scope like
+// resolveMessageName.
+func resolveEnumName(r *Registry, scope, name string) string {
+	return resolveName(scope, name, func(candidate string) bool {
+		_, ok := r.Enum(candidate)
+		return ok
+	})
+}
+
+// resolveName looks a relative name up from the scope outwards.
+func resolveName(scope, name string, exists func(string) bool) string {
+	if name == "" || name[0] == '.' {
+		return registryKey(name)
+	}
+
+	for ; ; scope = parentScope(scope) {
+		candidate := joinName(scope, name)
+		if exists(candidate) {
+			return candidate
+		}
+		if scope == "" {
+			return name
+		}
+	}
+}
+
+// Extendees returns the full names of the extended messages, sorted.
+func (x *ExtensionIndex) Extendees() []string {
+	return core.SortedKeys(x.byExtendee)
+}
+
+// Extensions returns the extensions of a message, with or without
+// leading dot, in declaration order.
+func (x *ExtensionIndex) Extensions(extendee string) []ExtensionInfo {
+	return core.SliceCopy(x.byExtendee[registryKey(extendee)])
+}
+
+// Lookup finds the extension of a message with the given number.
+func (x *ExtensionIndex) Lookup(extendee string, number int32) (ExtensionInfo, bool) {
+	for _, info := range x.byExtendee[registryKey(extendee)] {
+		if info.Field.GetNumber() == number {
+			return info, true
+		}
+	}
+	return ExtensionInfo{}, false
+}
+
+// ByName finds an extension by its full name, with or without leading dot.
+func (x *ExtensionIndex) ByName(name string) (ExtensionInfo, bool) {
+	info, ok := x.byName[registryKey(name)]
+	return info, ok
+}
+
+// InExtensionRange tells if a field number is within the extension
+// ranges of a message.
+func InExtensionRange(msg *descriptorpb.DescriptorProto, number int32) bool {
+	for _, r := range msg.GetExtensionRange() {
+		// end is exclusive
+		if number >= r.GetStart() && number < r.GetEnd() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func newExtensionTestFiles() []*descriptorpb.FileDescriptorProto {
+	base := NewFile("base.proto", "example")
+	base.MessageType = []*descriptorpb.DescriptorProto{
+		NewMessageWithExtensionRanges("Base",
+			[]*descriptorpb.DescriptorProto_ExtensionRange{
+				NewExtensionRange(100, 200),
+				NewExtensionRange(1000, 2000),
+			},
+			NewField("id", 1, TypeString)),
+	}
+	base.Extension = []*descriptorpb.FieldDescriptorProto{
+		NewExtensionField("top", 100, TypeString, ".example.Base"),
+	}
+
+	ext := NewFile("ext.proto", "example.ext")
+	outer := NewMessage("Outer")
+	outer.Extension = []*descriptorpb.FieldDescriptorProto{
+		// relative to example.ext.Outer, resolves to example.Base
+		NewMessageExtensionField("nested", 101, ".example.ext.Outer", "Base"),
+	}
+	ext.MessageType = []*descriptorpb.DescriptorProto{outer}
+	ext.Extension = []*descriptorpb.FieldDescriptorProto{
+		NewExtensionField("opt", 50000, TypeBool, ".google.protobuf.FieldOptions"),
+		NewExtensionField("unknown", 7, TypeBool, "Missing"),
+	}
+	return []*descriptorpb.FileDescriptorProto{base, ext}
+}
+
+func TestExtensionIndex(t *testing.T) {
+	files := newExtensionTestFiles()
+	x := NewExtensionIndex(files...)
+
+	core.AssertSliceEqual(t, []string{"Missing", "example.Base", "google.protobuf.FieldOptions"},
+		x.Extendees(), "Extendees")
+
+	exts := x.Extensions(".example.Base")
+	core.AssertMustEqual(t, 2, len(exts), "extensions of Base")
+	core.AssertEqual(t, "example.top", exts[0].FullName, "top-level name")
+	core.AssertEqual(t, "", exts[0].Scope, "top-level scope")
+	core.AssertTrue(t, exts[0].File == files[0], "top-level file")
+	core.AssertEqual(t, "example.ext.Outer.nested", exts[1].FullName, "nested name")
+	core.AssertEqual(t, "example.ext.Outer", exts[1].Scope, "nested scope")
+	core.AssertEqual(t, "example.Base", exts[1].Extendee, "resolved extendee")
+
+	info, ok := x.Lookup("example.Base", 101)
+	core.AssertTrue(t, ok, "Lookup")
+	core.AssertEqual(t, "nested", info.Field.GetName(), "Lookup field")
+	_, ok = x.Lookup("example.Base", 150)
+	core.AssertFalse(t, ok, "Lookup unused number")
+
+	info, ok = x.ByName(".example.ext.opt")
+	core.AssertTrue(t, ok, "ByName")
+	core.AssertEqual(t, "google.protobuf.FieldOptions", info.Extendee, "ByName extendee")
+	_, ok = x.ByName("example.ext.none")
+	core.AssertFalse(t, ok, "ByName missing")
+}
+
+func TestExtensionIndexParsed(t *testing.T) {
+	file, err := ParseProto("parsed.proto", `syntax = "proto2";
+package test;
+
+message Base {
+  extensions 10 to 20;
+}
+
+message Scope {
+  extend Base {
+    optional int32 inner = 10;
+  }
+}
+
+extend Base {
+  optional string outer = 11;
+}
+`)
+	core.AssertMustNoError(t, err, "ParseProto")
+
+	x := NewExtensionIndex(file)
+	var names []string
+	for _, info := range x.Extensions("test.Base") {
+		names = append(names, info.FullName)
+	}
+	core.AssertSliceEqual(t, []string{"test.outer", "test.Scope.inner"}, names, "extensions")
+}
+
+func TestInExtensionRange(t *testing.T) {
+	msg := newExtensionTestFiles()[0].MessageType[0]
+	core.AssertTrue(t, InExtensionRange(msg, 100), "range start")
+	core.AssertTrue(t, InExtensionRange(msg, 199), "range end")
+	core.AssertFalse(t, InExtensionRange(msg, 200), "end is exclusive")
+	core.AssertTrue(t, InExtensionRange(msg, 1500), "second range")
+	core.AssertFalse(t, InExtensionRange(msg, 1), "regular field")
+	core.AssertFalse(t, InExtensionRange(nil, 100), "nil message")
+}
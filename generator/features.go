@@ -0,0 +1,196 @@
+package generator
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// FileEdition returns the edition of a file, mapping the proto2 and
+// proto3 syntaxes to their pseudo-editions. Files without syntax are
+// proto2.
+func FileEdition(file *descriptorpb.FileDescriptorProto) descriptorpb.Edition {
+	switch file.GetSyntax() {
+	case "editions":
+		return file.GetEdition()
+	case "proto3":
+		return descriptorpb.Edition_EDITION_PROTO3
+	default:
+		return descriptorpb.Edition_EDITION_PROTO2
+	}
+}
+
+// EditionDefaults returns the default features of an edition. Editions
+// before proto2 use the proto2 defaults, and editions after the latest
+// known one use its defaults.
+func EditionDefaults(edition descriptorpb.Edition) *descriptorpb.FeatureSet {
+	fs := &descriptorpb.FeatureSet{
+		FieldPresence:           descriptorpb.FeatureSet_EXPLICIT.Enum(),
+		EnumType:                descriptorpb.FeatureSet_CLOSED.Enum(),
+		RepeatedFieldEncoding:   descriptorpb.FeatureSet_EXPANDED.Enum(),
+		Utf8Validation:          descriptorpb.FeatureSet_NONE.Enum(),
+		MessageEncoding:         descriptorpb.FeatureSet_LENGTH_PREFIXED.Enum(),
+		JsonFormat:              descriptorpb.FeatureSet_LEGACY_BEST_EFFORT.Enum(),
+		EnforceNamingStyle:      descriptorpb.FeatureSet_STYLE_LEGACY.Enum(),
+		DefaultSymbolVisibility: descriptorpb.FeatureSet_VisibilityFeature_EXPORT_ALL.Enum(),
+	}
+
+	if edition >= descriptorpb.Edition_EDITION_PROTO3 {
+		fs.FieldPresence = descriptorpb.FeatureSet_IMPLICIT.Enum()
+		fs.EnumType = descriptorpb.FeatureSet_OPEN.Enum()
+		fs.RepeatedFieldEncoding = descriptorpb.FeatureSet_PACKED.Enum()
+		fs.Utf8Validation = descriptorpb.FeatureSet_VERIFY.Enum()
+		fs.JsonFormat = descriptorpb.FeatureSet_ALLOW.Enum()
+	}
+	if edition >= descriptorpb.Edition_EDITION_2023 {
+		fs.FieldPresence = descriptorpb.FeatureSet_EXPLICIT.Enum()
+	}
+	if edition >= descriptorpb.Edition_EDITION_2024 {
+		fs.EnforceNamingStyle = descriptorpb.FeatureSet_STYLE2024.Enum()
+		fs.DefaultSymbolVisibility = descriptorpb.FeatureSet_VisibilityFeature_EXPORT_TOP_LEVEL.Enum()
+	}
+	return fs
+}
+
+// FileFeatures returns the resolved features of a file, its edition
+// defaults overridden by the features set in its options.
+func FileFeatures(file *descriptorpb.FileDescriptorProto) *descriptorpb.FeatureSet {
+	fs := EditionDefaults(FileEdition(file))
+	mergeFeatures(fs, file.GetOptions().GetFeatures())
+	return fs
+}
+
+// FieldFeatures returns the resolved features of a field or extension
+// declared in the given file. Features are inherited from the file,
+// the enclosing messages and the oneof, and the proto2 and proto3
+// equivalents are translated: required fields are LEGACY_REQUIRED,
+// groups DELIMITED, the packed option sets the repeated field encoding,
+// and proto3 optional fields have EXPLICIT presence.
+func FieldFeatures(file *descriptorpb.FileDescriptorProto,
+	field *descriptorpb.FieldDescriptorProto) *descriptorpb.FeatureSet {
+	fs := FileFeatures(file)
+
+	parents, oneof := fieldScope(file, field)
+	for _, msg := range parents {
+		mergeFeatures(fs, msg.GetOptions().GetFeatures())
+	}
+	mergeFeatures(fs, oneof.GetOptions().GetFeatures())
+	mergeFeatures(fs, field.GetOptions().GetFeatures())
+
+	if FileEdition(file) < descriptorpb.Edition_EDITION_2023 {
+		legacyFieldFeatures(fs, field)
+	}
+	return fs
+}
+
+func legacyFieldFeatures(fs *descriptorpb.FeatureSet, field *descriptorpb.FieldDescriptorProto) {
+	switch {
+	case field.GetLabel() == LabelRequired:
+		fs.FieldPresence = descriptorpb.FeatureSet_LEGACY_REQUIRED.Enum()
+	case field.GetProto3Optional():
+		fs.FieldPresence = descriptorpb.FeatureSet_EXPLICIT.Enum()
+	}
+
+	if field.GetType() == TypeGroup {
+		fs.MessageEncoding = descriptorpb.FeatureSet_DELIMITED.Enum()
+	}
+
+	if opts := field.GetOptions(); opts != nil && opts.Packed != nil {
+		if opts.GetPacked() {
+			fs.RepeatedFieldEncoding = descriptorpb.FeatureSet_PACKED.Enum()
+		} else {
+			fs.RepeatedFieldEncoding = descriptorpb.FeatureSet_EXPANDED.Enum()
+		}
+	}
+}
+
+func mergeFeatures(dst, src *descriptorpb.FeatureSet) {
+	if src != nil {
+		proto.Merge(dst, src)
+	}
+}
+
+// fieldScope finds the messages enclosing a field, outermost first,
+// and the oneof it belongs to. Fields not declared in the file have
+// no scope.
+func fieldScope(file *descriptorpb.FileDescriptorProto,
+	field *descriptorpb.FieldDescriptorProto) ([]*descriptorpb.DescriptorProto, *descriptorpb.OneofDescriptorProto) {
+	for _, msg := range file.GetMessageType() {
+		if parents, oneof, ok := findFieldScope(msg, field); ok {
+			return parents, oneof
+		}
+	}
+	return nil, nil
+}
+
+func findFieldScope(msg *descriptorpb.DescriptorProto,
+	field *descriptorpb.FieldDescriptorProto) ([]*descriptorpb.DescriptorProto, *descriptorpb.OneofDescriptorProto, bool) {
+	scope := []*descriptorpb.DescriptorProto{msg}
+	for _, f := range msg.Field {
+		if f == field {
+			return scope, fieldOneof(msg, field), true
+		}
+	}
+	for _, f := range msg.Extension {
+		if f == field {
+			return scope, nil, true
+		}
+	}
+
+	for _, nested := range msg.NestedType {
+		if parents, oneof, ok := findFieldScope(nested, field); ok {
+			return append(scope, parents...), oneof, true
+		}
+	}
+	return nil, nil, false
+}
+
+func fieldOneof(msg *descriptorpb.DescriptorProto,
+	field *descriptorpb.FieldDescriptorProto) *descriptorpb.OneofDescriptorProto {
+	if field.OneofIndex == nil {
+		return nil
+	}
+	i := int(field.GetOneofIndex())
+	if i < 0 || i >= len(msg.OneofDecl) {
+		return nil
+	}
+	return msg.OneofDecl[i]
+}
+
+// EnumFeatures returns the resolved features of an enum declared in the
+// given file, inherited from the file and the enclosing messages.
+func EnumFeatures(file *descriptorpb.FileDescriptorProto,
+	enum *descriptorpb.EnumDescriptorProto) *descriptorpb.FeatureSet {
+	fs := FileFeatures(file)
+	for _, msg := range enumScope(file, enum) {
+		mergeFeatures(fs, msg.GetOptions().GetFeatures())
+	}
+	mergeFeatures(fs, enum.GetOptions().GetFeatures())
+	return fs
+}
+
+// enumScope finds the messages enclosing an enum, outermost first.
+func enumScope(file *descriptorpb.FileDescriptorProto,
+	enum *descriptorpb.EnumDescriptorProto) []*descriptorpb.DescriptorProto {
+	for _, msg := range file.GetMessageType() {
+		if parents, ok := findEnumScope(msg, enum); ok {
+			return parents
+		}
+	}
+	return nil
+}
+
+func findEnumScope(msg *descriptorpb.DescriptorProto,
+	enum *descriptorpb.EnumDescriptorProto) ([]*descriptorpb.DescriptorProto, bool) {
+	scope := []*descriptorpb.DescriptorProto{msg}
+	for _, e := range msg.EnumType {
+		if e == enum {
+			return scope, true
+		}
+	}
+	for _, nested := range msg.NestedType {
+		if parents, ok := findEnumScope(nested, enum); ok {
+			return append(scope, parents...), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type editionDefaultsTestCase struct {
+	name     string
+	edition  descriptorpb.Edition
+	presence descriptorpb.FeatureSet_FieldPresence
+	enumType descriptorpb.FeatureSet_EnumType
+	repeated descriptorpb.FeatureSet_RepeatedFieldEncoding
+	naming   descriptorpb.FeatureSet_EnforceNamingStyle
+}
+
+func (tc editionDefaultsTestCase) Name() string {
+	return tc.name
+}
+
+func (tc editionDefaultsTestCase) Test(t *testing.T) {
+	t.Helper()
+	fs := EditionDefaults(tc.edition)
+	core.AssertEqual(t, tc.presence, fs.GetFieldPresence(), "field_presence")
+	core.AssertEqual(t, tc.enumType, fs.GetEnumType(), "enum_type")
+	core.AssertEqual(t, tc.repeated, fs.GetRepeatedFieldEncoding(), "repeated_field_encoding")
+	core.AssertEqual(t, tc.naming, fs.GetEnforceNamingStyle(), "enforce_naming_style")
+	core.AssertEqual(t, descriptorpb.FeatureSet_LENGTH_PREFIXED, fs.GetMessageEncoding(), "message_encoding")
+}
+
+var _ core.TestCase = editionDefaultsTestCase{}
+
+func TestEditionDefaults(t *testing.T) {
+	core.RunTestCases(t, []editionDefaultsTestCase{
+		{"proto2", descriptorpb.Edition_EDITION_PROTO2, descriptorpb.FeatureSet_EXPLICIT,
+			descriptorpb.FeatureSet_CLOSED, descriptorpb.FeatureSet_EXPANDED, descriptorpb.FeatureSet_STYLE_LEGACY},
+		{"proto3", descriptorpb.Edition_EDITION_PROTO3, descriptorpb.FeatureSet_IMPLICIT,
+			descriptorpb.FeatureSet_OPEN, descriptorpb.FeatureSet_PACKED, descriptorpb.FeatureSet_STYLE_LEGACY},
+		{"2023", descriptorpb.Edition_EDITION_2023, descriptorpb.FeatureSet_EXPLICIT,
+			descriptorpb.FeatureSet_OPEN, descriptorpb.FeatureSet_PACKED, descriptorpb.FeatureSet_STYLE_LEGACY},
+		{"2024", descriptorpb.Edition_EDITION_2024, descriptorpb.FeatureSet_EXPLICIT,
+			descriptorpb.FeatureSet_OPEN, descriptorpb.FeatureSet_PACKED, descriptorpb.FeatureSet_STYLE2024},
+		{"legacy", descriptorpb.Edition_EDITION_LEGACY, descriptorpb.FeatureSet_EXPLICIT,
+			descriptorpb.FeatureSet_CLOSED, descriptorpb.FeatureSet_EXPANDED, descriptorpb.FeatureSet_STYLE_LEGACY},
+	})
+}
+
+func TestFileEdition(t *testing.T) {
+	core.AssertEqual(t, descriptorpb.Edition_EDITION_PROTO2,
+		FileEdition(&descriptorpb.FileDescriptorProto{}), "no syntax")
+	core.AssertEqual(t, descriptorpb.Edition_EDITION_PROTO3,
+		FileEdition(&descriptorpb.FileDescriptorProto{Syntax: proto.String("proto3")}), "proto3")
+	core.AssertEqual(t, descriptorpb.Edition_EDITION_2023,
+		FileEdition(&descriptorpb.FileDescriptorProto{
+			Syntax:  proto.String("editions"),
+			Edition: descriptorpb.Edition_EDITION_2023.Enum(),
+		}), "editions")
+}
+
+func TestFieldFeatures(t *testing.T) {
+	file, err := ParseProto("features.proto", `edition = "2023";
+package f;
+option features.field_presence = IMPLICIT;
+message Outer {
+  option features.utf8_validation = NONE;
+  message Inner {
+    oneof choice {
+      option features.json_format = LEGACY_BEST_EFFORT;
+      string a = 1;
+    }
+    string b = 2 [features.field_presence = LEGACY_REQUIRED];
+  }
+}
+`)
+	core.AssertMustNoError(t, err, "ParseProto")
+
+	a := findTestField(file.MessageType, []string{"Outer", "Inner", "a"})
+	fs := FieldFeatures(file, a)
+	core.AssertEqual(t, descriptorpb.FeatureSet_IMPLICIT, fs.GetFieldPresence(), "file feature")
+	core.AssertEqual(t, descriptorpb.FeatureSet_NONE, fs.GetUtf8Validation(), "message feature")
+	core.AssertEqual(t, descriptorpb.FeatureSet_LEGACY_BEST_EFFORT, fs.GetJsonFormat(), "oneof feature")
+	core.AssertEqual(t, descriptorpb.FeatureSet_PACKED, fs.GetRepeatedFieldEncoding(), "edition default")
+
+	b := findTestField(file.MessageType, []string{"Outer", "Inner", "b"})
+	fs = FieldFeatures(file, b)
+	core.AssertEqual(t, descriptorpb.FeatureSet_LEGACY_REQUIRED, fs.GetFieldPresence(), "field feature")
+	core.AssertEqual(t, descriptorpb.FeatureSet_ALLOW, fs.GetJsonFormat(), "outside oneof")
+}
+
+func TestFieldFeaturesLegacy(t *testing.T) {
+	file, err := ParseProto("legacy.proto", `syntax = "proto2";
+package f;
+message M {
+  required int32 a = 1;
+  optional group G = 2 { optional int32 x = 1; }
+  repeated int32 c = 3 [packed = true];
+}
+`)
+	core.AssertMustNoError(t, err, "ParseProto")
+
+	fs := FieldFeatures(file, findTestField(file.MessageType, []string{"M", "a"}))
+	core.AssertEqual(t, descriptorpb.FeatureSet_LEGACY_REQUIRED, fs.GetFieldPresence(), "required")
+
+	fs = FieldFeatures(file, findTestField(file.MessageType, []string{"M", "g"}))
+	core.AssertEqual(t, descriptorpb.FeatureSet_DELIMITED, fs.GetMessageEncoding(), "group")
+
+	fs = FieldFeatures(file, findTestField(file.MessageType, []string{"M", "c"}))
+	core.AssertEqual(t, descriptorpb.FeatureSet_PACKED, fs.GetRepeatedFieldEncoding(), "packed")
+
+	file, err = ParseProto("optional.proto", `syntax = "proto3";
+package f;
+message M { optional int32 a = 1; int32 b = 2; }
+`)
+	core.AssertMustNoError(t, err, "ParseProto")
+
+	fs = FieldFeatures(file, findTestField(file.MessageType, []string{"M", "a"}))
+	core.AssertEqual(t, descriptorpb.FeatureSet_EXPLICIT, fs.GetFieldPresence(), "proto3 optional")
+	fs = FieldFeatures(file, findTestField(file.MessageType, []string{"M", "b"}))
+	core.AssertEqual(t, descriptorpb.FeatureSet_IMPLICIT, fs.GetFieldPresence(), "proto3 implicit")
+}
@@ -0,0 +1,67 @@
+package generator
+
+import (
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// FileFunc generates the outputs of one of the files to generate of a
+// request, independently of the others. Functions given to
+// ParallelFilePlugin are called from several goroutines at once, and
+// must be safe for concurrent use.
+type FileFunc func(req *pluginpb.CodeGeneratorRequest,
+	file *descriptorpb.FileDescriptorProto) ([]*pluginpb.CodeGeneratorResponse_File, error)
+
+// FilePlugin returns a plugin calling fn for each file to generate, in
+// order, and concatenating their outputs. The response declares the
+// given features as supported.
+func FilePlugin(fn FileFunc, features ...pluginpb.CodeGeneratorResponse_Feature) PluginFunc {
+	return func(req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+		files, err := filesToGenerate(req)
+		if err != nil {
+			return nil, err
+		}
+
+		resp := newFileResponse(features)
+		for _, file := range files {
+			out, err := fn(req, file)
+			if err != nil {
+				return nil, core.Wrapf(err, "%s", file.GetName())
+			}
+			resp.File = append(resp.File, out...)
+		}
+		return resp, nil
+	}
+}
+
+// filesToGenerate returns the descriptors of the files to generate.
+func filesToGenerate(req *pluginpb.CodeGeneratorRequest) ([]*descriptorpb.FileDescriptorProto, error) {
+	byName := make(map[string]*descriptorpb.FileDescriptorProto, len(req.GetProtoFile()))
+	for _, file := range req.GetProtoFile() {
+		byName[file.GetName()] = file
+	}
+
+	out := make([]*descriptorpb.FileDescriptorProto, 0, len(req.GetFileToGenerate()))
+	for _, name := range req.GetFileToGenerate() {
+		file, ok := byName[name]
+		if !ok {
+			return nil, core.Wrapf(core.ErrNotExists, "file to generate %q not in request", name)
+		}
+		out = append(out, file)
+	}
+	return out, nil
+}
+
+func newFileResponse(features []pluginpb.CodeGeneratorResponse_Feature) *pluginpb.CodeGeneratorResponse {
+	resp := &pluginpb.CodeGeneratorResponse{}
+	if len(features) > 0 {
+		var bits uint64
+		for _, f := range features {
+			bits |= uint64(f)
+		}
+		resp.SupportedFeatures = proto.Uint64(bits)
+	}
+	return resp
+}
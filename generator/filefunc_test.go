@@ -0,0 +1,87 @@
+package generator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// newFileFuncTestRequest builds a request generating a.proto, b.proto
+// importing it, and the independent c.proto.
+func newFileFuncTestRequest(t *testing.T, sources map[string]string,
+	opts ...RequestOption) *pluginpb.CodeGeneratorRequest {
+	t.Helper()
+	files := map[string]string{
+		"a.proto": `syntax = "proto3"; package a; message A { int32 n = 1; }`,
+		"b.proto": `syntax = "proto3"; package b; import "a.proto"; message B { a.A a = 1; }`,
+		"c.proto": `syntax = "proto3"; package c; message C {}`,
+	}
+	for name, src := range sources {
+		files[name] = src
+	}
+
+	p := &Parser{Files: files}
+	parsed, err := p.Parse("a.proto", "b.proto", "c.proto")
+	core.AssertMustNoError(t, err, "Parse")
+	req, err := NewCodeGeneratorRequest(parsed, opts...)
+	core.AssertMustNoError(t, err, "NewCodeGeneratorRequest")
+	return req
+}
+
+// listFileMessages is a FileFunc listing the messages of each file.
+func listFileMessages(_ *pluginpb.CodeGeneratorRequest,
+	file *descriptorpb.FileDescriptorProto) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+	var names []string
+	for _, msg := range file.MessageType {
+		names = append(names, msg.GetName())
+	}
+	return []*pluginpb.CodeGeneratorResponse_File{{
+		Name:    proto.String(strings.TrimSuffix(file.GetName(), ".proto") + ".txt"),
+		Content: proto.String(strings.Join(names, "\n") + "\n"),
+	}}, nil
+}
+
+func TestFilePlugin(t *testing.T) {
+	req := newFileFuncTestRequest(t, nil)
+
+	resp, err := FilePlugin(listFileMessages)(req)
+	core.AssertMustNoError(t, err, "FilePlugin")
+	core.AssertNil(t, resp.SupportedFeatures, "features")
+
+	var names []string
+	for _, f := range resp.File {
+		names = append(names, f.GetName())
+	}
+	core.AssertSliceEqual(t, []string{"a.txt", "b.txt", "c.txt"}, names, "outputs")
+	core.AssertEqual(t, "B\n", resp.File[1].GetContent(), "content")
+
+	resp, err = FilePlugin(listFileMessages,
+		pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL,
+		pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS)(req)
+	core.AssertMustNoError(t, err, "FilePlugin features")
+	core.AssertEqual(t, uint64(3), resp.GetSupportedFeatures(), "features")
+}
+
+func TestFilePluginErrors(t *testing.T) {
+	req := newFileFuncTestRequest(t, nil)
+
+	errFail := errors.New("fail")
+	_, err := FilePlugin(func(_ *pluginpb.CodeGeneratorRequest,
+		file *descriptorpb.FileDescriptorProto) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+		if file.GetName() == "b.proto" {
+			return nil, errFail
+		}
+		return nil, nil
+	})(req)
+	core.AssertErrorIs(t, err, errFail, "generator error")
+	core.AssertContains(t, err.Error(), "b.proto", "file named")
+
+	req.FileToGenerate = append(req.FileToGenerate, "missing.proto")
+	_, err = FilePlugin(listFileMessages)(req)
+	core.AssertErrorIs(t, err, core.ErrNotExists, "missing file")
+}
@@ -0,0 +1,236 @@
+package generator
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// GoldenUpdateEnv is the environment variable that, when true, makes
+// golden tests rewrite their golden files instead of comparing them.
+const GoldenUpdateEnv = "GOLDEN_UPDATE"
+
+// goldenSuffix is appended to the generated file names.
+const goldenSuffix = ".golden"
+
+// PluginFunc runs a protoc plugin in-process.
+type PluginFunc func(*pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error)
+
+// IsGoldenUpdate tells if GoldenUpdateEnv requests rewriting the
+// golden files.
+func IsGoldenUpdate() bool {
+	update, _ := strconv.ParseBool(os.Getenv(GoldenUpdateEnv))
+	return update
+}
+
+// GoldenTestCase runs a plugin against a request and compares the
+// files it generates with those in a golden directory.
+type GoldenTestCase struct {
+	// Request is passed to the plugin.
+	Request *pluginpb.CodeGeneratorRequest
+	// Plugin generates the files.
+	Plugin PluginFunc
+	// Dir holds a <name>.golden file for each generated file.
+	Dir  string
+	name string
+	// Update rewrites the golden files instead of comparing them.
+	Update bool
+	// Prune tells Dir only holds the golden files of this case, so
+	// those not generated are reported, or removed in update mode.
+	// Leave it unset when cases share a directory.
+	Prune bool
+}
+
+var _ core.TestCase = GoldenTestCase{}
+
+// NewGoldenTestCase creates a golden test case, in update mode when
+// GoldenUpdateEnv says so.
+func NewGoldenTestCase(name, dir string, plugin PluginFunc,
+	req *pluginpb.CodeGeneratorRequest) GoldenTestCase {
+	return GoldenTestCase{
+		Request: req,
+		Plugin:  plugin,
+		Dir:     dir,
+		Update:  IsGoldenUpdate(),
+		name:    name,
+	}
+}
+
+// Name returns the name of the test case.
+func (tc GoldenTestCase) Name() string {
+	return tc.name
+}
+
+// Test runs the plugin and checks its output.
+func (tc GoldenTestCase) Test(t *testing.T) {
+	t.Helper()
+	resp, err := tc.Plugin(tc.Request)
+	core.AssertMustNoError(t, err, "plugin")
+	if tc.Prune {
+		AssertGoldenDir(t, tc.Dir, resp, tc.Update)
+	} else {
+		AssertGolden(t, tc.Dir, resp, tc.Update)
+	}
+}
+
+// AssertGolden compares the files of a response with the golden files
+// in dir, reporting a unified diff for each mismatch and missing golden
+// files. In update mode the golden files are rewritten instead. Other
+// files in dir are left alone, so cases can share it.
+//
+// Content for insertion points is compared with <name>@<point>.golden,
+// and files without name are appended to the previous one as protoc does.
+func AssertGolden(t core.T, dir string, resp *pluginpb.CodeGeneratorResponse, update bool) bool {
+	t.Helper()
+	files, ok := goldenResponse(t, resp)
+	if !ok {
+		return false
+	}
+	return assertGolden(t, dir, files, nil, update)
+}
+
+// AssertGoldenDir is AssertGolden for a dir holding only the golden
+// files of this response. Golden files under it not generated are also
+// reported, and removed in update mode.
+func AssertGoldenDir(t core.T, dir string, resp *pluginpb.CodeGeneratorResponse, update bool) bool {
+	t.Helper()
+	files, ok := goldenResponse(t, resp)
+	if !ok {
+		return false
+	}
+
+	existing, err := listGolden(dir)
+	if err != nil {
+		t.Errorf("golden: %v", err)
+		return false
+	}
+	for name := range files {
+		delete(existing, name)
+	}
+	return assertGolden(t, dir, files, existing, update)
+}
+
+func goldenResponse(t core.T, resp *pluginpb.CodeGeneratorResponse) (map[string]string, bool) {
+	t.Helper()
+	if resp.GetError() != "" {
+		t.Errorf("plugin error: %s", resp.GetError())
+		return nil, false
+	}
+	return goldenFiles(resp), true
+}
+
+// assertGolden checks or updates the golden files of the generated
+// files, and the stale ones.
+func assertGolden(t core.T, dir string, files map[string]string, stale map[string]bool, update bool) bool {
+	t.Helper()
+	if update {
+		return updateGolden(t, dir, files, stale)
+	}
+
+	ok := true
+	for _, name := range core.SortedKeys(files) {
+		ok = checkGolden(t, dir, name, files[name]) && ok
+	}
+	for _, name := range core.SortedKeys(stale) {
+		t.Errorf("golden: %s%s not generated", name, goldenSuffix)
+		ok = false
+	}
+	return ok
+}
+
+func checkGolden(t core.T, dir, name, content string) bool {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name)+goldenSuffix)
+	want, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		t.Errorf("golden: %s missing, set %s=1 to create it", path, GoldenUpdateEnv)
+		return false
+	case err != nil:
+		t.Errorf("golden: %v", err)
+		return false
+	}
+
+	if diff := UnifiedDiff(path, name, string(want), content); diff != "" {
+		t.Errorf("golden: %s differs:\n%s", name, diff)
+		return false
+	}
+	return true
+}
+
+func updateGolden(t core.T, dir string, files map[string]string, stale map[string]bool) bool {
+	t.Helper()
+	for _, name := range core.SortedKeys(files) {
+		path := filepath.Join(dir, filepath.FromSlash(name)+goldenSuffix)
+		if err := writeGolden(path, files[name]); err != nil {
+			t.Errorf("golden: %v", err)
+			return false
+		}
+	}
+
+	for _, name := range core.SortedKeys(stale) {
+		path := filepath.Join(dir, filepath.FromSlash(name)+goldenSuffix)
+		if err := os.Remove(path); err != nil {
+			t.Errorf("golden: %v", err)
+			return false
+		}
+	}
+	return true
+}
+
+func writeGolden(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0o644)
+}
+
+// goldenFiles returns the content of each file of a response by its
+// golden name, merging the files continuing the previous one.
+func goldenFiles(resp *pluginpb.CodeGeneratorResponse) map[string]string {
+	out := make(map[string]string)
+	var last string
+	for _, f := range resp.GetFile() {
+		name := f.GetName()
+		switch {
+		case name == "":
+			name = last
+		case f.GetInsertionPoint() != "":
+			name += "@" + f.GetInsertionPoint()
+		}
+		out[name] += f.GetContent()
+		last = name
+	}
+	return out
+}
+
+// listGolden returns the names of the golden files under dir, which
+// doesn't need to exist.
+func listGolden(dir string) (map[string]bool, error) {
+	out := make(map[string]bool)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		switch {
+		case errors.Is(err, fs.ErrNotExist) && path == dir:
+			return filepath.SkipDir
+		case err != nil:
+			return err
+		case d.IsDir() || !strings.HasSuffix(path, goldenSuffix):
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		out[strings.TrimSuffix(filepath.ToSlash(rel), goldenSuffix)] = true
+		return nil
+	})
+	return out, err
+}
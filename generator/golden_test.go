@@ -0,0 +1,146 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// listMessagesPlugin generates a file per input file listing its
+// messages.
+func listMessagesPlugin(req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	resp := &pluginpb.CodeGeneratorResponse{}
+	for _, name := range req.FileToGenerate {
+		var sb strings.Builder
+		for _, file := range req.ProtoFile {
+			if file.GetName() != name {
+				continue
+			}
+			for _, msg := range file.MessageType {
+				_, _ = sb.WriteString(msg.GetName() + "\n")
+			}
+		}
+		resp.File = append(resp.File, &pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String(strings.TrimSuffix(name, ".proto") + ".txt"),
+			Content: proto.String(sb.String()),
+		})
+	}
+	return resp, nil
+}
+
+func newGoldenTestRequest(t *testing.T, messages ...string) *pluginpb.CodeGeneratorRequest {
+	t.Helper()
+	b := File("pkg/a.proto").Package("a")
+	for _, name := range messages {
+		b.Message(name)
+	}
+	req, err := NewCodeGeneratorRequest([]*descriptorpb.FileDescriptorProto{b.Build()})
+	core.AssertMustNoError(t, err, "NewCodeGeneratorRequest")
+	return req
+}
+
+func TestGoldenTestCase(t *testing.T) {
+	dir := t.TempDir()
+	tc := NewGoldenTestCase("list", dir, listMessagesPlugin, newGoldenTestRequest(t, "A", "B"))
+	core.AssertEqual(t, "list", tc.Name(), "Name")
+
+	tc.Update = true
+	tc.Test(t)
+
+	golden, err := os.ReadFile(filepath.Join(dir, "pkg", "a.txt.golden"))
+	core.AssertMustNoError(t, err, "golden written")
+	core.AssertEqual(t, "A\nB\n", string(golden), "golden content")
+
+	tc.Update = false
+	tc.Test(t)
+}
+
+func TestAssertGoldenMismatch(t *testing.T) {
+	dir := t.TempDir()
+	resp, err := listMessagesPlugin(newGoldenTestRequest(t, "A", "B"))
+	core.AssertMustNoError(t, err, "plugin")
+	core.AssertTrue(t, AssertGolden(t, dir, resp, true), "update")
+
+	resp, err = listMessagesPlugin(newGoldenTestRequest(t, "A", "C"))
+	core.AssertMustNoError(t, err, "plugin")
+
+	mock := &core.MockT{}
+	core.AssertFalse(t, AssertGolden(mock, dir, resp, false), "mismatch")
+	core.AssertMustEqual(t, 1, len(mock.Errors), "errors")
+	core.AssertContains(t, mock.Errors[0], "@@ -1,2 +1,2 @@\n A\n-B\n+C\n", "diff")
+}
+
+func TestAssertGoldenFiles(t *testing.T) {
+	dir := t.TempDir()
+	sibling := filepath.Join(dir, "sibling.txt.golden")
+	core.AssertMustNoError(t, os.WriteFile(sibling, []byte("x"), 0o644), "sibling golden")
+
+	resp := &pluginpb.CodeGeneratorResponse{File: []*pluginpb.CodeGeneratorResponse_File{
+		{Name: proto.String("a.txt"), Content: proto.String("one\n")},
+		{Content: proto.String("two\n")},
+		{Name: proto.String("a.txt"), InsertionPoint: proto.String("imports"), Content: proto.String("x\n")},
+	}}
+
+	mock := &core.MockT{}
+	core.AssertFalse(t, AssertGolden(mock, dir, resp, false), "missing")
+	core.AssertMustEqual(t, 2, len(mock.Errors), "errors")
+	core.AssertContains(t, mock.Errors[0], "a.txt.golden missing", "missing")
+	core.AssertContains(t, mock.Errors[1], "a.txt@imports.golden missing", "insertion point")
+
+	// golden files of other cases sharing dir are left alone
+	core.AssertTrue(t, AssertGolden(t, dir, resp, true), "update")
+	_, err := os.Stat(sibling)
+	core.AssertNoError(t, err, "sibling kept")
+
+	content, err := os.ReadFile(filepath.Join(dir, "a.txt.golden"))
+	core.AssertMustNoError(t, err, "ReadFile")
+	core.AssertEqual(t, "one\ntwo\n", string(content), "continuation")
+	core.AssertTrue(t, AssertGolden(t, dir, resp, false), "matches")
+}
+
+func TestAssertGoldenDir(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "sub", "stale.txt.golden")
+	core.AssertMustNoError(t, writeGolden(stale, "x"), "stale golden")
+
+	resp := &pluginpb.CodeGeneratorResponse{File: []*pluginpb.CodeGeneratorResponse_File{
+		{Name: proto.String("a.txt"), Content: proto.String("one\n")},
+	}}
+	core.AssertTrue(t, AssertGolden(t, dir, resp, true), "write")
+
+	mock := &core.MockT{}
+	core.AssertFalse(t, AssertGoldenDir(mock, dir, resp, false), "stale")
+	core.AssertMustEqual(t, 1, len(mock.Errors), "errors")
+	core.AssertContains(t, mock.Errors[0], "sub/stale.txt.golden not generated", "stale")
+
+	core.AssertTrue(t, AssertGoldenDir(t, dir, resp, true), "update")
+	_, err := os.Stat(stale)
+	core.AssertTrue(t, os.IsNotExist(err), "stale removed")
+	core.AssertTrue(t, AssertGoldenDir(t, dir, resp, false), "matches")
+
+	tc := NewGoldenTestCase("prune", dir, func(*pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+		return resp, nil
+	}, nil)
+	tc.Prune = true
+	tc.Test(t)
+}
+
+func TestAssertGoldenPluginError(t *testing.T) {
+	mock := &core.MockT{}
+	resp := &pluginpb.CodeGeneratorResponse{Error: proto.String("boom")}
+	core.AssertFalse(t, AssertGolden(mock, t.TempDir(), resp, false), "plugin error")
+	core.AssertContains(t, mock.Errors[0], "boom", "error")
+}
+
+func TestIsGoldenUpdate(t *testing.T) {
+	t.Setenv(GoldenUpdateEnv, "1")
+	core.AssertTrue(t, IsGoldenUpdate(), "set")
+	t.Setenv(GoldenUpdateEnv, "")
+	core.AssertFalse(t, IsGoldenUpdate(), "unset")
+}
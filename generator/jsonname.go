@@ -0,0 +1,180 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// JSONName computes the JSON name protoc assigns to a field when no
+// json_name is given explicitly. Underscores are removed and the
+// letter following each underscore is upper-cased.
+func JSONName(name string) string {
+	var sb strings.Builder
+	sb.Grow(len(name))
+
+	upper := false
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_':
+			upper = true
+		case upper && 'a' <= c && c <= 'z':
+			_ = sb.WriteByte(c - 'a' + 'A')
+			upper = false
+		default:
+			_ = sb.WriteByte(c)
+			upper = false
+		}
+	}
+	return sb.String()
+}
+
+// FieldJSONName returns the JSON name of a field, its json_name when
+// set or the default computed from its name.
+func FieldJSONName(field *descriptorpb.FieldDescriptorProto) string {
+	if field.JsonName != nil {
+		return field.GetJsonName()
+	}
+	return JSONName(field.GetName())
+}
+
+// HasCustomJSONName tells if a field sets a json_name different from
+// the default.
+func HasCustomJSONName(field *descriptorpb.FieldDescriptorProto) bool {
+	return field.JsonName != nil && field.GetJsonName() != JSONName(field.GetName())
+}
+
+//
+// Conflicts
+//
+
+// JSONNameConflict is a pair of fields of a message mapping to the
+// same JSON name.
+type JSONNameConflict struct {
+	// Message is the fully-qualified name of the message, with leading dot.
+	Message string
+	// Field is the name of the conflicting field.
+	Field string
+	// Previous is the name of the earlier field it conflicts with.
+	Previous string
+	// JSONName is the name both fields map to.
+	JSONName string
+	// FieldCustom is set when the name of Field is its custom json_name,
+	// and clear when it is the default one.
+	FieldCustom bool
+	// PreviousCustom is set when the name of Previous is its custom
+	// json_name, and clear when it is the default one.
+	PreviousCustom bool
+	// Warning is set when the message uses the LEGACY_BEST_EFFORT json
+	// format and a default name is involved, which protoc tolerates.
+	Warning bool
+}
+
+// String describes the conflict as protoc does.
+func (c JSONNameConflict) String() string {
+	return fmt.Sprintf("The %s JSON name of field %q (%q) conflicts with the %s JSON name of field %q.",
+		jsonNameKind(c.FieldCustom), c.Field, c.JSONName, jsonNameKind(c.PreviousCustom), c.Previous)
+}
+
+func jsonNameKind(custom bool) string {
+	if custom {
+		return "custom"
+	}
+	return "default"
+}
+
+// CheckJSONNames checks the fields of the named message of a file map
+// to distinct JSON names, both by their defaults and taking json_name
+// into account. Messages with the deprecated_legacy_json_field_conflicts
+// option use the legacy check instead, comparing lower-cased names
+// without underscores in proto3 files only.
+func CheckJSONNames(file *descriptorpb.FileDescriptorProto, name string) ([]JSONNameConflict, error) {
+	msg, ok := NewRegistry(file).Message(name)
+	if !ok {
+		return nil, core.Wrapf(core.ErrNotExists, "message %q not found in %q", name, file.GetName())
+	}
+	return checkJSONNames(file, "."+registryKey(name), msg), nil
+}
+
+// CheckFileJSONNames checks the JSON names of every message of a file,
+// in declaration order.
+func CheckFileJSONNames(file *descriptorpb.FileDescriptorProto) []JSONNameConflict {
+	var out []JSONNameConflict
+	r := NewRegistry(file)
+	for _, name := range r.MessageNames() {
+		msg, _ := r.Message(name)
+		out = append(out, checkJSONNames(file, name, msg)...)
+	}
+	return out
+}
+
+func checkJSONNames(file *descriptorpb.FileDescriptorProto, name string,
+	msg *descriptorpb.DescriptorProto) []JSONNameConflict {
+	if msg.GetOptions().GetDeprecatedLegacyJsonFieldConflicts() {
+		if file.GetSyntax() != "proto3" {
+			return nil
+		}
+		return legacyJSONConflicts(name, msg)
+	}
+
+	out := jsonConflicts(file, name, msg, false)
+	return append(out, jsonConflicts(file, name, msg, true)...)
+}
+
+func jsonConflicts(file *descriptorpb.FileDescriptorProto, name string,
+	msg *descriptorpb.DescriptorProto, custom bool) []JSONNameConflict {
+	var out []JSONNameConflict
+	seen := make(map[string]*descriptorpb.FieldDescriptorProto)
+	for _, field := range msg.Field {
+		jsonName := JSONName(field.GetName())
+		if custom {
+			jsonName = FieldJSONName(field)
+		}
+
+		prev, ok := seen[jsonName]
+		if !ok {
+			seen[jsonName] = field
+			continue
+		}
+		if custom && !HasCustomJSONName(field) && !HasCustomJSONName(prev) {
+			// already reported comparing the defaults
+			continue
+		}
+
+		fieldCustom := custom && HasCustomJSONName(field)
+		prevCustom := custom && HasCustomJSONName(prev)
+		legacy := FieldFeatures(file, field).GetJsonFormat() == descriptorpb.FeatureSet_LEGACY_BEST_EFFORT
+		out = append(out, JSONNameConflict{
+			Message:        name,
+			Field:          field.GetName(),
+			Previous:       prev.GetName(),
+			JSONName:       jsonName,
+			FieldCustom:    fieldCustom,
+			PreviousCustom: prevCustom,
+			Warning:        legacy && !(fieldCustom && prevCustom),
+		})
+	}
+	return out
+}
+
+func legacyJSONConflicts(name string, msg *descriptorpb.DescriptorProto) []JSONNameConflict {
+	var out []JSONNameConflict
+	seen := make(map[string]*descriptorpb.FieldDescriptorProto)
+	for _, field := range msg.Field {
+		key := strings.ToLower(strings.ReplaceAll(field.GetName(), "_", ""))
+		if prev, ok := seen[key]; ok {
+			out = append(out, JSONNameConflict{
+				Message:  name,
+				Field:    field.GetName(),
+				Previous: prev.GetName(),
+				JSONName: JSONName(field.GetName()),
+			})
+			continue
+		}
+		seen[key] = field
+	}
+	return out
+}
@@ -0,0 +1,201 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type jsonNameTestCase struct {
+	name     string
+	expected string
+}
+
+func (tc jsonNameTestCase) Name() string {
+	return tc.name
+}
+
+func (tc jsonNameTestCase) Test(t *testing.T) {
+	t.Helper()
+	core.AssertEqual(t, tc.expected, JSONName(tc.name), "JSONName")
+}
+
+var _ core.TestCase = jsonNameTestCase{}
+
+func TestJSONName(t *testing.T) {
+	core.RunTestCases(t, []jsonNameTestCase{
+		{"foo", "foo"},
+		{"foo_bar", "fooBar"},
+		{"foo_bar_baz", "fooBarBaz"},
+		{"FooBar", "FooBar"},
+		{"_foo", "Foo"},
+		{"foo__bar", "fooBar"},
+		{"foo_", "foo"},
+		{"foo_1bar", "foo1bar"},
+		{"foo_Bar", "fooBar"},
+	})
+}
+
+func TestFieldJSONName(t *testing.T) {
+	field := &descriptorpb.FieldDescriptorProto{Name: proto.String("user_id")}
+	core.AssertEqual(t, "userId", FieldJSONName(field), "default")
+	core.AssertFalse(t, HasCustomJSONName(field), "unset")
+
+	field.JsonName = proto.String("userId")
+	core.AssertFalse(t, HasCustomJSONName(field), "same as default")
+
+	field.JsonName = proto.String("uid")
+	core.AssertEqual(t, "uid", FieldJSONName(field), "custom")
+	core.AssertTrue(t, HasCustomJSONName(field), "custom")
+}
+
+func newJSONTestFile(syntax string, fields ...*descriptorpb.FieldDescriptorProto) *descriptorpb.FileDescriptorProto {
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("json.proto"),
+		Package: proto.String("j"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("M"), Field: fields},
+		},
+	}
+	switch syntax {
+	case "proto2":
+	case "2023":
+		file.Syntax = proto.String("editions")
+		file.Edition = descriptorpb.Edition_EDITION_2023.Enum()
+	default:
+		file.Syntax = proto.String(syntax)
+	}
+	return file
+}
+
+func newJSONTestField(name, jsonName string, number int32) *descriptorpb.FieldDescriptorProto {
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Type:   TypeString.Enum(),
+		Label:  LabelOptional.Enum(),
+	}
+	if jsonName != "" {
+		field.JsonName = proto.String(jsonName)
+	}
+	return field
+}
+
+type jsonConflictTestCase struct {
+	name     string
+	file     *descriptorpb.FileDescriptorProto
+	expected []JSONNameConflict
+}
+
+func (tc jsonConflictTestCase) Name() string {
+	return tc.name
+}
+
+func (tc jsonConflictTestCase) Test(t *testing.T) {
+	t.Helper()
+	got, err := CheckJSONNames(tc.file, "j.M")
+	core.AssertMustNoError(t, err, "CheckJSONNames")
+	core.AssertSliceEqual(t, tc.expected, got, "conflicts")
+}
+
+var _ core.TestCase = jsonConflictTestCase{}
+
+func TestCheckJSONNames(t *testing.T) {
+	core.RunTestCases(t, []jsonConflictTestCase{
+		{
+			name: "proto3 default conflict",
+			file: newJSONTestFile("proto3",
+				newJSONTestField("foo_bar", "", 1), newJSONTestField("fooBar", "", 2)),
+			expected: []JSONNameConflict{
+				{Message: ".j.M", Field: "fooBar", Previous: "foo_bar", JSONName: "fooBar"},
+			},
+		},
+		{
+			name: "proto2 default conflict is a warning",
+			file: newJSONTestFile("proto2",
+				newJSONTestField("foo_bar", "", 1), newJSONTestField("fooBar", "", 2)),
+			expected: []JSONNameConflict{
+				{Message: ".j.M", Field: "fooBar", Previous: "foo_bar", JSONName: "fooBar", Warning: true},
+			},
+		},
+		{
+			name: "editions custom conflict",
+			file: newJSONTestFile("2023",
+				newJSONTestField("a", "x", 1), newJSONTestField("b", "x", 2)),
+			expected: []JSONNameConflict{
+				{Message: ".j.M", Field: "b", Previous: "a", JSONName: "x", FieldCustom: true, PreviousCustom: true},
+			},
+		},
+		{
+			name: "proto2 custom conflict is an error",
+			file: newJSONTestFile("proto2",
+				newJSONTestField("a", "x", 1), newJSONTestField("b", "x", 2)),
+			expected: []JSONNameConflict{
+				{Message: ".j.M", Field: "b", Previous: "a", JSONName: "x", FieldCustom: true, PreviousCustom: true},
+			},
+		},
+		{
+			name: "proto2 custom against default is a warning",
+			file: newJSONTestFile("proto2",
+				newJSONTestField("bar", "", 1), newJSONTestField("foo", "bar", 2)),
+			expected: []JSONNameConflict{
+				{Message: ".j.M", Field: "foo", Previous: "bar", JSONName: "bar", FieldCustom: true, Warning: true},
+			},
+		},
+		{
+			name: "default against custom",
+			file: newJSONTestFile("proto3",
+				newJSONTestField("x", "bar", 1), newJSONTestField("bar", "", 2)),
+			expected: []JSONNameConflict{
+				{Message: ".j.M", Field: "bar", Previous: "x", JSONName: "bar", PreviousCustom: true},
+			},
+		},
+		{
+			name: "custom name resolves conflict",
+			file: newJSONTestFile("proto3",
+				newJSONTestField("foo", "", 1), newJSONTestField("bar", "baz", 2)),
+		},
+	})
+}
+
+func TestCheckJSONNamesLegacy(t *testing.T) {
+	file := newJSONTestFile("proto3",
+		newJSONTestField("FooBar", "", 1), newJSONTestField("foo_bar", "x", 2))
+	file.MessageType[0].Options = &descriptorpb.MessageOptions{
+		DeprecatedLegacyJsonFieldConflicts: proto.Bool(true),
+	}
+
+	got, err := CheckJSONNames(file, ".j.M")
+	core.AssertMustNoError(t, err, "CheckJSONNames")
+	core.AssertSliceEqual(t, []JSONNameConflict{
+		{Message: ".j.M", Field: "foo_bar", Previous: "FooBar", JSONName: "fooBar"},
+	}, got, "legacy proto3")
+
+	file.Syntax = nil
+	got, err = CheckJSONNames(file, ".j.M")
+	core.AssertMustNoError(t, err, "CheckJSONNames")
+	core.AssertEqual(t, 0, len(got), "legacy proto2")
+}
+
+func TestCheckFileJSONNames(t *testing.T) {
+	file := newJSONTestFile("proto3", newJSONTestField("a", "", 1))
+	file.MessageType[0].NestedType = []*descriptorpb.DescriptorProto{{
+		Name:  proto.String("N"),
+		Field: []*descriptorpb.FieldDescriptorProto{newJSONTestField("b_c", "", 1), newJSONTestField("bC", "", 2)},
+	}}
+
+	got := CheckFileJSONNames(file)
+	core.AssertMustEqual(t, 1, len(got), "conflicts")
+	core.AssertEqual(t, ".j.M.N", got[0].Message, "message")
+	core.AssertEqual(t, `The default JSON name of field "bC" ("bC") conflicts with the default JSON name of field "b_c".`,
+		got[0].String(), "String")
+
+	mixed := JSONNameConflict{Field: "foo", Previous: "bar", JSONName: "bar", FieldCustom: true}
+	core.AssertEqual(t, `The custom JSON name of field "foo" ("bar") conflicts with the default JSON name of field "bar".`,
+		mixed.String(), "String mixed")
+
+	_, err := CheckJSONNames(file, "j.Missing")
+	core.AssertErrorIs(t, err, core.ErrNotExists, "unknown message")
+}
@@ -0,0 +1,390 @@
+package generator
+
+import (
+	"strconv"
+	"strings"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// RenderMarkdown renders an API reference of the files of a set in
+// Markdown. Only the named files are documented, or all of them if
+// none are named, while the rest of the set is used to resolve types.
+//
+// Each file lists its services with a table of methods and their
+// streaming kind, its messages with a table of fields, and its enums
+// with a table of values. Messages and enums nested in others are
+// listed after their parent, except map entries. Comments are taken
+// from SourceCodeInfo, deprecated elements are marked, and references
+// to documented types link to their sections.
+func RenderMarkdown(set *descriptorpb.FileDescriptorSet, files ...string) (string, error) {
+	if set == nil {
+		return "", core.Wrap(core.ErrInvalid, "nil file descriptor set")
+	}
+
+	m := &mdRenderer{
+		r:      NewRegistry(set.File...),
+		linked: make(map[string]bool),
+	}
+
+	docs, err := m.documented(set, files)
+	if err != nil {
+		return "", err
+	}
+
+	m.printIndex(docs)
+	for _, file := range docs {
+		m.printFile(file)
+	}
+	return m.buf.String(), nil
+}
+
+// mdRenderer holds the state used while rendering Markdown.
+type mdRenderer struct {
+	buf    strings.Builder
+	r      *Registry
+	si     sourceInfo
+	file   *descriptorpb.FileDescriptorProto
+	linked map[string]bool
+}
+
+// mdType is a message or enum found while walking a file.
+type mdType struct {
+	name string
+	path []int32
+	msg  *descriptorpb.DescriptorProto
+	enum *descriptorpb.EnumDescriptorProto
+}
+
+// documented returns the files to document and marks their types
+// as link targets.
+func (m *mdRenderer) documented(set *descriptorpb.FileDescriptorSet,
+	names []string) ([]*descriptorpb.FileDescriptorProto, error) {
+	docs := set.File
+	if len(names) > 0 {
+		docs = make([]*descriptorpb.FileDescriptorProto, 0, len(names))
+		for _, name := range names {
+			file, ok := m.r.File(name)
+			if !ok {
+				return nil, core.Wrapf(core.ErrNotExists, "file %q not in set", name)
+			}
+			docs = append(docs, file)
+		}
+	}
+
+	for _, file := range docs {
+		msgs, enums := mdTypes(file)
+		for _, t := range append(msgs, enums...) {
+			m.linked[t.name] = true
+		}
+		for _, svc := range file.Service {
+			m.linked[ServiceFullName(file, svc)] = true
+		}
+	}
+	return docs, nil
+}
+
+// mdTypes lists the messages, except map entries, and the enums of
+// a file, each followed by those nested in it.
+func mdTypes(file *descriptorpb.FileDescriptorProto) (msgs, enums []mdType) {
+	var walk func(scope string, path []int32, msg *descriptorpb.DescriptorProto)
+	walk = func(scope string, path []int32, msg *descriptorpb.DescriptorProto) {
+		name := joinName(scope, msg.GetName())
+		if msg.GetOptions().GetMapEntry() {
+			return
+		}
+		msgs = append(msgs, mdType{name: name, path: path, msg: msg})
+		for i, enum := range msg.EnumType {
+			enums = append(enums, mdType{
+				name: joinName(name, enum.GetName()),
+				path: appendPath(path, pathMessageEnum, int32(i)),
+				enum: enum,
+			})
+		}
+		for i, nested := range msg.NestedType {
+			walk(name, appendPath(path, pathMessageNested, int32(i)), nested)
+		}
+	}
+
+	pkg := file.GetPackage()
+	for i, msg := range file.MessageType {
+		walk(pkg, []int32{pathFileMessage, int32(i)}, msg)
+	}
+	top := make([]mdType, 0, len(file.EnumType)+len(enums))
+	for i, enum := range file.EnumType {
+		top = append(top, mdType{
+			name: joinName(pkg, enum.GetName()),
+			path: []int32{pathFileEnum, int32(i)},
+			enum: enum,
+		})
+	}
+	return msgs, append(top, enums...)
+}
+
+func (m *mdRenderer) line(s string) {
+	_, _ = m.buf.WriteString(s)
+	_ = m.buf.WriteByte('\n')
+}
+
+// heading writes a heading preceded by an anchor for the given id.
+func (m *mdRenderer) heading(level int, id, title string) {
+	m.line("")
+	m.line(`<a id="` + id + `"></a>`)
+	m.line("")
+	m.line(strings.Repeat("#", level) + " " + title)
+}
+
+// paragraph writes the deprecation marker and the comments of a
+// descriptor as a block of text.
+func (m *mdRenderer) paragraph(deprecated bool, path []int32) {
+	if deprecated {
+		m.line("")
+		m.line("**Deprecated.**")
+	}
+	if lines := commentLines(m.comment(path)); len(lines) > 0 {
+		m.line("")
+		for _, l := range lines {
+			m.line(strings.TrimPrefix(l, " "))
+		}
+	}
+}
+
+// comment returns the leading comment of a descriptor, or its trailing
+// comment if there is none.
+func (m *mdRenderer) comment(path []int32) string {
+	loc := m.si.Get(path)
+	if s := loc.GetLeadingComments(); s != "" {
+		return s
+	}
+	return loc.GetTrailingComments()
+}
+
+// description returns the comments of a descriptor as a table cell,
+// prefixed by the deprecation marker.
+func (m *mdRenderer) description(deprecated bool, path []int32) string {
+	var words []string
+	if deprecated {
+		words = append(words, "**Deprecated.**")
+	}
+	for _, l := range commentLines(m.comment(path)) {
+		if l = strings.TrimSpace(l); l != "" {
+			words = append(words, l)
+		}
+	}
+	return mdCell(strings.Join(words, " "))
+}
+
+// table writes a table with the given header and rows.
+func (m *mdRenderer) table(header []string, rows [][]string) {
+	m.line("")
+	m.line("| " + strings.Join(header, " | ") + " |")
+	m.line(strings.Repeat("| --- ", len(header)) + "|")
+	for _, row := range rows {
+		m.line("| " + strings.Join(row, " | ") + " |")
+	}
+}
+
+// mdCell escapes the pipes of a table cell.
+func mdCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// mdCode formats a name as inline code.
+func mdCode(s string) string {
+	return "`" + s + "`"
+}
+
+// typeLink returns a reference to a message or enum, linked to its
+// section if documented.
+func (m *mdRenderer) typeLink(typeName string) string {
+	name := registryKey(typeName)
+	if m.linked[name] {
+		return "[" + mdCode(name) + "](#" + name + ")"
+	}
+	return mdCode(name)
+}
+
+func (m *mdRenderer) printIndex(docs []*descriptorpb.FileDescriptorProto) {
+	m.line("# API Reference")
+	m.line("")
+	for _, file := range docs {
+		m.line("- [" + mdCode(file.GetName()) + "](#" + file.GetName() + ")")
+	}
+}
+
+func (m *mdRenderer) printFile(file *descriptorpb.FileDescriptorProto) {
+	m.file = file
+	m.si = newSourceInfo(file)
+
+	m.heading(2, file.GetName(), mdCode(file.GetName()))
+	if pkg := file.GetPackage(); pkg != "" {
+		m.line("")
+		m.line("Package " + mdCode(pkg) + ".")
+	}
+	m.paragraph(IsDeprecated(file), []int32{pathFilePackage})
+
+	msgs, enums := mdTypes(file)
+	if len(file.Service) > 0 {
+		m.line("")
+		m.line("### Services")
+		for i, svc := range file.Service {
+			m.printService([]int32{pathFileService, int32(i)}, svc)
+		}
+	}
+	if len(msgs) > 0 {
+		m.line("")
+		m.line("### Messages")
+		for _, t := range msgs {
+			m.printMessage(t)
+		}
+	}
+	if len(enums) > 0 {
+		m.line("")
+		m.line("### Enums")
+		for _, t := range enums {
+			m.printEnum(t)
+		}
+	}
+}
+
+func (m *mdRenderer) printService(path []int32, svc *descriptorpb.ServiceDescriptorProto) {
+	name := ServiceFullName(m.file, svc)
+	m.heading(4, name, mdCode(name))
+	m.paragraph(IsDeprecated(svc), path)
+	if len(svc.Method) == 0 {
+		return
+	}
+
+	rows := make([][]string, 0, len(svc.Method))
+	for i, method := range svc.Method {
+		rows = append(rows, []string{
+			mdCode(method.GetName()),
+			m.typeLink(method.GetInputType()),
+			m.typeLink(method.GetOutputType()),
+			methodStreaming(method),
+			m.description(IsDeprecated(method), appendPath(path, pathServiceMethod, int32(i))),
+		})
+	}
+	m.table([]string{"Method", "Request", "Response", "Streaming", "Description"}, rows)
+}
+
+// methodStreaming describes the streaming kind of a method.
+func methodStreaming(method *descriptorpb.MethodDescriptorProto) string {
+	switch {
+	case method.GetClientStreaming() && method.GetServerStreaming():
+		return "bidirectional"
+	case method.GetClientStreaming():
+		return "client"
+	case method.GetServerStreaming():
+		return "server"
+	default:
+		return "unary"
+	}
+}
+
+func (m *mdRenderer) printMessage(t mdType) {
+	m.heading(4, t.name, mdCode(t.name))
+	m.paragraph(IsDeprecated(t.msg), t.path)
+	if len(t.msg.Field) == 0 {
+		return
+	}
+
+	rows := make([][]string, 0, len(t.msg.Field))
+	for i, field := range t.msg.Field {
+		rows = append(rows, []string{
+			mdCode(field.GetName()),
+			strconv.Itoa(int(field.GetNumber())),
+			m.fieldType(t.name, field),
+			m.fieldLabel(t.name, t.msg, field),
+			mdCode(FieldJSONName(field)),
+			m.description(IsDeprecated(field), appendPath(t.path, pathMessageField, int32(i))),
+		})
+	}
+	m.table([]string{"Field", "Number", "Type", "Label", "JSON Name", "Description"}, rows)
+}
+
+// fieldType returns the type of a field, as map<K, V> for map fields.
+func (m *mdRenderer) fieldType(scope string, field *descriptorpb.FieldDescriptorProto) string {
+	switch field.GetType() {
+	case TypeMessage, TypeGroup:
+		if entry := m.mapEntry(scope, field); entry != nil && len(entry.Field) == 2 {
+			name := registryKey(resolveMessageName(m.r, scope, field.GetTypeName()))
+			return "map<" + m.fieldType(name, entry.Field[0]) + ", " + m.fieldType(name, entry.Field[1]) + ">"
+		}
+		return m.typeLink(resolveMessageName(m.r, scope, field.GetTypeName()))
+	case TypeEnum:
+		return m.typeLink(resolveEnumName(m.r, scope, field.GetTypeName()))
+	default:
+		return mdCode(scalarTypeName(field.GetType()))
+	}
+}
+
+// fieldLabel returns the label of a field as written in .proto files,
+// or the oneof it belongs to. Editions files have no labels, so their
+// singular fields are marked optional only when they have explicit
+// presence and the file's default doesn't.
+func (m *mdRenderer) fieldLabel(scope string, msg *descriptorpb.DescriptorProto,
+	field *descriptorpb.FieldDescriptorProto) string {
+	switch {
+	case m.mapEntry(scope, field) != nil:
+		return ""
+	case field.GetLabel() == LabelRepeated:
+		return "repeated"
+	case isRealOneofMember(field):
+		if oneof := msg.GetOneofDecl(); int(field.GetOneofIndex()) < len(oneof) {
+			return "oneof " + mdCode(oneof[field.GetOneofIndex()].GetName())
+		}
+		return "oneof"
+	}
+
+	switch FieldFeatures(m.file, field).GetFieldPresence() {
+	case descriptorpb.FeatureSet_LEGACY_REQUIRED:
+		return "required"
+	case descriptorpb.FeatureSet_EXPLICIT:
+		switch FileSyntax(m.file) {
+		case SyntaxProto2:
+			return "optional"
+		case SyntaxProto3:
+			if field.GetProto3Optional() {
+				return "optional"
+			}
+		default:
+			if FileFeatures(m.file).GetFieldPresence() != descriptorpb.FeatureSet_EXPLICIT {
+				return "optional"
+			}
+		}
+	}
+	return ""
+}
+
+// mapEntry returns the map-entry message a field refers to, if it is
+// a map field.
+func (m *mdRenderer) mapEntry(scope string, field *descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	if field.GetLabel() != LabelRepeated || field.GetType() != TypeMessage {
+		return nil
+	}
+	entry, ok := m.r.Message(resolveMessageName(m.r, scope, field.GetTypeName()))
+	if !ok || !entry.GetOptions().GetMapEntry() {
+		return nil
+	}
+	return entry
+}
+
+func (m *mdRenderer) printEnum(t mdType) {
+	m.heading(4, t.name, mdCode(t.name))
+	m.paragraph(IsDeprecated(t.enum), t.path)
+	if len(t.enum.Value) == 0 {
+		return
+	}
+
+	rows := make([][]string, 0, len(t.enum.Value))
+	for i, value := range t.enum.Value {
+		rows = append(rows, []string{
+			mdCode(value.GetName()),
+			strconv.Itoa(int(value.GetNumber())),
+			m.description(IsDeprecated(value), appendPath(t.path, pathEnumValue, int32(i))),
+		})
+	}
+	m.table([]string{"Name", "Number", "Description"}, rows)
+}
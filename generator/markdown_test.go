@@ -0,0 +1,190 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const markdownTypesProto = `syntax = "proto3";
+
+package types;
+
+import "google/protobuf/timestamp.proto";
+
+// Status of an item.
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  // Item is active.
+  STATUS_ACTIVE = 1;
+  STATUS_LEGACY = 2 [deprecated = true];
+}
+
+// Item is a stored thing.
+// It has a name.
+message Item {
+  string item_id = 1; // Unique | stable id.
+  optional string title = 2;
+  repeated string tags = 3;
+  map<string, Item> children = 4;
+  google.protobuf.Timestamp created = 5;
+  oneof owner {
+    string user = 6;
+    string group = 7;
+  }
+
+  message Meta {
+    Kind kind = 1 [json_name = "k", deprecated = true];
+  }
+
+  enum Kind {
+    KIND_UNSPECIFIED = 0;
+  }
+}
+`
+
+const markdownAPIProto = `syntax = "proto3";
+
+// The item store API.
+package api;
+
+import "types.proto";
+
+// Store keeps items.
+service Store {
+  // Get returns an item.
+  rpc Get(types.Item) returns (types.Item);
+  rpc Watch(types.Item) returns (stream types.Item);
+  rpc Upload(stream types.Item) returns (types.Item);
+  rpc Sync(stream types.Item) returns (stream types.Item) {
+    option deprecated = true;
+  }
+}
+`
+
+func newMarkdownTestSet(t *testing.T) *descriptorpb.FileDescriptorSet {
+	t.Helper()
+	p := &Parser{Files: map[string]string{
+		"types.proto": markdownTypesProto,
+		"api.proto":   markdownAPIProto,
+	}}
+	files, err := p.ParseWithImports("api.proto")
+	core.AssertMustNoError(t, err, "Parse")
+	return &descriptorpb.FileDescriptorSet{File: files}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	set := newMarkdownTestSet(t)
+
+	out, err := RenderMarkdown(set, "types.proto", "api.proto")
+	core.AssertMustNoError(t, err, "RenderMarkdown")
+
+	for _, want := range []string{
+		"# API Reference\n\n- [`types.proto`](#types.proto)\n- [`api.proto`](#api.proto)\n",
+		"<a id=\"api.proto\"></a>\n\n## `api.proto`\n\nPackage `api`.\n\nThe item store API.\n",
+		"#### `api.Store`\n\nStore keeps items.\n",
+		"| Method | Request | Response | Streaming | Description |\n| --- | --- | --- | --- | --- |\n",
+		"| `Get` | [`types.Item`](#types.Item) | [`types.Item`](#types.Item) | unary | Get returns an item. |\n",
+		"| `Watch` | [`types.Item`](#types.Item) | [`types.Item`](#types.Item) | server |  |\n",
+		"| `Upload` | [`types.Item`](#types.Item) | [`types.Item`](#types.Item) | client |  |\n",
+		"| `Sync` | [`types.Item`](#types.Item) | [`types.Item`](#types.Item) | bidirectional | **Deprecated.** |\n",
+		"#### `types.Item`\n\nItem is a stored thing.\nIt has a name.\n",
+		"| `item_id` | 1 | `string` |  | `itemId` | Unique \\| stable id. |\n",
+		"| `title` | 2 | `string` | optional | `title` |  |\n",
+		"| `tags` | 3 | `string` | repeated | `tags` |  |\n",
+		"| `children` | 4 | map<`string`, [`types.Item`](#types.Item)> |  | `children` |  |\n",
+		"| `created` | 5 | `google.protobuf.Timestamp` |  | `created` |  |\n",
+		"| `user` | 6 | `string` | oneof `owner` | `user` |  |\n",
+		"| `kind` | 1 | [`types.Item.Kind`](#types.Item.Kind) |  | `k` | **Deprecated.** |\n",
+		"| `STATUS_ACTIVE` | 1 | Item is active. |\n",
+		"| `STATUS_LEGACY` | 2 | **Deprecated.** |\n",
+	} {
+		core.AssertContains(t, out, want, "output")
+	}
+
+	core.AssertFalse(t, strings.Contains(out, "ChildrenEntry"), "map entry listed")
+	core.AssertFalse(t, strings.Contains(out, "## `google/protobuf/timestamp.proto`"), "dependency documented")
+
+	// nested types follow their parent, enums after messages
+	order := []string{"#### `types.Item`", "#### `types.Item.Meta`", "#### `types.Status`", "#### `types.Item.Kind`"}
+	last := -1
+	for _, heading := range order {
+		i := strings.Index(out, heading)
+		core.AssertTrue(t, i > last, heading)
+		last = i
+	}
+}
+
+func TestRenderMarkdownSubset(t *testing.T) {
+	set := newMarkdownTestSet(t)
+
+	out, err := RenderMarkdown(set, "api.proto")
+	core.AssertMustNoError(t, err, "RenderMarkdown")
+	core.AssertContains(t, out, "| `Get` | `types.Item` | `types.Item` | unary |", "unlinked types")
+	core.AssertFalse(t, strings.Contains(out, "#### `types.Item`"), "types documented")
+
+	all, err := RenderMarkdown(set)
+	core.AssertMustNoError(t, err, "RenderMarkdown all")
+	core.AssertContains(t, all, "## `google/protobuf/timestamp.proto`", "dependency documented")
+	core.AssertContains(t, all, "[`google.protobuf.Timestamp`](#google.protobuf.Timestamp)", "linked dependency")
+}
+
+func TestRenderMarkdownErrors(t *testing.T) {
+	_, err := RenderMarkdown(nil)
+	core.AssertErrorIs(t, err, core.ErrInvalid, "nil set")
+
+	_, err = RenderMarkdown(newMarkdownTestSet(t), "missing.proto")
+	core.AssertErrorIs(t, err, core.ErrNotExists, "unknown file")
+}
+
+func TestRenderMarkdownProto2Labels(t *testing.T) {
+	file, err := ParseProto("legacy.proto", `syntax = "proto2";
+package legacy;
+message Old {
+  required int32 id = 1;
+  optional bytes data = 2;
+  optional group Extra = 3 {
+    optional int32 n = 1;
+  }
+}
+`)
+	core.AssertMustNoError(t, err, "ParseProto")
+
+	out, err := RenderMarkdown(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{file}})
+	core.AssertMustNoError(t, err, "RenderMarkdown")
+	core.AssertContains(t, out, "| `id` | 1 | `int32` | required | `id` |  |\n", "required")
+	core.AssertContains(t, out, "| `data` | 2 | `bytes` | optional | `data` |  |\n", "optional")
+	core.AssertContains(t, out, "| `extra` | 3 | [`legacy.Old.Extra`](#legacy.Old.Extra) | optional |", "group")
+}
+
+func TestRenderMarkdownEditions(t *testing.T) {
+	file, err := ParseProto("ed.proto", `edition = "2023";
+package ed;
+option features.field_presence = IMPLICIT;
+message M {
+  enum Kind {
+    KIND_UNSPECIFIED = 0;
+  }
+  Kind kind = 1;
+  int32 tracked = 2 [features.field_presence = EXPLICIT];
+  int32 id = 3 [features.field_presence = LEGACY_REQUIRED];
+}
+`)
+	core.AssertMustNoError(t, err, "ParseProto")
+	// descriptors may carry type names relative to the message
+	file.MessageType[0].Field[0].TypeName = proto.String("Kind")
+
+	out, err := RenderMarkdown(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{file}})
+	core.AssertMustNoError(t, err, "RenderMarkdown")
+	core.AssertContains(t, out, "| `kind` | 1 | [`ed.M.Kind`](#ed.M.Kind) |  |", "relative enum")
+	core.AssertContains(t, out, "| `tracked` | 2 | `int32` | optional |", "explicit")
+	core.AssertContains(t, out, "| `id` | 3 | `int32` | required |", "required")
+
+	file.Options = nil
+	out, err = RenderMarkdown(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{file}})
+	core.AssertMustNoError(t, err, "RenderMarkdown")
+	core.AssertContains(t, out, "| `tracked` | 2 | `int32` |  |", "explicit by default")
+}
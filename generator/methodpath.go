@@ -0,0 +1,102 @@
+package generator
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// MethodRoute identifies a method for routing calls.
+type MethodRoute struct {
+	// File declares the service.
+	File *descriptorpb.FileDescriptorProto
+	// Service declares the method.
+	Service *descriptorpb.ServiceDescriptorProto
+	// Method is the routed method.
+	Method *descriptorpb.MethodDescriptorProto
+	// Path is the gRPC-style full method path.
+	Path string
+	// Hash is the MethodHash of Path.
+	Hash uint32
+}
+
+// ServiceFullName returns the fully-qualified name of a service,
+// without leading dot.
+func ServiceFullName(file *descriptorpb.FileDescriptorProto, svc *descriptorpb.ServiceDescriptorProto) string {
+	return joinName(file.GetPackage(), svc.GetName())
+}
+
+// MethodPath returns the gRPC-style full method path of a method, as
+// in /pkg.Service/Method.
+func MethodPath(file *descriptorpb.FileDescriptorProto, svc *descriptorpb.ServiceDescriptorProto,
+	method *descriptorpb.MethodDescriptorProto) string {
+	return "/" + ServiceFullName(file, svc) + "/" + method.GetName()
+}
+
+// MethodHash returns the stable 32-bit identifier of a method path,
+// its FNV-1a hash.
+func MethodHash(path string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(path))
+	return h.Sum32()
+}
+
+// MethodRoutes returns the routes of every method of the services of
+// the given files, in declaration order.
+func MethodRoutes(files ...*descriptorpb.FileDescriptorProto) []MethodRoute {
+	var out []MethodRoute
+	for _, file := range files {
+		for _, svc := range file.GetService() {
+			for _, method := range svc.Method {
+				path := MethodPath(file, svc, method)
+				out = append(out, MethodRoute{
+					File:    file,
+					Service: svc,
+					Method:  method,
+					Path:    path,
+					Hash:    MethodHash(path),
+				})
+			}
+		}
+	}
+	return out
+}
+
+// CheckMethodRoutes fails with ErrExists if two routes share a path or
+// a hash, listing every collision.
+func CheckMethodRoutes(routes []MethodRoute) error {
+	byHash := make(map[uint32][]string)
+	var collisions []uint32
+	for _, route := range routes {
+		paths := byHash[route.Hash]
+		if len(paths) == 1 {
+			collisions = append(collisions, route.Hash)
+		}
+		byHash[route.Hash] = append(paths, route.Path)
+	}
+
+	if len(collisions) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, len(collisions))
+	for i, hash := range collisions {
+		msgs[i] = fmt.Sprintf("%08x: %s", hash, strings.Join(byHash[hash], ", "))
+	}
+	return core.Wrapf(core.ErrExists, "method hash collision: %s", strings.Join(msgs, "; "))
+}
+
+// RequestMethodRoutes returns the routes of all the services known to
+// a request, those of the files to generate and their dependencies,
+// failing generation if any of them collide.
+func RequestMethodRoutes(req *pluginpb.CodeGeneratorRequest) ([]MethodRoute, error) {
+	routes := MethodRoutes(req.GetProtoFile()...)
+	if err := CheckMethodRoutes(routes); err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
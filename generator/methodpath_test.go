@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type methodHashTestCase struct {
+	path string
+	want uint32
+}
+
+var _ core.TestCase = methodHashTestCase{}
+
+func (tc methodHashTestCase) Name() string {
+	return tc.path
+}
+
+func (tc methodHashTestCase) Test(t *testing.T) {
+	t.Helper()
+	core.AssertEqual(t, tc.want, MethodHash(tc.path), "MethodHash")
+}
+
+func methodHashTestCases() []methodHashTestCase {
+	return []methodHashTestCase{
+		// FNV-1a offset basis
+		{"", 0x811c9dc5},
+		{"/pkg.Service/Method", 0x96df188b},
+		{"/a.S/M33516", 0xad5d6c0c},
+		{"/a.S/M948720", 0xad5d6c0c},
+	}
+}
+
+func TestMethodHash(t *testing.T) {
+	core.RunTestCases(t, methodHashTestCases())
+}
+
+func TestMethodPath(t *testing.T) {
+	fd := File("svc.proto").Package("pkg.v1").
+		Message("Req").File().
+		Service("Store").Method("Get", "Req", "Req").Build()
+	svc, method := fd.Service[0], fd.Service[0].Method[0]
+	core.AssertEqual(t, "pkg.v1.Store", ServiceFullName(fd, svc), "ServiceFullName")
+	core.AssertEqual(t, "/pkg.v1.Store/Get", MethodPath(fd, svc, method), "MethodPath")
+
+	noPkg := NewFile("nopkg.proto", "")
+	core.AssertEqual(t, "/Store/Get", MethodPath(noPkg, svc, method), "no package")
+}
+
+func newMethodRouteTestFile(name, pkg, svc string, methods ...string) *descriptorpb.FileDescriptorProto {
+	sb := File(name).Package(pkg).Service(svc)
+	for _, m := range methods {
+		sb.Method(m, ".google.protobuf.Empty", ".google.protobuf.Empty")
+	}
+	return sb.Build()
+}
+
+func TestMethodRoutes(t *testing.T) {
+	a := newMethodRouteTestFile("a.proto", "a", "S", "Get", "Put")
+	b := newMethodRouteTestFile("b.proto", "b", "T", "List")
+
+	routes := MethodRoutes(a, b)
+	core.AssertMustEqual(t, 3, len(routes), "routes")
+	var paths []string
+	for _, r := range routes {
+		paths = append(paths, r.Path)
+		core.AssertEqual(t, MethodHash(r.Path), r.Hash, "hash of "+r.Path)
+	}
+	core.AssertSliceEqual(t, []string{"/a.S/Get", "/a.S/Put", "/b.T/List"}, paths, "paths")
+	core.AssertTrue(t, routes[2].File == b && routes[2].Method == b.Service[0].Method[0], "descriptors")
+	core.AssertNoError(t, CheckMethodRoutes(routes), "no collisions")
+}
+
+func TestRequestMethodRoutes(t *testing.T) {
+	a := newMethodRouteTestFile("a.proto", "a", "S", "M33516", "Get")
+	req, err := NewCodeGeneratorRequest([]*descriptorpb.FileDescriptorProto{a})
+	core.AssertMustNoError(t, err, "NewCodeGeneratorRequest")
+	routes, err := RequestMethodRoutes(req)
+	core.AssertMustNoError(t, err, "RequestMethodRoutes")
+	core.AssertEqual(t, 2, len(routes), "routes")
+
+	// the colliding method is declared in a dependency
+	b := newMethodRouteTestFile("b.proto", "b", "T", "Other")
+	b.Dependency = []string{"a.proto"}
+	a.Service[0].Method[1].Name = proto.String("M948720")
+	req, err = NewCodeGeneratorRequest([]*descriptorpb.FileDescriptorProto{b}, WithDependencies(a))
+	core.AssertMustNoError(t, err, "NewCodeGeneratorRequest")
+	_, err = RequestMethodRoutes(req)
+	core.AssertErrorIs(t, err, core.ErrExists, "collision")
+	core.AssertContains(t, err.Error(), "ad5d6c0c: /a.S/M33516, /a.S/M948720", "collision detail")
+}
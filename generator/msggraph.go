@@ -0,0 +1,376 @@
+package generator
+
+import (
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// EdgeKind classifies how a field refers to another message.
+type EdgeKind int
+
+const (
+	// EdgeSingular is a singular field with presence, which can be unset.
+	EdgeSingular EdgeKind = iota
+	// EdgeRequired is a required field, which must always be set.
+	EdgeRequired
+	// EdgeRepeated is a repeated field, which can be empty.
+	EdgeRepeated
+	// EdgeMap is a map field, which can be empty.
+	EdgeMap
+)
+
+// BreaksCycle tells if a field of this kind can be left empty, so a
+// cycle going through it still allows finite values.
+func (k EdgeKind) BreaksCycle() bool {
+	return k != EdgeRequired
+}
+
+// MessageEdge is a field referring to a message type.
+type MessageEdge struct {
+	// Field is the field declaring the reference.
+	Field *descriptorpb.FieldDescriptorProto
+	// From is the full name of the message declaring the field.
+	From string
+	// To is the full name of the referenced message. For map fields
+	// it's the type of the map values.
+	To string
+	// Kind tells how the field refers to the message.
+	Kind EdgeKind
+	// Cyclic is set when both messages belong to the same cycle.
+	Cyclic bool
+	// Closing is set on the edges that close a cycle when walking the
+	// messages in declaration order. Removing them makes the graph acyclic.
+	Closing bool
+}
+
+// MessageCycle is a strongly connected component of the message graph,
+// a set of messages that refer to each other directly or indirectly.
+type MessageCycle struct {
+	// Messages are the full names of the messages in the cycle.
+	Messages []string
+	// Edges are the fields within the cycle.
+	Edges []MessageEdge
+	// Breakable is set when every loop in the cycle goes through a
+	// field that can be left empty.
+	Breakable bool
+}
+
+// MessageGraph is the graph of message types connected by their
+// message-typed fields. Full names use the leading dot of type_name.
+type MessageGraph struct {
+	registry  *Registry
+	edges     map[string][]MessageEdge
+	component map[string]int
+	cycles    []MessageCycle
+	names     []string
+}
+
+// NewMessageGraph analyses the messages of the registry, using the
+// resolved type names of their fields. Map entries aren't nodes of the
+// graph, map fields refer to the type of their values instead.
+func NewMessageGraph(r *Registry) *MessageGraph {
+	g := &MessageGraph{
+		registry:  r,
+		edges:     make(map[string][]MessageEdge),
+		component: make(map[string]int),
+	}
+
+	for _, name := range r.MessageNames() {
+		msg, _ := r.Message(name)
+		if msg.GetOptions().GetMapEntry() {
+			continue
+		}
+		g.names = append(g.names, name)
+		g.edges[name] = g.messageEdges(name, msg)
+	}
+
+	g.findComponents()
+	return g
+}
+
+func (g *MessageGraph) messageEdges(name string, msg *descriptorpb.DescriptorProto) []MessageEdge {
+	var out []MessageEdge
+	for _, field := range msg.Field {
+		if edge, ok := g.fieldEdge(name, field); ok {
+			out = append(out, edge)
+		}
+	}
+	return out
+}
+
+func (g *MessageGraph) fieldEdge(from string, field *descriptorpb.FieldDescriptorProto) (MessageEdge, bool) {
+	if field.GetType() != TypeMessage && field.GetType() != TypeGroup {
+		return MessageEdge{}, false
+	}
+	target, ok := g.registry.Message(field.GetTypeName())
+	if !ok {
+		return MessageEdge{}, false
+	}
+
+	edge := MessageEdge{
+		Field: field,
+		From:  from,
+		To:    "." + registryKey(field.GetTypeName()),
+		Kind:  g.edgeKind(from, field),
+	}
+
+	if target.GetOptions().GetMapEntry() {
+		value := mapEntryValue(target)
+		if value == nil || value.GetType() != TypeMessage {
+			return MessageEdge{}, false
+		}
+		edge.To = "." + registryKey(value.GetTypeName())
+		edge.Kind = EdgeMap
+	}
+	return edge, true
+}
+
+func mapEntryValue(entry *descriptorpb.DescriptorProto) *descriptorpb.FieldDescriptorProto {
+	for _, field := range entry.Field {
+		if field.GetNumber() == 2 {
+			return field
+		}
+	}
+	return nil
+}
+
+func (g *MessageGraph) edgeKind(from string, field *descriptorpb.FieldDescriptorProto) EdgeKind {
+	switch {
+	case field.GetLabel() == LabelRepeated:
+		return EdgeRepeated
+	case field.GetLabel() == LabelRequired, g.isLegacyRequired(from, field):
+		return EdgeRequired
+	default:
+		return EdgeSingular
+	}
+}
+
+// isLegacyRequired tells if an editions field is required, either
+// directly or by the file default.
+func (g *MessageGraph) isLegacyRequired(from string, field *descriptorpb.FieldDescriptorProto) bool {
+	const legacyRequired = descriptorpb.FeatureSet_LEGACY_REQUIRED
+
+	if features := field.GetOptions().GetFeatures(); features != nil && features.FieldPresence != nil {
+		return features.GetFieldPresence() == legacyRequired
+	}
+	if name, ok := g.registry.FileOf(from); ok {
+		file, _ := g.registry.File(name)
+		return file.GetOptions().GetFeatures().GetFieldPresence() == legacyRequired
+	}
+	return false
+}
+
+// Edges returns the message-typed fields of a message.
+func (g *MessageGraph) Edges(name string) []MessageEdge {
+	edges := g.edges["."+registryKey(name)]
+	out := make([]MessageEdge, len(edges))
+	copy(out, edges)
+	return out
+}
+
+// Cycles returns the cycles of the graph, in declaration order of
+// their first message.
+func (g *MessageGraph) Cycles() []MessageCycle {
+	out := make([]MessageCycle, len(g.cycles))
+	copy(out, g.cycles)
+	return out
+}
+
+// CycleOf returns the cycle a message belongs to.
+func (g *MessageGraph) CycleOf(name string) (MessageCycle, bool) {
+	i, ok := g.component["."+registryKey(name)]
+	if !ok {
+		return MessageCycle{}, false
+	}
+	return g.cycles[i], true
+}
+
+// IsRecursive tells if a message can contain itself, directly or
+// indirectly.
+func (g *MessageGraph) IsRecursive(name string) bool {
+	_, ok := g.component["."+registryKey(name)]
+	return ok
+}
+
+// IsCyclicField tells if a field connects two messages of the same cycle.
+func (g *MessageGraph) IsCyclicField(field *descriptorpb.FieldDescriptorProto) bool {
+	edge, ok := g.edgeOf(field)
+	return ok && edge.Cyclic
+}
+
+// IsClosingField tells if a field closes a cycle, and so is where
+// emitters should use references instead of inlining the type.
+func (g *MessageGraph) IsClosingField(field *descriptorpb.FieldDescriptorProto) bool {
+	edge, ok := g.edgeOf(field)
+	return ok && edge.Closing
+}
+
+func (g *MessageGraph) edgeOf(field *descriptorpb.FieldDescriptorProto) (MessageEdge, bool) {
+	for _, name := range g.names {
+		for _, edge := range g.edges[name] {
+			if edge.Field == field {
+				return edge, true
+			}
+		}
+	}
+	return MessageEdge{}, false
+}
+
+//
+// Strongly connected components
+//
+
+// sccState holds the state of Tarjan's algorithm.
+type sccState struct {
+	g       *MessageGraph
+	index   map[string]int
+	low     map[string]int
+	onStack map[string]bool
+	active  map[string]bool
+	group   map[string]int
+	stack   []string
+	next    int
+	groups  int
+}
+
+func (g *MessageGraph) findComponents() {
+	s := &sccState{
+		g:       g,
+		index:   make(map[string]int),
+		low:     make(map[string]int),
+		onStack: make(map[string]bool),
+		active:  make(map[string]bool),
+		group:   make(map[string]int),
+	}
+	for _, name := range g.names {
+		if _, seen := s.index[name]; !seen {
+			s.visit(name)
+		}
+	}
+
+	g.collectCycles(s.group)
+}
+
+func (s *sccState) visit(v string) {
+	s.index[v], s.low[v] = s.next, s.next
+	s.next++
+	s.stack = append(s.stack, v)
+	s.onStack[v], s.active[v] = true, true
+
+	edges := s.g.edges[v]
+	for i := range edges {
+		w := edges[i].To
+		if _, node := s.g.edges[w]; !node {
+			continue
+		}
+		if s.active[w] {
+			edges[i].Closing = true
+		}
+
+		if _, seen := s.index[w]; !seen {
+			s.visit(w)
+			s.low[v] = min(s.low[v], s.low[w])
+		} else if s.onStack[w] {
+			s.low[v] = min(s.low[v], s.index[w])
+		}
+	}
+	s.active[v] = false
+
+	if s.low[v] == s.index[v] {
+		s.popComponent(v)
+	}
+}
+
+func (s *sccState) popComponent(root string) {
+	for {
+		w := s.stack[len(s.stack)-1]
+		s.stack = s.stack[:len(s.stack)-1]
+		s.onStack[w] = false
+		s.group[w] = s.groups
+		if w == root {
+			break
+		}
+	}
+	s.groups++
+}
+
+// collectCycles turns the components with loops into cycles,
+// ordered by the declaration of their messages.
+func (g *MessageGraph) collectCycles(group map[string]int) {
+	members := make(map[int][]string)
+	var order []int
+	for _, name := range g.names {
+		id := group[name]
+		if _, ok := members[id]; !ok {
+			order = append(order, id)
+		}
+		members[id] = append(members[id], name)
+	}
+
+	for _, id := range order {
+		names := members[id]
+		edges := g.markCyclic(names, group)
+		if len(edges) == 0 {
+			continue
+		}
+
+		for _, name := range names {
+			g.component[name] = len(g.cycles)
+		}
+		g.cycles = append(g.cycles, MessageCycle{
+			Messages:  names,
+			Edges:     edges,
+			Breakable: !hasRequiredLoop(names, edges),
+		})
+	}
+}
+
+// markCyclic flags and returns the edges within a component.
+func (g *MessageGraph) markCyclic(names []string, group map[string]int) []MessageEdge {
+	var out []MessageEdge
+	for _, name := range names {
+		edges := g.edges[name]
+		for i := range edges {
+			if to, ok := group[edges[i].To]; ok && to == group[name] {
+				if _, node := g.edges[edges[i].To]; node {
+					edges[i].Cyclic = true
+					out = append(out, edges[i])
+				}
+			}
+		}
+	}
+	return out
+}
+
+// hasRequiredLoop tells if the required edges alone form a loop.
+func hasRequiredLoop(names []string, edges []MessageEdge) bool {
+	next := make(map[string][]string)
+	for _, e := range edges {
+		if !e.Kind.BreaksCycle() {
+			next[e.From] = append(next[e.From], e.To)
+		}
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var loop func(string) bool
+	loop = func(v string) bool {
+		state[v] = visiting
+		for _, w := range next[v] {
+			if state[w] == visiting || (state[w] == 0 && loop(w)) {
+				return true
+			}
+		}
+		state[v] = visited
+		return false
+	}
+
+	for _, name := range names {
+		if state[name] == 0 && loop(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+)
+
+func newTestMessageGraph(t *testing.T, src string) (*MessageGraph, *Registry) {
+	t.Helper()
+	file, err := ParseProto("graph.proto", src)
+	core.AssertMustNoError(t, err, "ParseProto")
+
+	r := NewRegistry(file)
+	return NewMessageGraph(r), r
+}
+
+func TestMessageGraphSelfReference(t *testing.T) {
+	g, r := newTestMessageGraph(t, `syntax = "proto3";
+package g;
+message Node {
+  string name = 1;
+  Node parent = 2;
+  repeated Node children = 3;
+}
+message Leaf { Node node = 1; }
+`)
+
+	core.AssertTrue(t, g.IsRecursive(".g.Node"), "Node")
+	core.AssertFalse(t, g.IsRecursive("g.Leaf"), "Leaf")
+
+	cycles := g.Cycles()
+	core.AssertMustEqual(t, 1, len(cycles), "cycles")
+	core.AssertSliceEqual(t, []string{".g.Node"}, cycles[0].Messages, "messages")
+	core.AssertEqual(t, 2, len(cycles[0].Edges), "edges")
+	core.AssertTrue(t, cycles[0].Breakable, "breakable")
+
+	node, _ := r.Message(".g.Node")
+	core.AssertFalse(t, g.IsCyclicField(node.Field[0]), "scalar field")
+	core.AssertTrue(t, g.IsCyclicField(node.Field[1]), "parent")
+	core.AssertTrue(t, g.IsClosingField(node.Field[1]), "parent closes")
+	core.AssertTrue(t, g.IsClosingField(node.Field[2]), "children closes")
+
+	edges := g.Edges(".g.Node")
+	core.AssertEqual(t, EdgeSingular, edges[0].Kind, "parent kind")
+	core.AssertEqual(t, EdgeRepeated, edges[1].Kind, "children kind")
+
+	leaf, _ := r.Message(".g.Leaf")
+	core.AssertFalse(t, g.IsCyclicField(leaf.Field[0]), "leaf field")
+}
+
+func TestMessageGraphMutualRecursion(t *testing.T) {
+	g, r := newTestMessageGraph(t, `syntax = "proto3";
+package g;
+message A { B b = 1; }
+message B { map<string, C> cs = 1; }
+message C { A a = 1; }
+message D { A a = 1; }
+`)
+
+	cycle, ok := g.CycleOf(".g.B")
+	core.AssertMustTrue(t, ok, "CycleOf")
+	core.AssertSliceEqual(t, []string{".g.A", ".g.B", ".g.C"}, cycle.Messages, "messages")
+	core.AssertEqual(t, 3, len(cycle.Edges), "edges")
+
+	b := g.Edges(".g.B")
+	core.AssertMustEqual(t, 1, len(b), "map edge")
+	core.AssertEqual(t, EdgeMap, b[0].Kind, "map kind")
+	core.AssertEqual(t, ".g.C", b[0].To, "map value type")
+
+	// walking from A, only C.a closes the cycle
+	a, _ := r.Message(".g.A")
+	c, _ := r.Message(".g.C")
+	core.AssertFalse(t, g.IsClosingField(a.Field[0]), "A.b")
+	core.AssertTrue(t, g.IsClosingField(c.Field[0]), "C.a")
+	core.AssertFalse(t, g.IsRecursive(".g.D"), "D")
+	core.AssertFalse(t, g.IsRecursive(".g.B.CsEntry"), "map entry")
+}
+
+func TestMessageGraphRequiredCycle(t *testing.T) {
+	g, _ := newTestMessageGraph(t, `syntax = "proto2";
+package g;
+message A { required B b = 1; }
+message B { required A a = 1; }
+message C { required D d = 1; }
+message D { optional C c = 1; }
+`)
+
+	cycles := g.Cycles()
+	core.AssertMustEqual(t, 2, len(cycles), "cycles")
+	core.AssertFalse(t, cycles[0].Breakable, "required loop")
+	core.AssertTrue(t, cycles[1].Breakable, "optional link")
+	core.AssertEqual(t, EdgeRequired, cycles[0].Edges[0].Kind, "kind")
+}
+
+func TestMessageGraphLegacyRequired(t *testing.T) {
+	g, _ := newTestMessageGraph(t, `edition = "2023";
+package g;
+message A { A self = 1 [features.field_presence = LEGACY_REQUIRED]; }
+`)
+
+	cycle, ok := g.CycleOf("g.A")
+	core.AssertMustTrue(t, ok, "CycleOf")
+	core.AssertFalse(t, cycle.Breakable, "breakable")
+}
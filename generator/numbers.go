@@ -0,0 +1,217 @@
+package generator
+
+import (
+	"sort"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Field number limits.
+const (
+	// MinFieldNumber is the smallest valid field number.
+	MinFieldNumber = int32(protowire.MinValidNumber)
+	// MaxFieldNumber is the largest valid field number.
+	MaxFieldNumber = int32(protowire.MaxValidNumber)
+	// FirstImplementationNumber starts the range reserved for the
+	// protobuf implementation.
+	FirstImplementationNumber = int32(protowire.FirstReservedNumber)
+	// LastImplementationNumber ends the range reserved for the protobuf
+	// implementation.
+	LastImplementationNumber = int32(protowire.LastReservedNumber)
+)
+
+// NumberUse tells why a field number can't be used in a message.
+type NumberUse int
+
+const (
+	// NumberFree is a number available for a new field.
+	NumberFree NumberUse = iota
+	// NumberInvalid is outside the valid range of field numbers.
+	NumberInvalid
+	// NumberImplementation is in the 19000 to 19999 range.
+	NumberImplementation
+	// NumberField is already used by a field.
+	NumberField
+	// NumberReserved is in a reserved range of the message.
+	NumberReserved
+	// NumberExtensionRange is in an extension range of the message.
+	NumberExtensionRange
+)
+
+var numberUseNames = []string{
+	NumberFree:           "free",
+	NumberInvalid:        "invalid",
+	NumberImplementation: "reserved for the implementation",
+	NumberField:          "used by a field",
+	NumberReserved:       "reserved",
+	NumberExtensionRange: "in an extension range",
+}
+
+func (u NumberUse) String() string {
+	if u >= 0 && int(u) < len(numberUseNames) {
+		return numberUseNames[u]
+	}
+	return "unknown"
+}
+
+// CheckFieldNumber tells whether a number can be given to a new field
+// of the message, or why not.
+func CheckFieldNumber(msg *descriptorpb.DescriptorProto, n int32) NumberUse {
+	switch {
+	case n < MinFieldNumber || n > MaxFieldNumber:
+		return NumberInvalid
+	case n >= FirstImplementationNumber && n <= LastImplementationNumber:
+		return NumberImplementation
+	}
+
+	for _, field := range msg.GetField() {
+		if field.GetNumber() == n {
+			return NumberField
+		}
+	}
+	if IsReservedFieldNumber(msg, n) {
+		return NumberReserved
+	}
+	if InExtensionRange(msg, n) {
+		return NumberExtensionRange
+	}
+	return NumberFree
+}
+
+// IsReservedFieldNumber tells if a number is in a reserved range of
+// the message.
+func IsReservedFieldNumber(msg *descriptorpb.DescriptorProto, n int32) bool {
+	for _, r := range messageReservedRanges(msg.GetReservedRange()) {
+		if n >= r.start && n <= r.end {
+			return true
+		}
+	}
+	return false
+}
+
+// IsReservedFieldName tells if a name is reserved by the message.
+func IsReservedFieldName(msg *descriptorpb.DescriptorProto, name string) bool {
+	return core.SliceContains(msg.GetReservedName(), name)
+}
+
+// IsFieldNameFree tells if a new field of the message can have the
+// given name, neither used by a field nor reserved.
+func IsFieldNameFree(msg *descriptorpb.DescriptorProto, name string) bool {
+	for _, field := range msg.GetField() {
+		if field.GetName() == name {
+			return false
+		}
+	}
+	return !IsReservedFieldName(msg, name)
+}
+
+// HighestFieldNumber returns the largest number used by a field or
+// reserved by the message, zero if there are none.
+func HighestFieldNumber(msg *descriptorpb.DescriptorProto) int32 {
+	var highest int32
+	for _, field := range msg.GetField() {
+		highest = max(highest, field.GetNumber())
+	}
+	for _, r := range messageReservedRanges(msg.GetReservedRange()) {
+		highest = max(highest, r.end)
+	}
+	return min(highest, MaxFieldNumber)
+}
+
+// FieldNumberAllocator hands out field numbers not used by fields,
+// reserved ranges, extension ranges or the implementation range of a
+// message, nor previously allocated.
+type FieldNumberAllocator struct {
+	used []numberRange
+	// Min is the smallest number allocated by Next, MinFieldNumber by
+	// default. Setting it above HighestFieldNumber avoids reusing the
+	// numbers of deleted fields that weren't reserved.
+	Min int32
+}
+
+// NewFieldNumberAllocator creates an allocator for new fields of the
+// message.
+func NewFieldNumberAllocator(msg *descriptorpb.DescriptorProto) *FieldNumberAllocator {
+	a := &FieldNumberAllocator{Min: MinFieldNumber}
+	a.add(FirstImplementationNumber, LastImplementationNumber)
+	for _, field := range msg.GetField() {
+		a.add(field.GetNumber(), field.GetNumber())
+	}
+	for _, r := range messageReservedRanges(msg.GetReservedRange()) {
+		a.add(r.start, r.end)
+	}
+	for _, r := range msg.GetExtensionRange() {
+		// end is exclusive
+		a.add(r.GetStart(), r.GetEnd()-1)
+	}
+	return a
+}
+
+// add marks a range as used, keeping the ranges sorted and merged.
+func (a *FieldNumberAllocator) add(start, end int32) {
+	if start > end {
+		return
+	}
+	a.used = append(a.used, numberRange{start: start, end: end})
+	sort.Slice(a.used, func(i, j int) bool {
+		return a.used[i].start < a.used[j].start
+	})
+
+	merged := a.used[:1]
+	for _, r := range a.used[1:] {
+		last := &merged[len(merged)-1]
+		if int64(r.start) <= int64(last.end)+1 {
+			last.end = max(last.end, r.end)
+		} else {
+			merged = append(merged, r)
+		}
+	}
+	a.used = merged
+}
+
+// IsFree tells if the number is valid and neither used nor allocated.
+func (a *FieldNumberAllocator) IsFree(n int32) bool {
+	if n < MinFieldNumber || n > MaxFieldNumber {
+		return false
+	}
+	for _, r := range a.used {
+		if n >= r.start && n <= r.end {
+			return false
+		}
+	}
+	return true
+}
+
+// Next allocates the smallest free number not below Min.
+func (a *FieldNumberAllocator) Next() (int32, error) {
+	n := max(a.Min, MinFieldNumber)
+	for _, r := range a.used {
+		if n < r.start {
+			break
+		}
+		n = max(n, r.end+1)
+	}
+
+	if n > MaxFieldNumber || n < MinFieldNumber {
+		return 0, core.Wrap(core.ErrInvalid, "no free field numbers")
+	}
+	a.add(n, n)
+	return n, nil
+}
+
+// Allocate claims a specific number, failing if it isn't free.
+func (a *FieldNumberAllocator) Allocate(n int32) error {
+	if !a.IsFree(n) {
+		return core.Wrapf(core.ErrExists, "field number %d not available", n)
+	}
+	a.add(n, n)
+	return nil
+}
+
+// NextFieldNumber returns the smallest number available for a new
+// field of the message.
+func NextFieldNumber(msg *descriptorpb.DescriptorProto) (int32, error) {
+	return NewFieldNumberAllocator(msg).Next()
+}
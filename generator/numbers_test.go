@@ -0,0 +1,127 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// newNumbersTestMessage has fields 1, 2 and 4, reserves 5 to 9 and
+// "old", and extensions from 100 to 199.
+func newNumbersTestMessage() *descriptorpb.DescriptorProto {
+	msg := NewMessageWithReserved("Msg",
+		[]*descriptorpb.DescriptorProto_ReservedRange{NewReservedRange(5, 10)},
+		[]string{"old"},
+		NewField("a", 1, TypeString),
+		NewField("b", 2, TypeString),
+		NewField("d", 4, TypeString))
+	msg.ExtensionRange = []*descriptorpb.DescriptorProto_ExtensionRange{NewExtensionRange(100, 200)}
+	return msg
+}
+
+type checkFieldNumberTestCase struct {
+	name   string
+	number int32
+	want   NumberUse
+}
+
+var _ core.TestCase = checkFieldNumberTestCase{}
+
+func (tc checkFieldNumberTestCase) Name() string {
+	return tc.name
+}
+
+func (tc checkFieldNumberTestCase) Test(t *testing.T) {
+	t.Helper()
+	got := CheckFieldNumber(newNumbersTestMessage(), tc.number)
+	core.AssertEqual(t, tc.want, got, "CheckFieldNumber")
+}
+
+func checkFieldNumberTestCases() []checkFieldNumberTestCase {
+	return []checkFieldNumberTestCase{
+		{"free", 3, NumberFree},
+		{"field", 2, NumberField},
+		{"reserved start", 5, NumberReserved},
+		{"reserved end", 9, NumberReserved},
+		{"after reserved", 10, NumberFree},
+		{"extension range", 150, NumberExtensionRange},
+		{"implementation", 19500, NumberImplementation},
+		{"zero", 0, NumberInvalid},
+		{"too large", MaxFieldNumber + 1, NumberInvalid},
+		{"largest", MaxFieldNumber, NumberFree},
+	}
+}
+
+func TestCheckFieldNumber(t *testing.T) {
+	core.RunTestCases(t, checkFieldNumberTestCases())
+}
+
+func TestNumberUseString(t *testing.T) {
+	core.AssertEqual(t, "reserved", NumberReserved.String(), "reserved")
+	core.AssertEqual(t, "in an extension range", NumberExtensionRange.String(), "extension range")
+	core.AssertEqual(t, "unknown", NumberUse(99).String(), "unknown")
+}
+
+func TestReservedFieldNames(t *testing.T) {
+	msg := newNumbersTestMessage()
+	core.AssertTrue(t, IsReservedFieldName(msg, "old"), "reserved name")
+	core.AssertFalse(t, IsReservedFieldName(msg, "a"), "field name")
+	core.AssertFalse(t, IsFieldNameFree(msg, "old"), "reserved not free")
+	core.AssertFalse(t, IsFieldNameFree(msg, "a"), "used not free")
+	core.AssertTrue(t, IsFieldNameFree(msg, "c"), "free name")
+}
+
+func TestFieldNumberAllocator(t *testing.T) {
+	a := NewFieldNumberAllocator(newNumbersTestMessage())
+
+	var got []int32
+	for range 4 {
+		n, err := a.Next()
+		core.AssertMustNoError(t, err, "Next")
+		got = append(got, n)
+	}
+	core.AssertSliceEqual(t, []int32{3, 10, 11, 12}, got, "allocated")
+
+	core.AssertTrue(t, a.IsFree(13), "13 free")
+	core.AssertMustNoError(t, a.Allocate(13), "Allocate")
+	core.AssertFalse(t, a.IsFree(13), "13 allocated")
+	core.AssertErrorIs(t, a.Allocate(13), core.ErrExists, "allocated twice")
+	core.AssertErrorIs(t, a.Allocate(150), core.ErrExists, "extension range")
+	core.AssertErrorIs(t, a.Allocate(0), core.ErrExists, "invalid")
+
+	a.Min = 98
+	n, err := a.Next()
+	core.AssertMustNoError(t, err, "Next")
+	core.AssertEqual(t, int32(98), n, "from Min")
+	n, err = a.Next()
+	core.AssertMustNoError(t, err, "Next")
+	core.AssertEqual(t, int32(99), n, "before extension range")
+	n, err = a.Next()
+	core.AssertMustNoError(t, err, "Next")
+	core.AssertEqual(t, int32(200), n, "after extension range")
+}
+
+func TestFieldNumberAllocatorLimits(t *testing.T) {
+	a := NewFieldNumberAllocator(NewMessage("Empty"))
+	a.Min = FirstImplementationNumber
+	n, err := a.Next()
+	core.AssertMustNoError(t, err, "Next")
+	core.AssertEqual(t, LastImplementationNumber+1, n, "skips implementation range")
+
+	a.Min = MaxFieldNumber
+	n, err = a.Next()
+	core.AssertMustNoError(t, err, "Next")
+	core.AssertEqual(t, MaxFieldNumber, n, "largest")
+	_, err = a.Next()
+	core.AssertErrorIs(t, err, core.ErrInvalid, "exhausted")
+}
+
+func TestHighestFieldNumber(t *testing.T) {
+	core.AssertEqual(t, int32(9), HighestFieldNumber(newNumbersTestMessage()), "reserved end")
+	core.AssertEqual(t, int32(0), HighestFieldNumber(NewMessage("Empty")), "empty")
+
+	n, err := NextFieldNumber(newNumbersTestMessage())
+	core.AssertMustNoError(t, err, "NextFieldNumber")
+	core.AssertEqual(t, int32(3), n, "NextFieldNumber")
+}
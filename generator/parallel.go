@@ -0,0 +1,169 @@
+package generator
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// ContextFileFunc is a FileFunc receiving a context, cancelled when the
+// generation of an earlier file fails or the parent context is done.
+// Like the FileFunc of ParallelFilePlugin, it's called concurrently.
+type ContextFileFunc func(ctx context.Context, req *pluginpb.CodeGeneratorRequest,
+	file *descriptorpb.FileDescriptorProto) ([]*pluginpb.CodeGeneratorResponse_File, error)
+
+// ParallelFilePlugin returns a plugin calling fn for the files to
+// generate concurrently, on up to workers goroutines or GOMAXPROCS when
+// not positive. The response is the one FilePlugin would return.
+//
+// fn is called from several goroutines at once and must be safe for
+// concurrent use. The request and its descriptors are shared by the
+// calls, so fn must only read them. Cache.Wrap can be used
+// concurrently. A panic in fn is recovered and reported as the error
+// of its file.
+func ParallelFilePlugin(workers int, fn FileFunc, features ...pluginpb.CodeGeneratorResponse_Feature) PluginFunc {
+	ctxFn := func(_ context.Context, req *pluginpb.CodeGeneratorRequest,
+		file *descriptorpb.FileDescriptorProto) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+		return fn(req, file)
+	}
+
+	return func(req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+		files, err := GenerateFiles(context.Background(), req, workers, ctxFn)
+		if err != nil {
+			return nil, err
+		}
+
+		resp := newFileResponse(features)
+		resp.File = files
+		return resp, nil
+	}
+}
+
+// GenerateFiles calls fn for the files to generate concurrently, on up
+// to workers goroutines or GOMAXPROCS when not positive, and returns
+// their outputs in the order of file_to_generate.
+//
+// Errors are those sequential generation would report: when a file
+// fails, the files after it are cancelled or skipped while those
+// before it complete, and the error of the first failing file is
+// returned. Panics in fn are recovered as core.PanicError and count as
+// the error of their file.
+func GenerateFiles(ctx context.Context, req *pluginpb.CodeGeneratorRequest, workers int,
+	fn ContextFileFunc) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+	files, err := filesToGenerate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	run := &fileRun{
+		ctx:     ctx,
+		req:     req,
+		fn:      fn,
+		files:   files,
+		failed:  len(files),
+		cancels: make(map[int]context.CancelFunc),
+		outputs: make([][]*pluginpb.CodeGeneratorResponse_File, len(files)),
+		errs:    make([]error, len(files)),
+	}
+	run.start(min(workers, len(files)))
+	return run.result()
+}
+
+// fileRun holds the state of a GenerateFiles call.
+type fileRun struct {
+	ctx   context.Context
+	req   *pluginpb.CodeGeneratorRequest
+	fn    ContextFileFunc
+	files []*descriptorpb.FileDescriptorProto
+
+	mu      sync.Mutex
+	failed  int
+	cancels map[int]context.CancelFunc
+	outputs [][]*pluginpb.CodeGeneratorResponse_File
+	errs    []error
+}
+
+// start runs the workers, feeding them the files in order, and waits
+// for them to finish.
+func (r *fileRun) start(workers int) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r.generate(i)
+			}
+		}()
+	}
+
+	for i := range r.files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// generate runs fn for a file, unless an earlier one failed, catching
+// its panics.
+func (r *fileRun) generate(i int) {
+	r.mu.Lock()
+	if i > r.failed {
+		r.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.cancels[i] = cancel
+	r.mu.Unlock()
+
+	var out []*pluginpb.CodeGeneratorResponse_File
+	err := ctx.Err()
+	if err == nil {
+		err = core.Catch(func() error {
+			var e error
+			out, e = r.fn(ctx, r.req, r.files[i])
+			return e
+		})
+	}
+	cancel()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.cancels, i)
+	if err == nil {
+		r.outputs[i] = out
+		return
+	}
+
+	r.errs[i] = err
+	if i < r.failed {
+		r.failed = i
+		for j, cancel := range r.cancels {
+			if j > i {
+				cancel()
+			}
+		}
+	}
+}
+
+// result concatenates the outputs in order, or returns the error of the
+// first file failing.
+func (r *fileRun) result() ([]*pluginpb.CodeGeneratorResponse_File, error) {
+	if r.failed < len(r.files) {
+		return nil, core.Wrapf(r.errs[r.failed], "%s", r.files[r.failed].GetName())
+	}
+
+	var out []*pluginpb.CodeGeneratorResponse_File
+	for _, files := range r.outputs {
+		out = append(out, files...)
+	}
+	return out, nil
+}
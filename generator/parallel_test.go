@@ -0,0 +1,179 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// newParallelTestRequest builds a request generating n files, f00.proto
+// onwards, declaring a message each.
+func newParallelTestRequest(t *testing.T, n int) *pluginpb.CodeGeneratorRequest {
+	t.Helper()
+	files := make([]*descriptorpb.FileDescriptorProto, n)
+	for i := range files {
+		files[i] = File(fmt.Sprintf("f%02d.proto", i)).Package(fmt.Sprintf("p%d", i)).
+			Message(fmt.Sprintf("M%d", i)).File().Build()
+	}
+	req, err := NewCodeGeneratorRequest(files)
+	core.AssertMustNoError(t, err, "NewCodeGeneratorRequest")
+	return req
+}
+
+// parallelTestIndex returns the number of a file of newParallelTestRequest.
+func parallelTestIndex(file *descriptorpb.FileDescriptorProto) int {
+	var i int
+	_, _ = fmt.Sscanf(file.GetName(), "f%02d.proto", &i)
+	return i
+}
+
+func TestParallelFilePlugin(t *testing.T) {
+	req := newParallelTestRequest(t, 20)
+
+	var running, peak atomic.Int32
+	slow := func(req *pluginpb.CodeGeneratorRequest,
+		file *descriptorpb.FileDescriptorProto) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+
+		// later files finish first
+		time.Sleep(time.Duration(20-parallelTestIndex(file)) * time.Millisecond)
+		// shared descriptors are only read
+		r := NewRegistry(req.GetProtoFile()...)
+		if _, ok := r.Message(file.GetPackage() + "." + file.MessageType[0].GetName()); !ok {
+			return nil, core.ErrNotExists
+		}
+		return listFileMessages(req, file)
+	}
+
+	want, err := FilePlugin(listFileMessages)(req)
+	core.AssertMustNoError(t, err, "FilePlugin")
+	got, err := ParallelFilePlugin(4, slow, pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)(req)
+	core.AssertMustNoError(t, err, "ParallelFilePlugin")
+
+	core.AssertMustEqual(t, len(want.File), len(got.File), "outputs")
+	for i := range want.File {
+		core.AssertEqual(t, want.File[i].GetName(), got.File[i].GetName(), "name")
+		core.AssertEqual(t, want.File[i].GetContent(), got.File[i].GetContent(), "content")
+	}
+	core.AssertEqual(t, uint64(1), got.GetSupportedFeatures(), "features")
+
+	core.AssertTrue(t, peak.Load() <= 4, "bounded workers")
+	core.AssertTrue(t, peak.Load() > 1, "concurrent")
+}
+
+func TestGenerateFilesFirstError(t *testing.T) {
+	req := newParallelTestRequest(t, 10)
+	errSlow, errFast := errors.New("slow"), errors.New("fast")
+
+	var started [10]atomic.Bool
+	fn := func(ctx context.Context, req *pluginpb.CodeGeneratorRequest,
+		file *descriptorpb.FileDescriptorProto) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+		i := parallelTestIndex(file)
+		started[i].Store(true)
+		switch {
+		case i == 2:
+			// fails after a later file did
+			time.Sleep(50 * time.Millisecond)
+			return nil, errSlow
+		case i == 3:
+			return nil, errFast
+		case i > 3:
+			// waits until cancelled
+			<-ctx.Done()
+			return nil, ctx.Err()
+		default:
+			return listFileMessages(req, file)
+		}
+	}
+
+	_, err := GenerateFiles(context.Background(), req, 4, fn)
+	core.AssertErrorIs(t, err, errSlow, "first file failing")
+	core.AssertContains(t, err.Error(), "f02.proto", "file named")
+	core.AssertFalse(t, started[9].Load(), "later files skipped")
+}
+
+func TestGenerateFilesPanic(t *testing.T) {
+	req := newParallelTestRequest(t, 4)
+
+	_, err := GenerateFiles(context.Background(), req, 2, func(_ context.Context, req *pluginpb.CodeGeneratorRequest,
+		file *descriptorpb.FileDescriptorProto) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+		if parallelTestIndex(file) == 1 {
+			panic("boom")
+		}
+		return listFileMessages(req, file)
+	})
+	core.AssertError(t, err, "panic")
+	core.AssertContains(t, err.Error(), "f01.proto", "file named")
+	core.AssertContains(t, err.Error(), "boom", "payload")
+
+	var pe *core.PanicError
+	core.AssertTrue(t, errors.As(err, &pe), "PanicError")
+}
+
+func TestGenerateFilesContext(t *testing.T) {
+	req := newParallelTestRequest(t, 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var calls atomic.Int32
+	_, err := GenerateFiles(ctx, req, 2, func(_ context.Context, req *pluginpb.CodeGeneratorRequest,
+		file *descriptorpb.FileDescriptorProto) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+		calls.Add(1)
+		return listFileMessages(req, file)
+	})
+	core.AssertErrorIs(t, err, context.Canceled, "cancelled")
+	core.AssertEqual(t, int32(0), calls.Load(), "nothing generated")
+}
+
+func TestGenerateFilesSequential(t *testing.T) {
+	req := newParallelTestRequest(t, 3)
+
+	var order []int
+	files, err := GenerateFiles(context.Background(), req, 1, func(_ context.Context,
+		req *pluginpb.CodeGeneratorRequest,
+		file *descriptorpb.FileDescriptorProto) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+		order = append(order, parallelTestIndex(file))
+		return listFileMessages(req, file)
+	})
+	core.AssertMustNoError(t, err, "GenerateFiles")
+	core.AssertSliceEqual(t, []int{0, 1, 2}, order, "one worker")
+	core.AssertEqual(t, 3, len(files), "outputs")
+
+	req.FileToGenerate = append(req.FileToGenerate, "missing.proto")
+	_, err = GenerateFiles(context.Background(), req, 0, nil)
+	core.AssertErrorIs(t, err, core.ErrNotExists, "missing file")
+}
+
+func TestParallelFilePluginCache(t *testing.T) {
+	req := newParallelTestRequest(t, 8)
+	c := &Cache{Dir: t.TempDir(), Version: "v1"}
+
+	var calls atomic.Int32
+	plugin := ParallelFilePlugin(4, c.Wrap(func(req *pluginpb.CodeGeneratorRequest,
+		file *descriptorpb.FileDescriptorProto) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+		calls.Add(1)
+		return listFileMessages(req, file)
+	}))
+
+	for range 2 {
+		resp, err := plugin(req)
+		core.AssertMustNoError(t, err, "ParallelFilePlugin")
+		core.AssertEqual(t, 8, len(resp.File), "outputs")
+		core.AssertEqual(t, "f03.txt", resp.File[3].GetName(), "order")
+	}
+	core.AssertEqual(t, int32(8), calls.Load(), "second run cached")
+}
@@ -0,0 +1,192 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ParseError describes a problem found while compiling .proto source.
+// Line and Column are one-based, and zero when unknown.
+type ParseError struct {
+	File    string
+	Message string
+	Line    int
+	Column  int
+}
+
+// Error implements the error interface using protoc's format.
+func (e *ParseError) Error() string {
+	switch {
+	case e.Line > 0:
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	case e.File != "":
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	default:
+		return e.Message
+	}
+}
+
+// Parser compiles .proto source text into file descriptors without
+// requiring protoc.
+//
+// Imports are resolved from the in-memory Files map first, and then
+// from the bundled well-known types (google/protobuf/*.proto).
+// proto2, proto3 and editions syntax are supported, and the resulting
+// descriptors are validated using protodesc.
+type Parser struct {
+	// Files maps import paths to .proto source text.
+	Files map[string]string
+
+	// SkipSourceCodeInfo disables the generation of SourceCodeInfo.
+	SkipSourceCodeInfo bool
+
+	results  map[string]*parsedFile
+	registry *protoregistry.Files
+	stack    []string
+}
+
+// parsedFile holds a compiled file and its linked counterpart.
+type parsedFile struct {
+	proto *descriptorpb.FileDescriptorProto
+	desc  protoreflect.FileDescriptor
+}
+
+// ParseProto compiles a single .proto source into a file descriptor.
+// The source may only import well-known types.
+func ParseProto(name, src string) (*descriptorpb.FileDescriptorProto, error) {
+	p := &Parser{
+		Files: map[string]string{name: src},
+	}
+
+	files, err := p.Parse(name)
+	if err != nil {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+// Parse compiles the named files and returns their descriptors,
+// in the same order as requested.
+func (p *Parser) Parse(names ...string) ([]*descriptorpb.FileDescriptorProto, error) {
+	out := make([]*descriptorpb.FileDescriptorProto, 0, len(names))
+	for _, name := range names {
+		pf, err := p.load(name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, pf.proto)
+	}
+	return out, nil
+}
+
+// ParseWithImports compiles the named files and returns their descriptors
+// together with all their transitive imports, including bundled
+// well-known types. Dependencies always precede the files importing them.
+func (p *Parser) ParseWithImports(names ...string) ([]*descriptorpb.FileDescriptorProto, error) {
+	if _, err := p.Parse(names...); err != nil {
+		return nil, err
+	}
+
+	var out []*descriptorpb.FileDescriptorProto
+	seen := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+
+		pf := p.results[name]
+		for _, dep := range pf.proto.Dependency {
+			visit(dep)
+		}
+		out = append(out, pf.proto)
+	}
+
+	for _, name := range names {
+		visit(name)
+	}
+	return out, nil
+}
+
+// load compiles a file and its imports, caching the result.
+func (p *Parser) load(name string) (*parsedFile, error) {
+	if pf, ok := p.results[name]; ok {
+		return pf, nil
+	}
+	for i, s := range p.stack {
+		if s == name {
+			cycle := append(core.SliceCopy(p.stack[i:]), name)
+			return nil, &ParseError{
+				File:    name,
+				Message: "import cycle: " + strings.Join(cycle, " -> "),
+			}
+		}
+	}
+
+	p.init()
+	src, ok := p.Files[name]
+	if !ok {
+		return p.loadWellKnown(name)
+	}
+
+	p.stack = append(p.stack, name)
+	defer func() { p.stack = p.stack[:len(p.stack)-1] }()
+
+	fp, err := parseSource(name, src, !p.SkipSourceCodeInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dep := range fp.file.Dependency {
+		if _, err := p.load(dep); err != nil {
+			return nil, err
+		}
+	}
+
+	pf, err := p.link(fp)
+	if err != nil {
+		return nil, err
+	}
+	p.results[name] = pf
+	return pf, nil
+}
+
+func (p *Parser) init() {
+	if p.results == nil {
+		p.results = make(map[string]*parsedFile)
+	}
+	if p.registry == nil {
+		p.registry = new(protoregistry.Files)
+	}
+}
+
+// loadWellKnown uses a bundled descriptor for files not given as source.
+func (p *Parser) loadWellKnown(name string) (*parsedFile, error) {
+	fd, ok := wellKnownFile(name)
+	if !ok {
+		return nil, &ParseError{
+			File:    name,
+			Message: "file not found",
+		}
+	}
+
+	for i := 0; i < fd.Imports().Len(); i++ {
+		if _, err := p.load(fd.Imports().Get(i).Path()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.registry.RegisterFile(fd); err != nil {
+		return nil, core.Wrap(err, name)
+	}
+
+	pf := &parsedFile{proto: wellKnownFileProto(fd), desc: fd}
+	p.results[name] = pf
+	return pf, nil
+}
@@ -0,0 +1,1136 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// parseBailout carries a parse error up the recursive descent.
+type parseBailout struct {
+	err error
+}
+
+// recoverBailout returns the error carried by a parseBailout panic,
+// re-panicking anything else.
+func recoverBailout(r any) error {
+	if r == nil {
+		return nil
+	}
+	b, ok := r.(parseBailout)
+	if !ok {
+		panic(r)
+	}
+	return b.err
+}
+
+// refKind identifies which name of a descriptor a reference is for.
+type refKind int
+
+const (
+	refType refKind = iota
+	refExtendee
+	refInput
+	refOutput
+)
+
+// refKey identifies a name reference needing resolution.
+type refKey struct {
+	desc proto.Message
+	kind refKind
+}
+
+// fileParser builds a FileDescriptorProto from the tokens of a file.
+type fileParser struct {
+	file     *descriptorpb.FileDescriptorProto
+	refs     map[refKey]token
+	src      string
+	name     string
+	syntax   string
+	toks     []token
+	locs     []*descriptorpb.SourceCodeInfo_Location
+	options  []*pendingOption
+	defaults []*pendingDefault
+	pos      int
+
+	sourceInfo bool
+}
+
+// pendingDefault is a default value waiting for its field type to be resolved.
+type pendingDefault struct {
+	field *descriptorpb.FieldDescriptorProto
+	value optionValue
+}
+
+// parseSource parses .proto source text without resolving names.
+func parseSource(name, src string, sourceInfo bool) (fp *fileParser, err error) {
+	toks, err := tokenize(name, src)
+	if err != nil {
+		return nil, err
+	}
+
+	fp = &fileParser{
+		file:       &descriptorpb.FileDescriptorProto{Name: proto.String(name)},
+		refs:       make(map[refKey]token),
+		src:        src,
+		name:       name,
+		toks:       toks,
+		sourceInfo: sourceInfo,
+	}
+
+	defer func() {
+		if e := recoverBailout(recover()); e != nil {
+			fp, err = nil, e
+		}
+	}()
+
+	fp.parseFile()
+	return fp, nil
+}
+
+//
+// Token helpers
+//
+
+func (fp *fileParser) peek() token {
+	return fp.toks[fp.pos]
+}
+
+func (fp *fileParser) peekAt(n int) token {
+	if i := fp.pos + n; i < len(fp.toks) {
+		return fp.toks[i]
+	}
+	return fp.toks[len(fp.toks)-1]
+}
+
+func (fp *fileParser) next() token {
+	t := fp.toks[fp.pos]
+	if t.kind != tokenEOF {
+		fp.pos++
+	}
+	return t
+}
+
+// last returns the most recently consumed token.
+func (fp *fileParser) last() token {
+	if fp.pos > 0 {
+		return fp.toks[fp.pos-1]
+	}
+	return fp.toks[0]
+}
+
+func (t token) is(text string) bool {
+	return (t.kind == tokenIdent || t.kind == tokenSymbol) && t.text == text
+}
+
+func (fp *fileParser) lookingAt(text string) bool {
+	return fp.peek().is(text)
+}
+
+func (fp *fileParser) tryConsume(text string) bool {
+	if fp.lookingAt(text) {
+		fp.next()
+		return true
+	}
+	return false
+}
+
+func (fp *fileParser) consume(text string) token {
+	if !fp.lookingAt(text) {
+		fp.fail(fp.peek(), "Expected %q.", text)
+	}
+	return fp.next()
+}
+
+func (fp *fileParser) consumeIdent(what string) token {
+	if fp.peek().kind != tokenIdent {
+		fp.fail(fp.peek(), "Expected %s.", what)
+	}
+	return fp.next()
+}
+
+// consumeString reads one or more adjacent string literals.
+func (fp *fileParser) consumeString(what string) (string, token) {
+	first := fp.peek()
+	if first.kind != tokenString {
+		fp.fail(first, "Expected %s.", what)
+	}
+
+	var sb strings.Builder
+	for fp.peek().kind == tokenString {
+		_, _ = sb.WriteString(fp.next().value)
+	}
+	return sb.String(), first
+}
+
+// consumeInt reads an optionally negative integer within [minValue, maxValue].
+func (fp *fileParser) consumeInt(what string, minValue, maxValue int64) (int64, token) {
+	first := fp.peek()
+	negative := fp.tryConsume("-")
+
+	t := fp.peek()
+	if t.kind != tokenInt {
+		fp.fail(t, "Expected %s.", what)
+	}
+	fp.next()
+
+	u, err := strconv.ParseUint(t.text, 0, 64)
+	if err != nil || u > 1<<63 {
+		fp.fail(t, "Integer out of range.")
+	}
+
+	v := int64(u)
+	if negative {
+		v = -v
+	}
+	if v < minValue || v > maxValue {
+		fp.fail(t, "Integer out of range.")
+	}
+	return v, first
+}
+
+func (fp *fileParser) fail(t token, format string, args ...any) {
+	panic(parseBailout{err: &ParseError{
+		File:    fp.name,
+		Line:    t.line + 1,
+		Column:  t.col + 1,
+		Message: fmt.Sprintf(format, args...),
+	}})
+}
+
+//
+// Source locations
+//
+
+// startLoc records a new location, spans are completed by endLoc.
+func (fp *fileParser) startLoc(path []int32, first token) *descriptorpb.SourceCodeInfo_Location {
+	loc := &descriptorpb.SourceCodeInfo_Location{
+		Path: appendPath(nil, path...),
+		Span: []int32{int32(first.line), int32(first.col)},
+	}
+	fp.locs = append(fp.locs, loc)
+	return loc
+}
+
+// endLoc completes the span of a location at the end of the given token.
+func (fp *fileParser) endLoc(loc *descriptorpb.SourceCodeInfo_Location, last token) {
+	if int32(last.endLine) == loc.Span[0] {
+		loc.Span = append(loc.Span[:2], int32(last.endCol))
+	} else {
+		loc.Span = append(loc.Span[:2], int32(last.endLine), int32(last.endCol))
+	}
+}
+
+// tokenLoc records the location of a single token.
+func (fp *fileParser) tokenLoc(path []int32, t token) {
+	fp.endLoc(fp.startLoc(path, t), t)
+}
+
+// attachComments copies the comments before the first token of a
+// declaration, and after the token ending its header.
+func attachComments(loc *descriptorpb.SourceCodeInfo_Location, first, end token) {
+	if first.leading != "" {
+		loc.LeadingComments = proto.String(first.leading)
+	}
+	if end.trailing != "" {
+		loc.TrailingComments = proto.String(end.trailing)
+	}
+	loc.LeadingDetachedComments = append(loc.LeadingDetachedComments, first.detached...)
+}
+
+//
+// File
+//
+
+func (fp *fileParser) parseFile() {
+	root := fp.startLoc(nil, fp.peek())
+	fp.parseSyntax()
+
+	for fp.peek().kind != tokenEOF {
+		fp.parseTopLevel()
+	}
+
+	fp.endLoc(root, fp.last())
+	if fp.sourceInfo {
+		fp.file.SourceCodeInfo = &descriptorpb.SourceCodeInfo{Location: fp.locs}
+	}
+}
+
+func (fp *fileParser) parseSyntax() {
+	first := fp.peek()
+	switch {
+	case first.is("syntax"):
+		loc := fp.startLoc([]int32{pathFileSyntax}, first)
+		fp.next()
+		fp.consume("=")
+		syntax, t := fp.consumeString("syntax identifier")
+		switch syntax {
+		case SyntaxProto2:
+		case SyntaxProto3:
+			fp.file.Syntax = proto.String(syntax)
+		default:
+			fp.fail(t, "Unrecognized syntax identifier %q. This parser only recognizes \"proto2\" and \"proto3\".",
+				syntax)
+		}
+		end := fp.consume(";")
+		attachComments(loc, first, end)
+		fp.endLoc(loc, end)
+	case first.is("edition"):
+		loc := fp.startLoc([]int32{pathFileEdition}, first)
+		fp.next()
+		fp.consume("=")
+		edition, t := fp.consumeString("edition")
+		value, ok := descriptorpb.Edition_value["EDITION_"+edition]
+		if !ok || edition == "" || !isDigit(edition[0]) {
+			fp.fail(t, "Unknown edition %q.", edition)
+		}
+		fp.file.Syntax = proto.String(SyntaxEditions)
+		fp.file.Edition = descriptorpb.Edition(value).Enum()
+		end := fp.consume(";")
+		attachComments(loc, first, end)
+		fp.endLoc(loc, end)
+	}
+	fp.syntax = FileSyntax(fp.file)
+}
+
+func (fp *fileParser) parseTopLevel() {
+	file := fp.file
+	pkg := file.GetPackage()
+
+	switch t := fp.peek(); {
+	case fp.tryConsume(";"):
+	case t.is("message"):
+		path := []int32{pathFileMessage, int32(len(file.MessageType))}
+		file.MessageType = append(file.MessageType, nil)
+		file.MessageType[path[1]] = fp.parseMessage(path, pkg)
+	case t.is("enum"):
+		path := []int32{pathFileEnum, int32(len(file.EnumType))}
+		file.EnumType = append(file.EnumType, nil)
+		file.EnumType[path[1]] = fp.parseEnum(path, pkg)
+	case t.is("service"):
+		path := []int32{pathFileService, int32(len(file.Service))}
+		file.Service = append(file.Service, nil)
+		file.Service[path[1]] = fp.parseService(path)
+	case t.is("extend"):
+		fp.parseExtend(&fieldContext{
+			fields:     &file.Extension,
+			fieldsPath: []int32{pathFileExtension},
+			nested:     &file.MessageType,
+			nestedPath: []int32{pathFileMessage},
+			scope:      pkg,
+		})
+	case t.is("import"):
+		fp.parseImport()
+	case t.is("package"):
+		fp.parsePackage()
+	case t.is("option"):
+		fp.parseOptionStatement([]int32{pathFileOptions}, pkg, func() proto.Message {
+			if file.Options == nil {
+				file.Options = &descriptorpb.FileOptions{}
+			}
+			return file.Options
+		})
+	default:
+		fp.fail(t, "Expected top-level statement (e.g. \"message\").")
+	}
+}
+
+func (fp *fileParser) parseImport() {
+	first := fp.next()
+	index := int32(len(fp.file.Dependency))
+	loc := fp.startLoc([]int32{pathFileDependency, index}, first)
+
+	switch {
+	case fp.tryConsume("public"):
+		fp.file.PublicDependency = append(fp.file.PublicDependency, index)
+	case fp.tryConsume("weak"):
+		fp.file.WeakDependency = append(fp.file.WeakDependency, index)
+	}
+
+	path, _ := fp.consumeString("a string naming the file to import")
+	fp.file.Dependency = append(fp.file.Dependency, path)
+
+	end := fp.consume(";")
+	attachComments(loc, first, end)
+	fp.endLoc(loc, end)
+}
+
+func (fp *fileParser) parsePackage() {
+	first := fp.next()
+	if fp.file.Package != nil {
+		fp.fail(first, "Multiple package definitions.")
+	}
+
+	loc := fp.startLoc([]int32{pathFilePackage}, first)
+	fp.file.Package = proto.String(fp.parseFullIdent(false))
+
+	end := fp.consume(";")
+	attachComments(loc, first, end)
+	fp.endLoc(loc, end)
+}
+
+// parseFullIdent reads a dotted identifier, optionally fully-qualified.
+func (fp *fileParser) parseFullIdent(allowLeadingDot bool) string {
+	var sb strings.Builder
+	if allowLeadingDot && fp.tryConsume(".") {
+		_ = sb.WriteByte('.')
+	}
+
+	_, _ = sb.WriteString(fp.consumeIdent("identifier").text)
+	for fp.tryConsume(".") {
+		_ = sb.WriteByte('.')
+		_, _ = sb.WriteString(fp.consumeIdent("identifier").text)
+	}
+	return sb.String()
+}
+
+//
+// Messages
+//
+
+func (fp *fileParser) parseMessage(path []int32, scope string) *descriptorpb.DescriptorProto {
+	first := fp.consume("message")
+	loc := fp.startLoc(path, first)
+
+	name := fp.consumeIdent("message name")
+	fp.tokenLoc(appendPath(path, 1), name)
+	msg := &descriptorpb.DescriptorProto{Name: proto.String(name.text)}
+
+	open := fp.consume("{")
+	attachComments(loc, first, open)
+	fp.parseMessageBody(msg, path, joinName(scope, name.text))
+	fp.endLoc(loc, fp.consume("}"))
+	return msg
+}
+
+func (fp *fileParser) parseMessageBody(msg *descriptorpb.DescriptorProto, path []int32, scope string) {
+	ctx := &fieldContext{
+		fields:     &msg.Field,
+		fieldsPath: appendPath(path, pathMessageField),
+		nested:     &msg.NestedType,
+		nestedPath: appendPath(path, pathMessageNested),
+		scope:      scope,
+	}
+
+	for !fp.lookingAt("}") {
+		if fp.peek().kind == tokenEOF {
+			fp.fail(fp.peek(), "Reached end of input in message definition (missing '}').")
+		}
+		fp.parseMessageStatement(msg, path, ctx)
+	}
+
+	fp.addSyntheticOneofs(msg)
+}
+
+func (fp *fileParser) parseMessageStatement(msg *descriptorpb.DescriptorProto,
+	path []int32, ctx *fieldContext) {
+	switch t := fp.peek(); {
+	case fp.tryConsume(";"):
+	case t.is("message"):
+		p := appendPath(path, pathMessageNested, int32(len(msg.NestedType)))
+		msg.NestedType = append(msg.NestedType, nil)
+		msg.NestedType[p[len(p)-1]] = fp.parseMessage(p, ctx.scope)
+	case t.is("enum"):
+		p := appendPath(path, pathMessageEnum, int32(len(msg.EnumType)))
+		msg.EnumType = append(msg.EnumType, nil)
+		msg.EnumType[p[len(p)-1]] = fp.parseEnum(p, ctx.scope)
+	case t.is("extensions"):
+		fp.parseExtensionRanges(msg, path, ctx.scope)
+	case t.is("reserved"):
+		fp.parseMessageReserved(msg, path)
+	case t.is("extend"):
+		fp.parseExtend(&fieldContext{
+			fields:     &msg.Extension,
+			fieldsPath: appendPath(path, pathMessageExtension),
+			nested:     &msg.NestedType,
+			nestedPath: appendPath(path, pathMessageNested),
+			scope:      ctx.scope,
+		})
+	case t.is("option"):
+		fp.parseOptionStatement(appendPath(path, pathMessageOptions), ctx.scope, func() proto.Message {
+			if msg.Options == nil {
+				msg.Options = &descriptorpb.MessageOptions{}
+			}
+			return msg.Options
+		})
+	case t.is("oneof"):
+		fp.parseOneof(msg, path, ctx)
+	default:
+		fp.parseField(ctx)
+	}
+}
+
+// addSyntheticOneofs declares the oneofs backing proto3 optional fields.
+func (fp *fileParser) addSyntheticOneofs(msg *descriptorpb.DescriptorProto) {
+	names := make(map[string]bool)
+	for _, f := range msg.Field {
+		names[f.GetName()] = true
+	}
+	for _, m := range msg.NestedType {
+		names[m.GetName()] = true
+	}
+	for _, e := range msg.EnumType {
+		names[e.GetName()] = true
+	}
+	for _, o := range msg.OneofDecl {
+		names[o.GetName()] = true
+	}
+
+	for _, f := range msg.Field {
+		if !f.GetProto3Optional() {
+			continue
+		}
+
+		name := f.GetName()
+		if !strings.HasPrefix(name, "_") {
+			name = "_" + name
+		}
+		for names[name] {
+			name = "X" + name
+		}
+		names[name] = true
+
+		f.OneofIndex = proto.Int32(int32(len(msg.OneofDecl)))
+		msg.OneofDecl = append(msg.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String(name)})
+	}
+}
+
+func (fp *fileParser) parseOneof(msg *descriptorpb.DescriptorProto, path []int32, ctx *fieldContext) {
+	first := fp.consume("oneof")
+	index := int32(len(msg.OneofDecl))
+	oneofPath := appendPath(path, pathMessageOneof, index)
+	loc := fp.startLoc(oneofPath, first)
+
+	name := fp.consumeIdent("oneof name")
+	fp.tokenLoc(appendPath(oneofPath, 1), name)
+	oneof := &descriptorpb.OneofDescriptorProto{Name: proto.String(name.text)}
+	msg.OneofDecl = append(msg.OneofDecl, oneof)
+
+	open := fp.consume("{")
+	attachComments(loc, first, open)
+
+	oneofCtx := *ctx
+	oneofCtx.oneofIndex = proto.Int32(index)
+	for !fp.lookingAt("}") {
+		switch t := fp.peek(); {
+		case t.kind == tokenEOF:
+			fp.fail(t, "Reached end of input in oneof definition (missing '}').")
+		case fp.tryConsume(";"):
+		case t.is("option"):
+			fp.parseOptionStatement(appendPath(oneofPath, pathOneofOptions), ctx.scope, func() proto.Message {
+				if oneof.Options == nil {
+					oneof.Options = &descriptorpb.OneofOptions{}
+				}
+				return oneof.Options
+			})
+		default:
+			fp.parseField(&oneofCtx)
+		}
+	}
+	fp.endLoc(loc, fp.consume("}"))
+}
+
+func (fp *fileParser) parseExtensionRanges(msg *descriptorpb.DescriptorProto, path []int32, scope string) {
+	first := fp.consume("extensions")
+	loc := fp.startLoc(appendPath(path, pathMessageExtensionRange), first)
+
+	startIndex := len(msg.ExtensionRange)
+	for {
+		r := fp.parseRange(1, maxFieldNumber)
+		msg.ExtensionRange = append(msg.ExtensionRange, &descriptorpb.DescriptorProto_ExtensionRange{
+			Start: proto.Int32(r.start),
+			End:   proto.Int32(r.end + 1),
+		})
+		if !fp.tryConsume(",") {
+			break
+		}
+	}
+
+	if fp.lookingAt("[") {
+		opts := fp.parseOptionList()
+		for i := startIndex; i < len(msg.ExtensionRange); i++ {
+			r := msg.ExtensionRange[i]
+			for _, o := range opts {
+				fp.addOption(o, scope, func() proto.Message {
+					if r.Options == nil {
+						r.Options = &descriptorpb.ExtensionRangeOptions{}
+					}
+					return r.Options
+				})
+			}
+		}
+	}
+
+	end := fp.consume(";")
+	attachComments(loc, first, end)
+	fp.endLoc(loc, end)
+}
+
+// parseRange reads "N", "N to M" or "N to max".
+func (fp *fileParser) parseRange(minValue, maxValue int64) numberRange {
+	start, _ := fp.consumeInt("field number range", minValue, maxValue)
+	end := start
+	if fp.tryConsume("to") {
+		if fp.tryConsume("max") {
+			end = maxValue
+		} else {
+			end, _ = fp.consumeInt("integer", minValue, maxValue)
+		}
+	}
+	return numberRange{start: int32(start), end: int32(end)}
+}
+
+func (fp *fileParser) parseMessageReserved(msg *descriptorpb.DescriptorProto, path []int32) {
+	first := fp.consume("reserved")
+
+	if names, ok := fp.parseReservedNames(); ok {
+		loc := fp.startLoc(appendPath(path, pathMessageReservedName), first)
+		msg.ReservedName = append(msg.ReservedName, names...)
+		end := fp.consume(";")
+		attachComments(loc, first, end)
+		fp.endLoc(loc, end)
+		return
+	}
+
+	loc := fp.startLoc(appendPath(path, pathMessageReservedRange), first)
+	for {
+		r := fp.parseRange(1, maxFieldNumber)
+		msg.ReservedRange = append(msg.ReservedRange, &descriptorpb.DescriptorProto_ReservedRange{
+			Start: proto.Int32(r.start),
+			End:   proto.Int32(r.end + 1),
+		})
+		if !fp.tryConsume(",") {
+			break
+		}
+	}
+	end := fp.consume(";")
+	attachComments(loc, first, end)
+	fp.endLoc(loc, end)
+}
+
+// parseReservedNames reads a list of reserved names, quoted strings in
+// proto2 and proto3 and identifiers in editions.
+func (fp *fileParser) parseReservedNames() ([]string, bool) {
+	t := fp.peek()
+	switch {
+	case t.kind == tokenString && fp.syntax == SyntaxEditions:
+		fp.fail(t, "Reserved names must be identifiers in editions, not string literals.")
+	case t.kind == tokenIdent && fp.syntax != SyntaxEditions:
+		fp.fail(t, "Reserved names must be string literals. (Only editions supports identifiers.)")
+	case t.kind != tokenString && t.kind != tokenIdent:
+		return nil, false
+	}
+
+	var names []string
+	for {
+		if fp.syntax == SyntaxEditions {
+			names = append(names, fp.consumeIdent("reserved name").text)
+		} else {
+			name, _ := fp.consumeString("reserved name")
+			names = append(names, name)
+		}
+		if !fp.tryConsume(",") {
+			return names, true
+		}
+	}
+}
+
+//
+// Fields
+//
+
+// fieldContext describes where parsed fields, and the nested types
+// they declare, are stored.
+type fieldContext struct {
+	fields     *[]*descriptorpb.FieldDescriptorProto
+	nested     *[]*descriptorpb.DescriptorProto
+	oneofIndex *int32
+	fieldsPath []int32
+	nestedPath []int32
+	scope      string
+	extendee   string
+
+	extendeeTok token
+}
+
+func (fp *fileParser) parseExtend(ctx *fieldContext) {
+	first := fp.consume("extend")
+	loc := fp.startLoc(ctx.fieldsPath, first)
+
+	ctx.extendeeTok = fp.peek()
+	ctx.extendee = fp.parseFullIdent(true)
+
+	open := fp.consume("{")
+	attachComments(loc, first, open)
+	for !fp.lookingAt("}") {
+		switch t := fp.peek(); {
+		case t.kind == tokenEOF:
+			fp.fail(t, "Reached end of input in extend definition (missing '}').")
+		case fp.tryConsume(";"):
+		default:
+			fp.parseField(ctx)
+		}
+	}
+	fp.endLoc(loc, fp.consume("}"))
+}
+
+func (fp *fileParser) parseField(ctx *fieldContext) {
+	first := fp.peek()
+	index := int32(len(*ctx.fields))
+	path := appendPath(ctx.fieldsPath, index)
+	loc := fp.startLoc(path, first)
+
+	field := &descriptorpb.FieldDescriptorProto{}
+	*ctx.fields = append(*ctx.fields, field)
+	if ctx.extendee != "" {
+		field.Extendee = proto.String(ctx.extendee)
+		fp.refs[refKey{field, refExtendee}] = ctx.extendeeTok
+	}
+	if ctx.oneofIndex != nil {
+		field.OneofIndex = proto.Int32(*ctx.oneofIndex)
+	}
+
+	hasLabel := fp.parseLabel(ctx, field, path)
+	switch {
+	case fp.lookingAt("map") && fp.peekAt(1).is("<"):
+		fp.parseMapField(ctx, field, path, hasLabel)
+	case fp.lookingAt("group"):
+		fp.parseGroupField(ctx, field, path, loc, first)
+		return
+	default:
+		fp.parseFieldType(field, path)
+		fp.parseFieldNameAndNumber(field, path)
+	}
+
+	fp.parseFieldOptions(ctx, field, path)
+	end := fp.consume(";")
+	attachComments(loc, first, end)
+	fp.endLoc(loc, end)
+}
+
+// parseLabel reads the optional label of a field, validating it against
+// the syntax, and returns whether one was present.
+func (fp *fileParser) parseLabel(ctx *fieldContext, field *descriptorpb.FieldDescriptorProto, path []int32) bool {
+	t := fp.peek()
+	label, ok := map[string]descriptorpb.FieldDescriptorProto_Label{
+		"optional": LabelOptional,
+		"required": LabelRequired,
+		"repeated": LabelRepeated,
+	}[t.text]
+
+	if !ok || t.kind != tokenIdent {
+		if ctx.oneofIndex == nil && ctx.extendee == "" && fp.syntax == SyntaxProto2 &&
+			!(fp.lookingAt("map") && fp.peekAt(1).is("<")) {
+			fp.fail(t, "Expected \"required\", \"optional\", or \"repeated\".")
+		}
+		field.Label = LabelOptional.Enum()
+		return false
+	}
+
+	switch {
+	case ctx.oneofIndex != nil:
+		fp.fail(t, "Fields in oneofs must not have labels (required / optional / repeated).")
+	case fp.syntax == SyntaxEditions && label != LabelRepeated:
+		fp.fail(t, "Label %q is not supported in editions. Use features.field_presence instead.", t.text)
+	case fp.syntax == SyntaxProto3 && label == LabelRequired:
+		fp.fail(t, "Required fields are not allowed in proto3.")
+	case fp.syntax == SyntaxProto3 && label == LabelOptional && ctx.extendee == "":
+		field.Proto3Optional = proto.Bool(true)
+	}
+
+	fp.next()
+	fp.tokenLoc(appendPath(path, 4), t)
+	field.Label = label.Enum()
+	return true
+}
+
+// scalarTypes maps .proto scalar keywords to field types.
+var scalarTypes = map[string]descriptorpb.FieldDescriptorProto_Type{
+	"double":   TypeDouble,
+	"float":    TypeFloat,
+	"int64":    TypeInt64,
+	"uint64":   TypeUInt64,
+	"int32":    TypeInt32,
+	"fixed64":  TypeFixed64,
+	"fixed32":  TypeFixed32,
+	"bool":     TypeBool,
+	"string":   TypeString,
+	"bytes":    TypeBytes,
+	"uint32":   TypeUInt32,
+	"sfixed32": TypeSFixed32,
+	"sfixed64": TypeSFixed64,
+	"sint32":   TypeSInt32,
+	"sint64":   TypeSInt64,
+}
+
+// parseFieldType reads a scalar or named type. Locations are only
+// recorded when a path is given, map entry fields don't have them.
+func (fp *fileParser) parseFieldType(field *descriptorpb.FieldDescriptorProto, path []int32) {
+	t := fp.peek()
+	if typ, ok := scalarTypes[t.text]; ok && t.kind == tokenIdent && !fp.peekAt(1).is(".") {
+		fp.next()
+		if path != nil {
+			fp.tokenLoc(appendPath(path, 5), t)
+		}
+		field.Type = typ.Enum()
+		return
+	}
+
+	field.TypeName = proto.String(fp.parseFullIdent(true))
+	fp.refs[refKey{field, refType}] = t
+	if path != nil {
+		fp.endLoc(fp.startLoc(appendPath(path, 6), t), fp.last())
+	}
+}
+
+func (fp *fileParser) parseFieldNameAndNumber(field *descriptorpb.FieldDescriptorProto, path []int32) {
+	name := fp.consumeIdent("field name")
+	fp.tokenLoc(appendPath(path, 1), name)
+	field.Name = proto.String(name.text)
+
+	fp.consume("=")
+	number, t := fp.consumeInt("field number", 0, maxFieldNumber)
+	fp.tokenLoc(appendPath(path, 3), t)
+	field.Number = proto.Int32(int32(number))
+}
+
+func (fp *fileParser) parseMapField(ctx *fieldContext, field *descriptorpb.FieldDescriptorProto,
+	path []int32, hasLabel bool) {
+	t := fp.next()
+	switch {
+	case hasLabel:
+		fp.fail(t, "Field labels (required/optional/repeated) are not allowed on map fields.")
+	case ctx.oneofIndex != nil:
+		fp.fail(t, "Map fields are not allowed in oneofs.")
+	case ctx.extendee != "":
+		fp.fail(t, "Map fields are not allowed to be extensions.")
+	}
+
+	loc := fp.startLoc(appendPath(path, 6), t)
+	fp.consume("<")
+	key := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String("key"),
+		Number:   proto.Int32(1),
+		Label:    LabelOptional.Enum(),
+		JsonName: proto.String("key"),
+	}
+	fp.parseFieldType(key, nil)
+	fp.consume(",")
+	value := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String("value"),
+		Number:   proto.Int32(2),
+		Label:    LabelOptional.Enum(),
+		JsonName: proto.String("value"),
+	}
+	fp.parseFieldType(value, nil)
+	fp.endLoc(loc, fp.consume(">"))
+
+	fp.parseFieldNameAndNumber(field, path)
+
+	entry := &descriptorpb.DescriptorProto{
+		Name:    proto.String(mapEntryName(field.GetName())),
+		Field:   []*descriptorpb.FieldDescriptorProto{key, value},
+		Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+	}
+	*ctx.nested = append(*ctx.nested, entry)
+
+	field.Label = LabelRepeated.Enum()
+	field.Type = TypeMessage.Enum()
+	field.TypeName = proto.String(entry.GetName())
+}
+
+// mapEntryName returns the name protoc gives to the entry message of
+// a map field, e.g. "FooBarEntry" for "foo_bar".
+func mapEntryName(fieldName string) string {
+	var sb strings.Builder
+	upper := true
+	for i := 0; i < len(fieldName); i++ {
+		c := fieldName[i]
+		switch {
+		case c == '_':
+			upper = true
+		case upper && 'a' <= c && c <= 'z':
+			_ = sb.WriteByte(c - 'a' + 'A')
+			upper = false
+		default:
+			_ = sb.WriteByte(c)
+			upper = false
+		}
+	}
+	return sb.String() + "Entry"
+}
+
+func (fp *fileParser) parseGroupField(ctx *fieldContext, field *descriptorpb.FieldDescriptorProto,
+	path []int32, loc *descriptorpb.SourceCodeInfo_Location, first token) {
+	t := fp.next()
+	if fp.syntax != SyntaxProto2 {
+		fp.fail(t, "Group syntax is no longer supported in %s. Use a message field instead.", fp.syntax)
+	}
+
+	name := fp.consumeIdent("group name")
+	if c := name.text[0]; c < 'A' || c > 'Z' {
+		fp.fail(name, "Group names must start with a capital letter.")
+	}
+	fp.tokenLoc(appendPath(path, 1), name)
+	field.Name = proto.String(strings.ToLower(name.text))
+	field.Type = TypeGroup.Enum()
+	field.TypeName = proto.String(name.text)
+
+	fp.consume("=")
+	number, nt := fp.consumeInt("field number", 0, maxFieldNumber)
+	fp.tokenLoc(appendPath(path, 3), nt)
+	field.Number = proto.Int32(int32(number))
+	fp.parseFieldOptions(ctx, field, path)
+
+	nestedPath := appendPath(ctx.nestedPath, int32(len(*ctx.nested)))
+	group := &descriptorpb.DescriptorProto{Name: proto.String(name.text)}
+	*ctx.nested = append(*ctx.nested, group)
+	groupLoc := fp.startLoc(nestedPath, first)
+
+	open := fp.consume("{")
+	attachComments(loc, first, open)
+	fp.parseMessageBody(group, nestedPath, joinName(ctx.scope, name.text))
+	end := fp.consume("}")
+	fp.endLoc(loc, end)
+	fp.endLoc(groupLoc, end)
+}
+
+func (fp *fileParser) parseFieldOptions(ctx *fieldContext, field *descriptorpb.FieldDescriptorProto, path []int32) {
+	if !fp.lookingAt("[") {
+		return
+	}
+
+	loc := fp.startLoc(appendPath(path, pathFieldOptions), fp.peek())
+	for _, o := range fp.parseOptionList() {
+		switch {
+		case o.isSimple("default"):
+			if fp.syntax == SyntaxProto3 {
+				fp.fail(o.tok, "Explicit default values are not allowed in proto3.")
+			}
+			fp.defaults = append(fp.defaults, &pendingDefault{field: field, value: o.value})
+		case o.isSimple("json_name"):
+			if ctx.extendee != "" {
+				fp.fail(o.tok, "option json_name is not allowed on extension fields.")
+			}
+			if o.value.kind != valueString {
+				fp.fail(o.value.tok, "Expected string for JSON name.")
+			}
+			field.JsonName = proto.String(o.value.str)
+		default:
+			fp.addOption(o, ctx.scope, func() proto.Message {
+				if field.Options == nil {
+					field.Options = &descriptorpb.FieldOptions{}
+				}
+				return field.Options
+			})
+		}
+	}
+	fp.endLoc(loc, fp.last())
+}
+
+//
+// Enums
+//
+
+func (fp *fileParser) parseEnum(path []int32, scope string) *descriptorpb.EnumDescriptorProto {
+	first := fp.consume("enum")
+	loc := fp.startLoc(path, first)
+
+	name := fp.consumeIdent("enum name")
+	fp.tokenLoc(appendPath(path, 1), name)
+	enum := &descriptorpb.EnumDescriptorProto{Name: proto.String(name.text)}
+
+	open := fp.consume("{")
+	attachComments(loc, first, open)
+	for !fp.lookingAt("}") {
+		fp.parseEnumStatement(enum, path, scope)
+	}
+	fp.endLoc(loc, fp.consume("}"))
+	return enum
+}
+
+func (fp *fileParser) parseEnumStatement(enum *descriptorpb.EnumDescriptorProto, path []int32, scope string) {
+	switch t := fp.peek(); {
+	case t.kind == tokenEOF:
+		fp.fail(t, "Reached end of input in enum definition (missing '}').")
+	case fp.tryConsume(";"):
+	case t.is("option") && !fp.peekAt(1).is("="):
+		fp.parseOptionStatement(appendPath(path, pathEnumOptions), scope, func() proto.Message {
+			if enum.Options == nil {
+				enum.Options = &descriptorpb.EnumOptions{}
+			}
+			return enum.Options
+		})
+	case t.is("reserved") && !fp.peekAt(1).is("="):
+		fp.parseEnumReserved(enum, path)
+	default:
+		fp.parseEnumValue(enum, path, scope)
+	}
+}
+
+func (fp *fileParser) parseEnumValue(enum *descriptorpb.EnumDescriptorProto, path []int32, scope string) {
+	first := fp.peek()
+	valuePath := appendPath(path, pathEnumValue, int32(len(enum.Value)))
+	loc := fp.startLoc(valuePath, first)
+
+	name := fp.consumeIdent("enum constant name")
+	fp.tokenLoc(appendPath(valuePath, 1), name)
+	fp.consume("=")
+	number, t := fp.consumeInt("integer", -maxEnumNumber-1, maxEnumNumber)
+	fp.tokenLoc(appendPath(valuePath, 2), t)
+
+	value := &descriptorpb.EnumValueDescriptorProto{
+		Name:   proto.String(name.text),
+		Number: proto.Int32(int32(number)),
+	}
+	enum.Value = append(enum.Value, value)
+
+	if fp.lookingAt("[") {
+		for _, o := range fp.parseOptionList() {
+			fp.addOption(o, scope, func() proto.Message {
+				if value.Options == nil {
+					value.Options = &descriptorpb.EnumValueOptions{}
+				}
+				return value.Options
+			})
+		}
+	}
+
+	end := fp.consume(";")
+	attachComments(loc, first, end)
+	fp.endLoc(loc, end)
+}
+
+func (fp *fileParser) parseEnumReserved(enum *descriptorpb.EnumDescriptorProto, path []int32) {
+	first := fp.consume("reserved")
+
+	if names, ok := fp.parseReservedNames(); ok {
+		loc := fp.startLoc(appendPath(path, pathEnumReservedName), first)
+		enum.ReservedName = append(enum.ReservedName, names...)
+		end := fp.consume(";")
+		attachComments(loc, first, end)
+		fp.endLoc(loc, end)
+		return
+	}
+
+	loc := fp.startLoc(appendPath(path, pathEnumReservedRange), first)
+	for {
+		r := fp.parseRange(-maxEnumNumber-1, maxEnumNumber)
+		enum.ReservedRange = append(enum.ReservedRange, &descriptorpb.EnumDescriptorProto_EnumReservedRange{
+			Start: proto.Int32(r.start),
+			End:   proto.Int32(r.end),
+		})
+		if !fp.tryConsume(",") {
+			break
+		}
+	}
+	end := fp.consume(";")
+	attachComments(loc, first, end)
+	fp.endLoc(loc, end)
+}
+
+//
+// Services
+//
+
+func (fp *fileParser) parseService(path []int32) *descriptorpb.ServiceDescriptorProto {
+	first := fp.consume("service")
+	loc := fp.startLoc(path, first)
+
+	name := fp.consumeIdent("service name")
+	fp.tokenLoc(appendPath(path, 1), name)
+	svc := &descriptorpb.ServiceDescriptorProto{Name: proto.String(name.text)}
+	scope := joinName(fp.file.GetPackage(), name.text)
+
+	open := fp.consume("{")
+	attachComments(loc, first, open)
+	for !fp.lookingAt("}") {
+		switch t := fp.peek(); {
+		case t.kind == tokenEOF:
+			fp.fail(t, "Reached end of input in service definition (missing '}').")
+		case fp.tryConsume(";"):
+		case t.is("option"):
+			fp.parseOptionStatement(appendPath(path, pathServiceOptions), scope, func() proto.Message {
+				if svc.Options == nil {
+					svc.Options = &descriptorpb.ServiceOptions{}
+				}
+				return svc.Options
+			})
+		case t.is("rpc"):
+			methodPath := appendPath(path, pathServiceMethod, int32(len(svc.Method)))
+			svc.Method = append(svc.Method, fp.parseMethod(methodPath, scope))
+		default:
+			fp.fail(t, "Expected \"rpc\".")
+		}
+	}
+	fp.endLoc(loc, fp.consume("}"))
+	return svc
+}
+
+func (fp *fileParser) parseMethod(path []int32, scope string) *descriptorpb.MethodDescriptorProto {
+	first := fp.consume("rpc")
+	loc := fp.startLoc(path, first)
+
+	name := fp.consumeIdent("method name")
+	fp.tokenLoc(appendPath(path, 1), name)
+	method := &descriptorpb.MethodDescriptorProto{Name: proto.String(name.text)}
+
+	fp.consume("(")
+	if t := fp.peek(); fp.tryConsume("stream") {
+		fp.tokenLoc(appendPath(path, 5), t)
+		method.ClientStreaming = proto.Bool(true)
+	}
+	in := fp.peek()
+	method.InputType = proto.String(fp.parseFullIdent(true))
+	fp.tokenLoc(appendPath(path, 2), in)
+	fp.consume(")")
+
+	fp.consume("returns")
+	fp.consume("(")
+	if t := fp.peek(); fp.tryConsume("stream") {
+		fp.tokenLoc(appendPath(path, 6), t)
+		method.ServerStreaming = proto.Bool(true)
+	}
+	out := fp.peek()
+	method.OutputType = proto.String(fp.parseFullIdent(true))
+	fp.tokenLoc(appendPath(path, 3), out)
+	fp.consume(")")
+
+	fp.refs[refKey{method, refInput}] = in
+	fp.refs[refKey{method, refOutput}] = out
+
+	if fp.lookingAt("{") {
+		open := fp.next()
+		attachComments(loc, first, open)
+		fp.parseMethodOptions(method, path, scope)
+		fp.endLoc(loc, fp.consume("}"))
+		return method
+	}
+
+	end := fp.consume(";")
+	attachComments(loc, first, end)
+	fp.endLoc(loc, end)
+	return method
+}
+
+func (fp *fileParser) parseMethodOptions(method *descriptorpb.MethodDescriptorProto, path []int32, scope string) {
+	for !fp.lookingAt("}") {
+		switch t := fp.peek(); {
+		case t.kind == tokenEOF:
+			fp.fail(t, "Reached end of input in method options (missing '}').")
+		case fp.tryConsume(";"):
+		case t.is("option"):
+			fp.parseOptionStatement(appendPath(path, pathMethodOptions), scope, func() proto.Message {
+				if method.Options == nil {
+					method.Options = &descriptorpb.MethodOptions{}
+				}
+				return method.Options
+			})
+		default:
+			fp.fail(t, "Expected \"option\".")
+		}
+	}
+}
@@ -0,0 +1,541 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind classifies lexical tokens of .proto source.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenInt
+	tokenFloat
+	tokenString
+	tokenSymbol
+)
+
+// token is a lexical token with its position and attached comments.
+// Lines and columns are zero-based, as in SourceCodeInfo, with tabs
+// advancing to the next multiple of eight columns.
+type token struct {
+	text     string
+	value    string // decoded value of string literals
+	leading  string
+	trailing string
+	detached []string
+
+	kind    tokenKind
+	offset  int
+	end     int
+	line    int
+	col     int
+	endLine int
+	endCol  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// lexer splits .proto source into tokens, attributing comments to them
+// following protoc's rules for leading, trailing and detached comments.
+type lexer struct {
+	name string
+	src  string
+	pos  int
+	line int
+	col  int
+}
+
+// tokenize scans the whole source.
+func tokenize(name, src string) ([]token, error) {
+	lx := &lexer{name: name, src: src}
+
+	var toks []token
+	var prev *token
+	for {
+		detached, leading, err := lx.scanGap(prev)
+		if err != nil {
+			return nil, err
+		}
+
+		tok, err := lx.scanToken()
+		if err != nil {
+			return nil, err
+		}
+		tok.detached = detached
+		tok.leading = leading
+		toks = append(toks, tok)
+
+		if tok.kind == tokenEOF {
+			return toks, nil
+		}
+		prev = &toks[len(toks)-1]
+	}
+}
+
+func (lx *lexer) errorf(format string, args ...any) error {
+	return &ParseError{
+		File:    lx.name,
+		Line:    lx.line + 1,
+		Column:  lx.col + 1,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (lx *lexer) peekByte(n int) byte {
+	if lx.pos+n < len(lx.src) {
+		return lx.src[lx.pos+n]
+	}
+	return 0
+}
+
+func (lx *lexer) atEOF() bool {
+	return lx.pos >= len(lx.src)
+}
+
+// advance consumes one byte, tracking lines and columns.
+func (lx *lexer) advance() {
+	switch lx.src[lx.pos] {
+	case '\n':
+		lx.line++
+		lx.col = 0
+	case '\t':
+		lx.col += 8 - lx.col%8
+	default:
+		lx.col++
+	}
+	lx.pos++
+}
+
+func (lx *lexer) skipSpaceNoNewline() {
+	for !lx.atEOF() {
+		switch lx.src[lx.pos] {
+		case ' ', '\t', '\r', '\v', '\f':
+			lx.advance()
+		default:
+			return
+		}
+	}
+}
+
+// commentStart identifies what starts at the current position.
+type commentStart int
+
+const (
+	noComment commentStart = iota
+	lineComment
+	blockComment
+)
+
+func (lx *lexer) tryCommentStart() commentStart {
+	if lx.peekByte(0) != '/' {
+		return noComment
+	}
+	switch lx.peekByte(1) {
+	case '/':
+		lx.advance()
+		lx.advance()
+		return lineComment
+	case '*':
+		lx.advance()
+		lx.advance()
+		return blockComment
+	default:
+		return noComment
+	}
+}
+
+// consumeLineComment returns the text after "//", including the newline.
+func (lx *lexer) consumeLineComment() string {
+	start := lx.pos
+	for !lx.atEOF() && lx.src[lx.pos] != '\n' {
+		lx.advance()
+	}
+	if !lx.atEOF() {
+		lx.advance()
+	}
+	return lx.src[start:lx.pos]
+}
+
+// consumeBlockComment returns the text between "/*" and "*/",
+// removing the leading asterisks of continuation lines.
+func (lx *lexer) consumeBlockComment() (string, error) {
+	var sb strings.Builder
+	for {
+		if lx.atEOF() {
+			return "", lx.errorf("End-of-file inside block comment.")
+		}
+
+		c := lx.src[lx.pos]
+		if c == '*' && lx.peekByte(1) == '/' {
+			lx.advance()
+			lx.advance()
+			return sb.String(), nil
+		}
+
+		lx.advance()
+		_ = sb.WriteByte(c)
+		if c == '\n' {
+			lx.skipSpaceNoNewline()
+			if lx.peekByte(0) == '*' && lx.peekByte(1) != '/' {
+				lx.advance()
+			}
+		}
+	}
+}
+
+// commentCollector accumulates comments found between two tokens.
+type commentCollector struct {
+	prev      *token
+	buf       strings.Builder
+	detached  []string
+	has       bool
+	isLine    bool
+	canAttach bool
+}
+
+func (cc *commentCollector) lineBuffer() *strings.Builder {
+	if cc.has && !cc.isLine {
+		cc.flush()
+	}
+	cc.has, cc.isLine = true, true
+	return &cc.buf
+}
+
+func (cc *commentCollector) blockBuffer() *strings.Builder {
+	if cc.has {
+		cc.flush()
+	}
+	cc.has, cc.isLine = true, false
+	return &cc.buf
+}
+
+func (cc *commentCollector) clear() {
+	cc.buf.Reset()
+	cc.has = false
+}
+
+func (cc *commentCollector) flush() {
+	if !cc.has {
+		return
+	}
+	if cc.canAttach && cc.prev != nil {
+		cc.prev.trailing += cc.buf.String()
+		cc.canAttach = false
+	} else {
+		cc.detached = append(cc.detached, cc.buf.String())
+	}
+	cc.clear()
+}
+
+// scanGap consumes whitespace and comments before the next token.
+// Comments are attached as trailing comments of prev, returned as
+// detached comments, or returned as the leading comment of the next token.
+func (lx *lexer) scanGap(prev *token) ([]string, string, error) {
+	cc := &commentCollector{prev: prev, canAttach: prev != nil}
+
+	trailingEnd := -1
+	if prev != nil {
+		done, err := lx.scanSameLine(cc, &trailingEnd)
+		if err != nil || done {
+			return nil, "", err
+		}
+	}
+
+	prevLine := -1
+	if prev != nil {
+		prevLine = prev.endLine
+	}
+
+	for {
+		lx.skipSpaceNoNewline()
+		switch lx.tryCommentStart() {
+		case lineComment:
+			_, _ = cc.lineBuffer().WriteString(lx.consumeLineComment())
+		case blockComment:
+			s, err := lx.consumeBlockComment()
+			if err != nil {
+				return nil, "", err
+			}
+			_, _ = cc.blockBuffer().WriteString(s)
+			lx.skipSpaceNoNewline()
+			if lx.peekByte(0) == '\n' {
+				lx.advance()
+			}
+		default:
+			if lx.peekByte(0) == '\n' {
+				// blank line
+				lx.advance()
+				cc.flush()
+				cc.canAttach = false
+				continue
+			}
+			return lx.finishGap(cc, prevLine, trailingEnd)
+		}
+	}
+}
+
+// scanSameLine handles comments on the same line as the previous token.
+// Returns true if the next token is on the same line.
+func (lx *lexer) scanSameLine(cc *commentCollector, trailingEnd *int) (bool, error) {
+	lx.skipSpaceNoNewline()
+	switch lx.tryCommentStart() {
+	case lineComment:
+		*trailingEnd = lx.line
+		_, _ = cc.lineBuffer().WriteString(lx.consumeLineComment())
+		cc.flush()
+	case blockComment:
+		s, err := lx.consumeBlockComment()
+		if err != nil {
+			return false, err
+		}
+		_, _ = cc.blockBuffer().WriteString(s)
+		*trailingEnd = lx.line
+		lx.skipSpaceNoNewline()
+		if lx.peekByte(0) != '\n' {
+			// next token on the same line, ambiguous
+			cc.clear()
+			return true, nil
+		}
+		lx.advance()
+		cc.flush()
+	default:
+		if lx.peekByte(0) != '\n' {
+			return true, nil
+		}
+		lx.advance()
+	}
+	return false, nil
+}
+
+func (lx *lexer) finishGap(cc *commentCollector, prevLine, trailingEnd int) ([]string, string, error) {
+	if lx.atEOF() {
+		cc.flush()
+		return cc.detached, "", nil
+	}
+
+	switch lx.src[lx.pos] {
+	case '}', ']', ')':
+		// end of scope, comments can't lead the next token
+		cc.flush()
+	}
+	if lx.line == prevLine || lx.line == trailingEnd {
+		cc.canAttach = false
+	}
+
+	var leading string
+	if cc.has {
+		leading = cc.buf.String()
+	}
+	return cc.detached, leading, nil
+}
+
+// scanToken reads the next token.
+func (lx *lexer) scanToken() (token, error) {
+	tok := token{offset: lx.pos, line: lx.line, col: lx.col}
+	if lx.atEOF() {
+		tok.kind = tokenEOF
+		tok.end, tok.endLine, tok.endCol = lx.pos, lx.line, lx.col
+		return tok, nil
+	}
+
+	c := lx.src[lx.pos]
+	var err error
+	switch {
+	case isIdentStart(c):
+		tok.kind = tokenIdent
+		for !lx.atEOF() && isIdentChar(lx.src[lx.pos]) {
+			lx.advance()
+		}
+	case isDigit(c) || (c == '.' && isDigit(lx.peekByte(1))):
+		tok.kind, err = lx.scanNumber()
+	case c == '"' || c == '\'':
+		tok.kind = tokenString
+		tok.value, err = lx.scanString(c)
+	default:
+		tok.kind = tokenSymbol
+		lx.advance()
+	}
+	if err != nil {
+		return tok, err
+	}
+
+	tok.text = lx.src[tok.offset:lx.pos]
+	tok.end, tok.endLine, tok.endCol = lx.pos, lx.line, lx.col
+	return tok, nil
+}
+
+func (lx *lexer) scanNumber() (tokenKind, error) {
+	kind := tokenInt
+	if lx.src[lx.pos] == '0' && (lx.peekByte(1) == 'x' || lx.peekByte(1) == 'X') {
+		lx.advance()
+		lx.advance()
+		if !isHexDigit(lx.peekByte(0)) {
+			return kind, lx.errorf("\"0x\" must be followed by hex digits.")
+		}
+		for !lx.atEOF() && isHexDigit(lx.src[lx.pos]) {
+			lx.advance()
+		}
+		return kind, lx.checkNumberEnd()
+	}
+
+	for !lx.atEOF() && isDigit(lx.src[lx.pos]) {
+		lx.advance()
+	}
+	if lx.peekByte(0) == '.' {
+		kind = tokenFloat
+		lx.advance()
+		for !lx.atEOF() && isDigit(lx.src[lx.pos]) {
+			lx.advance()
+		}
+	}
+	if c := lx.peekByte(0); c == 'e' || c == 'E' {
+		kind = tokenFloat
+		lx.advance()
+		if c := lx.peekByte(0); c == '+' || c == '-' {
+			lx.advance()
+		}
+		if !isDigit(lx.peekByte(0)) {
+			return kind, lx.errorf("\"e\" must be followed by exponent.")
+		}
+		for !lx.atEOF() && isDigit(lx.src[lx.pos]) {
+			lx.advance()
+		}
+	}
+	return kind, lx.checkNumberEnd()
+}
+
+func (lx *lexer) checkNumberEnd() error {
+	if c := lx.peekByte(0); isIdentChar(c) || c == '.' {
+		return lx.errorf("Need space between number and identifier.")
+	}
+	return nil
+}
+
+// scanString reads a quoted string literal and decodes its escapes.
+func (lx *lexer) scanString(quote byte) (string, error) {
+	lx.advance()
+
+	var sb strings.Builder
+	for {
+		if lx.atEOF() || lx.src[lx.pos] == '\n' {
+			return "", lx.errorf("String literals cannot cross line boundaries.")
+		}
+
+		c := lx.src[lx.pos]
+		switch {
+		case c == quote:
+			lx.advance()
+			return sb.String(), nil
+		case c == '\\':
+			lx.advance()
+			if err := lx.scanEscape(&sb); err != nil {
+				return "", err
+			}
+		default:
+			lx.advance()
+			_ = sb.WriteByte(c)
+		}
+	}
+}
+
+func (lx *lexer) scanEscape(sb *strings.Builder) error {
+	if lx.atEOF() {
+		return lx.errorf("Invalid escape sequence in string literal.")
+	}
+
+	c := lx.src[lx.pos]
+	if r, ok := simpleEscapes[c]; ok {
+		lx.advance()
+		_ = sb.WriteByte(r)
+		return nil
+	}
+
+	switch {
+	case isOctalDigit(c):
+		_ = sb.WriteByte(byte(lx.scanDigits(3, 8)))
+	case c == 'x' || c == 'X':
+		lx.advance()
+		if !isHexDigit(lx.peekByte(0)) {
+			return lx.errorf("Expected hex digits for escape sequence.")
+		}
+		_ = sb.WriteByte(byte(lx.scanDigits(2, 16)))
+	case c == 'u' || c == 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		lx.advance()
+		r := lx.scanDigits(n, 16)
+		if !utf8.ValidRune(rune(r)) {
+			return lx.errorf("Invalid unicode escape sequence.")
+		}
+		_, _ = sb.WriteRune(rune(r))
+	default:
+		return lx.errorf("Invalid escape sequence in string literal.")
+	}
+	return nil
+}
+
+// scanDigits reads up to max digits in the given base.
+func (lx *lexer) scanDigits(maxDigits, base int) uint32 {
+	var v uint32
+	for i := 0; i < maxDigits && !lx.atEOF(); i++ {
+		d, ok := digitValue(lx.src[lx.pos], base)
+		if !ok {
+			break
+		}
+		v = v*uint32(base) + d
+		lx.advance()
+	}
+	return v
+}
+
+var simpleEscapes = map[byte]byte{
+	'a': '\a', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t', 'v': '\v',
+	'\\': '\\', '?': '?', '\'': '\'', '"': '"',
+}
+
+func digitValue(c byte, base int) (uint32, bool) {
+	var d uint32
+	switch {
+	case '0' <= c && c <= '9':
+		d = uint32(c - '0')
+	case 'a' <= c && c <= 'f':
+		d = uint32(c-'a') + 10
+	case 'A' <= c && c <= 'F':
+		d = uint32(c-'A') + 10
+	default:
+		return 0, false
+	}
+	return d, d < uint32(base)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isOctalDigit(c byte) bool {
+	return '0' <= c && c <= '7'
+}
+
+func isHexDigit(c byte) bool {
+	_, ok := digitValue(c, 16)
+	return ok
+}
@@ -0,0 +1,407 @@
+package generator
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// linker resolves the names of a parsed file, interprets its options
+// and validates the result.
+type linker struct {
+	p     *Parser
+	fp    *fileParser
+	syms  *symbolTable
+	types *optionTypes
+}
+
+// link turns a parsed file into a validated and registered descriptor.
+// Standard options are interpreted before validation, as they may
+// affect it, and custom options once the file's own extensions can be
+// resolved.
+func (p *Parser) link(fp *fileParser) (pf *parsedFile, err error) {
+	defer func() {
+		if e := recoverBailout(recover()); e != nil {
+			pf, err = nil, e
+		}
+	}()
+
+	l := p.newLinker(fp)
+	l.resolveFile()
+	l.resolveDefaults()
+	l.interpretOptions(false)
+	setJSONNames(fp.file)
+
+	fd := l.build()
+	if err := p.registry.RegisterFile(fd); err != nil {
+		return nil, &ParseError{File: fp.name, Message: err.Error()}
+	}
+	if l.interpretOptions(true) {
+		fd = l.build()
+	}
+	return &parsedFile{proto: fp.file, desc: fd}, nil
+}
+
+func (p *Parser) newLinker(fp *fileParser) *linker {
+	syms := newSymbolTable()
+	syms.addFile(fp.file)
+
+	seen := make(map[string]bool)
+	for _, dep := range fp.file.Dependency {
+		p.addImportedSymbols(syms, dep, seen)
+	}
+
+	return &linker{
+		p:     p,
+		fp:    fp,
+		syms:  syms,
+		types: &optionTypes{local: dynamicpb.NewTypes(p.registry)},
+	}
+}
+
+// addImportedSymbols declares the symbols of an imported file, and of
+// the files it imports publicly.
+func (p *Parser) addImportedSymbols(syms *symbolTable, name string, seen map[string]bool) {
+	if seen[name] {
+		return
+	}
+	seen[name] = true
+
+	file := p.results[name].proto
+	syms.addFile(file)
+	for _, i := range file.PublicDependency {
+		p.addImportedSymbols(syms, file.Dependency[i], seen)
+	}
+}
+
+// build validates the file using protodesc.
+func (l *linker) build() protoreflect.FileDescriptor {
+	fd, err := protodesc.NewFile(l.fp.file, l.p.registry)
+	if err != nil {
+		panic(parseBailout{err: &ParseError{File: l.fp.name, Message: err.Error()}})
+	}
+	return fd
+}
+
+//
+// Names
+//
+
+func (l *linker) resolveFile() {
+	file := l.fp.file
+	pkg := file.GetPackage()
+
+	l.resolveMessages(pkg, file.MessageType)
+	l.resolveFields(pkg, file.Extension)
+	for _, svc := range file.Service {
+		scope := joinName(pkg, svc.GetName())
+		for _, method := range svc.Method {
+			method.InputType = proto.String("." +
+				l.resolveMessageName(scope, method.GetInputType(), l.fp.refs[refKey{method, refInput}]))
+			method.OutputType = proto.String("." +
+				l.resolveMessageName(scope, method.GetOutputType(), l.fp.refs[refKey{method, refOutput}]))
+		}
+	}
+}
+
+func (l *linker) resolveMessages(scope string, msgs []*descriptorpb.DescriptorProto) {
+	for _, msg := range msgs {
+		name := joinName(scope, msg.GetName())
+		l.resolveFields(name, msg.Field)
+		l.resolveFields(name, msg.Extension)
+		l.resolveMessages(name, msg.NestedType)
+	}
+}
+
+func (l *linker) resolveFields(scope string, fields []*descriptorpb.FieldDescriptorProto) {
+	for _, field := range fields {
+		if field.Extendee != nil {
+			full := l.resolveMessageName(scope, field.GetExtendee(), l.fp.refs[refKey{field, refExtendee}])
+			field.Extendee = proto.String("." + full)
+		}
+		if field.TypeName != nil {
+			l.resolveFieldType(scope, field)
+		}
+	}
+}
+
+func (l *linker) resolveFieldType(scope string, field *descriptorpb.FieldDescriptorProto) {
+	tok := l.fp.refs[refKey{field, refType}]
+	full, kind := l.resolveName(scope, field.GetTypeName(), tok, true)
+
+	switch {
+	case field.GetType() == TypeGroup:
+		if kind != symbolMessage {
+			l.fp.fail(tok, "%q is not a message type.", field.GetTypeName())
+		}
+	case kind == symbolMessage:
+		field.Type = TypeMessage.Enum()
+	default:
+		field.Type = TypeEnum.Enum()
+	}
+	field.TypeName = proto.String("." + full)
+}
+
+func (l *linker) resolveMessageName(scope, name string, tok token) string {
+	full, kind := l.resolveName(scope, name, tok, false)
+	if kind != symbolMessage {
+		l.fp.fail(tok, "%q is not a message type.", name)
+	}
+	return full
+}
+
+// resolveName looks up a symbol, failing with protoc's messages.
+func (l *linker) resolveName(scope, name string, tok token, typesOnly bool) (string, symbolKind) {
+	full, kind, ok := l.syms.lookup(scope, name, typesOnly)
+	switch {
+	case ok && typesOnly && !kind.isType():
+		l.fp.fail(tok, "%q is not a type.", name)
+	case !ok && full != "":
+		l.fp.fail(tok, "%q is resolved to %q, which is not defined. "+
+			"The innermost scope is searched first in name resolution. "+
+			"Consider using a leading '.'(i.e., \".%s\") to start from the outermost scope.",
+			name, full, name)
+	case !ok:
+		l.fp.fail(tok, "%q is not defined.", name)
+	}
+	return full, kind
+}
+
+// setJSONNames fills the json_name of every field, as protoc does
+// for the files given to plugins.
+func setJSONNames(file *descriptorpb.FileDescriptorProto) {
+	setFieldJSONNames(file.Extension)
+	setMessageJSONNames(file.MessageType)
+}
+
+func setMessageJSONNames(msgs []*descriptorpb.DescriptorProto) {
+	for _, msg := range msgs {
+		setFieldJSONNames(msg.Field)
+		setFieldJSONNames(msg.Extension)
+		setMessageJSONNames(msg.NestedType)
+	}
+}
+
+func setFieldJSONNames(fields []*descriptorpb.FieldDescriptorProto) {
+	for _, field := range fields {
+		if field.JsonName == nil {
+			field.JsonName = proto.String(JSONName(field.GetName()))
+		}
+	}
+}
+
+//
+// Default values
+//
+
+func (l *linker) resolveDefaults() {
+	for _, d := range l.fp.defaults {
+		d.field.DefaultValue = proto.String(l.defaultValue(d.field, d.value))
+	}
+}
+
+// defaultValue converts a default value to the form used by
+// FieldDescriptorProto.default_value.
+func (l *linker) defaultValue(field *descriptorpb.FieldDescriptorProto, v optionValue) string {
+	switch field.GetType() {
+	case TypeMessage, TypeGroup:
+		l.fp.fail(v.tok, "Messages can't have default values.")
+	case TypeEnum:
+		return l.enumDefault(field, v)
+	}
+	if field.GetLabel() == LabelRepeated {
+		l.fp.fail(v.tok, "Repeated fields can't have default values.")
+	}
+
+	kind := protoreflect.Kind(field.GetType())
+	value := l.scalarValue(kind, v, "default")
+	switch kind {
+	case protoreflect.BoolKind:
+		return strconv.FormatBool(value.Bool())
+	case protoreflect.FloatKind:
+		return formatFloat(value.Float(), 32)
+	case protoreflect.DoubleKind:
+		return formatFloat(value.Float(), 64)
+	case protoreflect.StringKind:
+		return value.String()
+	case protoreflect.BytesKind:
+		return cEscape(value.Bytes())
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return strconv.FormatUint(value.Uint(), 10)
+	default:
+		return strconv.FormatInt(value.Int(), 10)
+	}
+}
+
+func (l *linker) enumDefault(field *descriptorpb.FieldDescriptorProto, v optionValue) string {
+	if field.GetLabel() == LabelRepeated {
+		l.fp.fail(v.tok, "Repeated fields can't have default values.")
+	}
+	if v.kind != valueIdent || v.negative {
+		l.fp.fail(v.tok, "Default value for an enum field must be an identifier.")
+	}
+
+	enum := strings.TrimPrefix(field.GetTypeName(), ".")
+	if !core.SliceContains(l.syms.enums[enum], v.str) {
+		l.fp.fail(v.tok, "Enum type %q has no value named %q.", enum, v.str)
+	}
+	return v.str
+}
+
+// cEscape escapes binary data as protoc does for bytes default values.
+func cEscape(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		if c == '\'' {
+			_, _ = sb.WriteString(`\'`)
+		} else {
+			writeEscapedByte(&sb, c)
+		}
+	}
+	return sb.String()
+}
+
+//
+// Scalar values
+//
+
+// scalarValue converts a literal to a value of the given scalar kind.
+func (l *linker) scalarValue(kind protoreflect.Kind, v optionValue, what string) protoreflect.Value {
+	switch kind {
+	case protoreflect.BoolKind:
+		if v.kind != valueIdent || v.negative || (v.str != "true" && v.str != "false") {
+			l.fp.fail(v.tok, "Value must be \"true\" or \"false\" for boolean option %q.", what)
+		}
+		return protoreflect.ValueOfBool(v.str == "true")
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(l.intValue(v, math.MinInt32, math.MaxInt32, what)))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(l.intValue(v, math.MinInt64, math.MaxInt64, what))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(l.uintValue(v, math.MaxUint32, what)))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(l.uintValue(v, math.MaxUint64, what))
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(l.floatValue(v, what)))
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(l.floatValue(v, what))
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(l.stringValue(v, what))
+	default:
+		return protoreflect.ValueOfBytes([]byte(l.stringValue(v, what)))
+	}
+}
+
+func (l *linker) intValue(v optionValue, minValue, maxValue int64, what string) int64 {
+	if v.kind != valueInt {
+		l.fp.fail(v.tok, "Value must be integer for int option %q.", what)
+	}
+
+	u, err := strconv.ParseUint(v.str, 0, 64)
+	switch {
+	case err != nil:
+	case v.negative && u <= uint64(-(minValue+1))+1:
+		return -int64(u)
+	case !v.negative && u <= uint64(maxValue):
+		return int64(u)
+	}
+	l.fp.fail(v.tok, "Value out of range for int option %q.", what)
+	return 0
+}
+
+func (l *linker) uintValue(v optionValue, maxValue uint64, what string) uint64 {
+	if v.kind != valueInt || v.negative {
+		l.fp.fail(v.tok, "Value must be non-negative integer for uint option %q.", what)
+	}
+
+	u, err := strconv.ParseUint(v.str, 0, 64)
+	if err != nil || u > maxValue {
+		l.fp.fail(v.tok, "Value out of range for uint option %q.", what)
+	}
+	return u
+}
+
+func (l *linker) floatValue(v optionValue, what string) float64 {
+	var f float64
+	switch {
+	case v.kind == valueInt:
+		u, err := strconv.ParseUint(v.str, 0, 64)
+		if err != nil {
+			l.fp.fail(v.tok, "Value out of range for float option %q.", what)
+		}
+		f = float64(u)
+	case v.kind == valueFloat:
+		var err error
+		f, err = strconv.ParseFloat(v.str, 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			l.fp.fail(v.tok, "Invalid float value for option %q.", what)
+		}
+	case v.kind == valueIdent && v.str == "inf":
+		f = math.Inf(1)
+	case v.kind == valueIdent && v.str == "nan":
+		f = math.NaN()
+	default:
+		l.fp.fail(v.tok, "Value must be number for float option %q.", what)
+	}
+
+	if v.negative {
+		f = -f
+	}
+	return f
+}
+
+func (l *linker) stringValue(v optionValue, what string) string {
+	if v.kind != valueString {
+		l.fp.fail(v.tok, "Value must be quoted string for string option %q.", what)
+	}
+	return v.str
+}
+
+//
+// Type resolution for option values
+//
+
+// optionTypes resolves extensions and messages used by options,
+// preferring the types linked into the binary so generated extensions
+// keep their Go types.
+type optionTypes struct {
+	local *dynamicpb.Types
+}
+
+func (t *optionTypes) FindExtensionByName(name protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	if xt, err := protoregistry.GlobalTypes.FindExtensionByName(name); err == nil {
+		return xt, nil
+	}
+	return t.local.FindExtensionByName(name)
+}
+
+func (t *optionTypes) FindExtensionByNumber(message protoreflect.FullName,
+	field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	if xt, err := protoregistry.GlobalTypes.FindExtensionByNumber(message, field); err == nil {
+		return xt, nil
+	}
+	return t.local.FindExtensionByNumber(message, field)
+}
+
+func (t *optionTypes) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(name); err == nil {
+		return mt, nil
+	}
+	return t.local.FindMessageByName(name)
+}
+
+func (t *optionTypes) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	if mt, err := protoregistry.GlobalTypes.FindMessageByURL(url); err == nil {
+		return mt, nil
+	}
+	return t.local.FindMessageByURL(url)
+}
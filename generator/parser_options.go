@@ -0,0 +1,322 @@
+package generator
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// valueKind classifies the literal assigned to an option.
+type valueKind int
+
+const (
+	valueIdent valueKind = iota
+	valueInt
+	valueFloat
+	valueString
+	valueAggregate
+)
+
+// optionValue is an uninterpreted option value. str holds the decoded
+// value of strings, the raw text of numbers and identifiers, and the
+// text-format source between the braces of aggregates.
+type optionValue struct {
+	str      string
+	tok      token
+	kind     valueKind
+	negative bool
+}
+
+// optionNamePart is one component of an option name, extension names
+// are written in parentheses.
+type optionNamePart struct {
+	name      string
+	tok       token
+	extension bool
+}
+
+// parsedOption is an option assignment as written in the source.
+type parsedOption struct {
+	name  []optionNamePart
+	value optionValue
+	tok   token
+}
+
+// isSimple tells if the option is the given plain name, without
+// dots or extensions.
+func (o parsedOption) isSimple(name string) bool {
+	return len(o.name) == 1 && !o.name[0].extension && o.name[0].name == name
+}
+
+// String returns the option name as written in the source.
+func (o parsedOption) String() string {
+	parts := make([]string, len(o.name))
+	for i, part := range o.name {
+		if part.extension {
+			parts[i] = "(" + part.name + ")"
+		} else {
+			parts[i] = part.name
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// pendingOption is an option waiting to be interpreted once names
+// can be resolved.
+type pendingOption struct {
+	target func() proto.Message
+	loc    *descriptorpb.SourceCodeInfo_Location
+	scope  string
+	parsedOption
+}
+
+// parseOptionStatement reads an "option name = value;" statement.
+func (fp *fileParser) parseOptionStatement(path []int32, scope string, target func() proto.Message) {
+	first := fp.consume("option")
+	loc := fp.startLoc(path, first)
+
+	o := fp.parseOption()
+	fp.addOption(o, scope, target)
+	fp.options[len(fp.options)-1].loc = loc
+
+	end := fp.consume(";")
+	attachComments(loc, first, end)
+	fp.endLoc(loc, end)
+}
+
+// parseOptionList reads a bracketed list of options.
+func (fp *fileParser) parseOptionList() []parsedOption {
+	fp.consume("[")
+
+	var out []parsedOption
+	for {
+		out = append(out, fp.parseOption())
+		if !fp.tryConsume(",") {
+			break
+		}
+	}
+
+	fp.consume("]")
+	return out
+}
+
+func (fp *fileParser) parseOption() parsedOption {
+	o := parsedOption{tok: fp.peek()}
+	for {
+		o.name = append(o.name, fp.parseOptionNamePart())
+		if !fp.tryConsume(".") {
+			break
+		}
+	}
+
+	fp.consume("=")
+	o.value = fp.parseOptionValue()
+	return o
+}
+
+func (fp *fileParser) parseOptionNamePart() optionNamePart {
+	t := fp.peek()
+	if !fp.tryConsume("(") {
+		return optionNamePart{name: fp.consumeIdent("identifier").text, tok: t}
+	}
+
+	name := fp.parseFullIdent(true)
+	fp.consume(")")
+	return optionNamePart{name: name, tok: t, extension: true}
+}
+
+func (fp *fileParser) parseOptionValue() optionValue {
+	t := fp.peek()
+	switch {
+	case t.is("{"):
+		return fp.parseAggregate()
+	case t.kind == tokenString:
+		s, _ := fp.consumeString("string")
+		return optionValue{str: s, tok: t, kind: valueString}
+	}
+
+	negative := fp.tryConsume("-")
+	v := fp.next()
+	out := optionValue{str: v.text, tok: t, negative: negative}
+	switch v.kind {
+	case tokenIdent:
+		out.kind = valueIdent
+	case tokenInt:
+		out.kind = valueInt
+	case tokenFloat:
+		out.kind = valueFloat
+	default:
+		fp.fail(v, "Expected option value.")
+	}
+	return out
+}
+
+// parseAggregate captures the text-format source of a message value.
+func (fp *fileParser) parseAggregate() optionValue {
+	open := fp.consume("{")
+	depth := 1
+	for depth > 0 {
+		switch t := fp.next(); {
+		case t.kind == tokenEOF:
+			fp.fail(t, "Unexpected end of stream while parsing aggregate value.")
+		case t.is("{"):
+			depth++
+		case t.is("}"):
+			depth--
+		}
+	}
+
+	return optionValue{
+		str:  fp.src[open.end:fp.last().offset],
+		tok:  open,
+		kind: valueAggregate,
+	}
+}
+
+// addOption queues an option for interpretation.
+func (fp *fileParser) addOption(o parsedOption, scope string, target func() proto.Message) {
+	fp.options = append(fp.options, &pendingOption{
+		target:       target,
+		scope:        scope,
+		parsedOption: o,
+	})
+}
+
+//
+// Interpretation
+//
+
+// interpretOptions applies the pending options whose name starts
+// with an extension, or those which don't, and tells if any was.
+func (l *linker) interpretOptions(extensions bool) bool {
+	var found bool
+	for _, o := range l.fp.options {
+		if o.name[0].extension == extensions {
+			l.interpretOption(o)
+			found = true
+		}
+	}
+	return found
+}
+
+func (l *linker) interpretOption(o *pendingOption) {
+	m := o.target().ProtoReflect()
+
+	var path []int32
+	if o.loc != nil {
+		path = o.loc.Path
+	}
+
+	last := len(o.name) - 1
+	for _, part := range o.name[:last] {
+		fd := l.optionField(m, part, o.scope)
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			l.fp.fail(part.tok, "Option %q is an atomic type, not a message.", o.String())
+		}
+		path = append(path, int32(fd.Number()))
+		m = m.Mutable(fd).Message()
+	}
+
+	fd := l.optionField(m, o.name[last], o.scope)
+	path = append(path, int32(fd.Number()))
+	if index, ok := l.setOption(m, fd, o); ok {
+		path = append(path, index)
+	}
+
+	if o.loc != nil {
+		o.loc.Path = path
+	}
+}
+
+// optionField finds the field or extension named by part.
+func (l *linker) optionField(m protoreflect.Message, part optionNamePart,
+	scope string) protoreflect.FieldDescriptor {
+	md := m.Descriptor()
+	if !part.extension {
+		fd := md.Fields().ByName(protoreflect.Name(part.name))
+		switch {
+		case fd == nil:
+			l.fp.fail(part.tok, "Option %q unknown. Ensure that your proto definition file "+
+				"imports the proto which defines the option.", part.name)
+		case fd.Name() == "uninterpreted_option":
+			l.fp.fail(part.tok, "Option %q can't be set directly.", part.name)
+		case fd.Name() == "map_entry" && md.FullName() == "google.protobuf.MessageOptions":
+			l.fp.fail(part.tok, "map_entry should not be set explicitly. Use map<KeyType, ValueType> instead.")
+		}
+		return fd
+	}
+
+	full, kind, ok := l.syms.lookup(scope, part.name, false)
+	if !ok || kind != symbolExtension {
+		l.fp.fail(part.tok, "Option \"(%s)\" unknown. Ensure that your proto definition file "+
+			"imports the proto which defines the option.", part.name)
+	}
+	xt, err := l.types.FindExtensionByName(protoreflect.FullName(full))
+	if err != nil {
+		l.fp.fail(part.tok, "Option \"(%s)\" can't be used within the file declaring it.", part.name)
+	}
+
+	xd := xt.TypeDescriptor()
+	if xd.ContainingMessage().FullName() != md.FullName() {
+		l.fp.fail(part.tok, "Option field \"(%s)\" is not a field or extension of %q.", full, md.FullName())
+	}
+	return xd
+}
+
+// setOption assigns the value of an option, returning the index
+// of the new element for repeated fields.
+func (l *linker) setOption(m protoreflect.Message, fd protoreflect.FieldDescriptor,
+	o *pendingOption) (int32, bool) {
+	switch {
+	case fd.IsMap():
+		l.fp.fail(o.value.tok, "Map option %q can only be set as part of an aggregate value.", o.String())
+	case fd.IsList():
+		list := m.Mutable(fd).List()
+		index := int32(list.Len())
+		list.Append(l.optionValue(fd, o, list.NewElement))
+		return index, true
+	case m.Has(fd):
+		l.fp.fail(o.tok, "Option %q was already set.", o.String())
+	}
+
+	m.Set(fd, l.optionValue(fd, o, func() protoreflect.Value {
+		return m.NewField(fd)
+	}))
+	return 0, false
+}
+
+// optionValue converts the value of an option to the type of its field.
+func (l *linker) optionValue(fd protoreflect.FieldDescriptor, o *pendingOption,
+	newValue func() protoreflect.Value) protoreflect.Value {
+	v, what := o.value, o.String()
+
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if v.kind != valueIdent || v.negative {
+			l.fp.fail(v.tok, "Value must be identifier for enum-valued option %q.", what)
+		}
+		ev := fd.Enum().Values().ByName(protoreflect.Name(v.str))
+		if ev == nil {
+			l.fp.fail(v.tok, "Enum type %q has no value named %q for option %q.",
+				fd.Enum().FullName(), v.str, what)
+		}
+		return protoreflect.ValueOfEnum(ev.Number())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if v.kind != valueAggregate {
+			l.fp.fail(v.tok, "Option %q is a message. To set the entire message, use syntax like "+
+				"\"%s = { <proto text format> }\". To set fields within it, use syntax like \"%s.foo = value\".",
+				what, what, what)
+		}
+		value := newValue()
+		opts := prototext.UnmarshalOptions{Resolver: l.types}
+		if err := opts.Unmarshal([]byte(v.str), value.Message().Interface()); err != nil {
+			l.fp.fail(v.tok, "Error while parsing option value for %q: %s", what, err)
+		}
+		return value
+	default:
+		return l.scalarValue(fd.Kind(), v, what)
+	}
+}
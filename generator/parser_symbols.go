@@ -0,0 +1,139 @@
+package generator
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// symbolKind classifies the symbols visible to a file.
+type symbolKind int
+
+const (
+	symbolPackage symbolKind = iota + 1
+	symbolMessage
+	symbolEnum
+	symbolEnumValue
+	symbolField
+	symbolOneof
+	symbolExtension
+	symbolService
+	symbolMethod
+)
+
+// isType tells if the symbol can be used as a field type.
+func (k symbolKind) isType() bool {
+	return k == symbolMessage || k == symbolEnum
+}
+
+// isAggregate tells if the symbol can contain other symbols.
+func (k symbolKind) isAggregate() bool {
+	switch k {
+	case symbolPackage, symbolMessage, symbolEnum, symbolService:
+		return true
+	default:
+		return false
+	}
+}
+
+// symbolTable indexes the full names, without leading dot, declared
+// by a file and the files it can see.
+type symbolTable struct {
+	kinds map[string]symbolKind
+	enums map[string][]string
+}
+
+func newSymbolTable() *symbolTable {
+	return &symbolTable{
+		kinds: make(map[string]symbolKind),
+		enums: make(map[string][]string),
+	}
+}
+
+func (st *symbolTable) addFile(file *descriptorpb.FileDescriptorProto) {
+	pkg := file.GetPackage()
+	for s := pkg; s != ""; s = parentScope(s) {
+		st.kinds[s] = symbolPackage
+	}
+
+	st.addMessages(pkg, file.MessageType)
+	st.addEnums(pkg, file.EnumType)
+	st.addFields(pkg, file.Extension, symbolExtension)
+	for _, svc := range file.Service {
+		name := joinName(pkg, svc.GetName())
+		st.kinds[name] = symbolService
+		for _, method := range svc.Method {
+			st.kinds[joinName(name, method.GetName())] = symbolMethod
+		}
+	}
+}
+
+func (st *symbolTable) addMessages(scope string, msgs []*descriptorpb.DescriptorProto) {
+	for _, msg := range msgs {
+		name := joinName(scope, msg.GetName())
+		st.kinds[name] = symbolMessage
+		st.addFields(name, msg.Field, symbolField)
+		st.addFields(name, msg.Extension, symbolExtension)
+		for _, oneof := range msg.OneofDecl {
+			st.kinds[joinName(name, oneof.GetName())] = symbolOneof
+		}
+		st.addMessages(name, msg.NestedType)
+		st.addEnums(name, msg.EnumType)
+	}
+}
+
+func (st *symbolTable) addFields(scope string, fields []*descriptorpb.FieldDescriptorProto, kind symbolKind) {
+	for _, field := range fields {
+		st.kinds[joinName(scope, field.GetName())] = kind
+	}
+}
+
+// addEnums declares enums and their values, which are siblings of
+// the enum type rather than children.
+func (st *symbolTable) addEnums(scope string, enums []*descriptorpb.EnumDescriptorProto) {
+	for _, enum := range enums {
+		name := joinName(scope, enum.GetName())
+		st.kinds[name] = symbolEnum
+
+		values := make([]string, 0, len(enum.Value))
+		for _, v := range enum.Value {
+			st.kinds[joinName(scope, v.GetName())] = symbolEnumValue
+			values = append(values, v.GetName())
+		}
+		st.enums[name] = values
+	}
+}
+
+// lookup resolves a name relative to scope using protoc's rules,
+// searching from the innermost scope outwards. When only the first
+// component of a dotted name is found, the attempted full name is
+// returned with ok false.
+func (st *symbolTable) lookup(scope, name string, typesOnly bool) (full string, kind symbolKind, ok bool) {
+	if strings.HasPrefix(name, ".") {
+		full = name[1:]
+		kind, ok = st.kinds[full]
+		return full, kind, ok
+	}
+
+	first, compound := name, false
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		first, compound = name[:i], true
+	}
+
+	for s := scope; ; s = parentScope(s) {
+		kind, found := st.kinds[joinName(s, first)]
+		switch {
+		case !found:
+		case compound && kind.isAggregate():
+			full = joinName(s, name)
+			kind, ok = st.kinds[full]
+			return full, kind, ok
+		case !compound && (!typesOnly || kind.isType()):
+			return joinName(s, first), kind, true
+		}
+
+		if s == "" {
+			return "", 0, false
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Compile-time verification that test case types implement TestCase interface
+var _ core.TestCase = parseRoundTripTestCase{}
+var _ core.TestCase = parseErrorTestCase{}
+var _ core.TestCase = parseDefaultTestCase{}
+
+type parseRoundTripTestCase struct {
+	name string
+	src  string
+}
+
+func newParseRoundTripTestCase(name, src string) parseRoundTripTestCase {
+	return parseRoundTripTestCase{
+		name: name,
+		src:  src,
+	}
+}
+
+func (tc parseRoundTripTestCase) Name() string {
+	return tc.name
+}
+
+func (tc parseRoundTripTestCase) Test(t *testing.T) {
+	t.Helper()
+	file, err := ParseProto(tc.name+".proto", tc.src)
+	core.AssertMustNoError(t, err, "ParseProto")
+
+	out, err := PrintFile(file)
+	core.AssertMustNoError(t, err, "PrintFile")
+	core.AssertEqual(t, tc.src, out, "round trip")
+}
+
+func TestParseProtoRoundTrip(t *testing.T) {
+	testCases := []parseRoundTripTestCase{
+		newParseRoundTripTestCase("proto3", printerProto3Expected),
+		newParseRoundTripTestCase("proto2", printerProto2Expected),
+		newParseRoundTripTestCase("editions", printerEditionsExpected),
+		newParseRoundTripTestCase("comments", printerCommentsExpected),
+	}
+
+	core.RunTestCases(t, testCases)
+}
+
+func TestParseProto(t *testing.T) {
+	file, err := ParseProto("user.proto", `syntax = "proto3";
+package example.v1;
+
+message User {
+  string display_name = 1;
+  optional int32 age = 2;
+  map<string, Status> roles = 3;
+
+  enum Status {
+    STATUS_UNSPECIFIED = 0;
+  }
+}
+`)
+	core.AssertMustNoError(t, err, "ParseProto")
+	core.AssertEqual(t, "user.proto", file.GetName(), "name")
+	core.AssertEqual(t, "example.v1", file.GetPackage(), "package")
+	core.AssertEqual(t, SyntaxProto3, file.GetSyntax(), "syntax")
+
+	user := file.MessageType[0]
+	core.AssertEqual(t, 3, len(user.Field), "fields")
+	core.AssertEqual(t, "displayName", user.Field[0].GetJsonName(), "json_name")
+
+	age := user.Field[1]
+	core.AssertTrue(t, age.GetProto3Optional(), "proto3_optional")
+	core.AssertEqual(t, "_age", user.OneofDecl[age.GetOneofIndex()].GetName(), "synthetic oneof")
+
+	roles := user.Field[2]
+	core.AssertEqual(t, ".example.v1.User.RolesEntry", roles.GetTypeName(), "map entry type")
+	core.AssertEqual(t, LabelRepeated, roles.GetLabel(), "map label")
+
+	entry := user.NestedType[0]
+	core.AssertTrue(t, entry.GetOptions().GetMapEntry(), "map_entry")
+	core.AssertEqual(t, TypeEnum, entry.Field[1].GetType(), "map value type")
+	core.AssertEqual(t, ".example.v1.User.Status", entry.Field[1].GetTypeName(), "map value type name")
+}
+
+func TestParseProtoSourceCodeInfo(t *testing.T) {
+	file, err := ParseProto("c.proto", `// detached
+
+// Leading.
+syntax = "proto3";
+
+/* Block
+ * comment */
+message Ping {
+  uint32 seq = 1; // trailing
+}
+`)
+	core.AssertMustNoError(t, err, "ParseProto")
+
+	si := newSourceInfo(file)
+	syntax := si.Get([]int32{pathFileSyntax})
+	core.AssertMustNotNil(t, syntax, "syntax location")
+	core.AssertSliceEqual(t, []string{" detached\n"}, syntax.LeadingDetachedComments, "detached")
+	core.AssertEqual(t, " Leading.\n", syntax.GetLeadingComments(), "leading")
+	core.AssertSliceEqual(t, []int32{3, 0, 18}, syntax.Span, "syntax span")
+
+	msg := si.Get([]int32{pathFileMessage, 0})
+	core.AssertMustNotNil(t, msg, "message location")
+	core.AssertEqual(t, " Block\n comment ", msg.GetLeadingComments(), "block comment")
+	core.AssertSliceEqual(t, []int32{7, 0, 9, 1}, msg.Span, "message span")
+
+	field := si.Get([]int32{pathFileMessage, 0, pathMessageField, 0})
+	core.AssertMustNotNil(t, field, "field location")
+	core.AssertEqual(t, " trailing\n", field.GetTrailingComments(), "trailing")
+
+	p := &Parser{
+		Files:              map[string]string{"c.proto": `syntax = "proto3";`},
+		SkipSourceCodeInfo: true,
+	}
+	files, err := p.Parse("c.proto")
+	core.AssertMustNoError(t, err, "Parse")
+	core.AssertNil(t, files[0].SourceCodeInfo, "SourceCodeInfo")
+}
+
+func TestParserImports(t *testing.T) {
+	p := &Parser{Files: map[string]string{
+		"base.proto": `syntax = "proto3";
+package base;
+message Base {}
+`,
+		"reexport.proto": `syntax = "proto3";
+import public "base.proto";
+`,
+		"main.proto": `syntax = "proto3";
+package main;
+import "reexport.proto";
+import "google/protobuf/timestamp.proto";
+message Main {
+  base.Base base = 1;
+  google.protobuf.Timestamp at = 2;
+}
+`,
+	}}
+
+	files, err := p.ParseWithImports("main.proto")
+	core.AssertMustNoError(t, err, "ParseWithImports")
+
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.GetName()
+	}
+	core.AssertSliceEqual(t, []string{
+		"base.proto", "reexport.proto", "google/protobuf/timestamp.proto", "main.proto",
+	}, names, "files")
+
+	main := files[len(files)-1].MessageType[0]
+	core.AssertEqual(t, ".base.Base", main.Field[0].GetTypeName(), "public import")
+	core.AssertEqual(t, ".google.protobuf.Timestamp", main.Field[1].GetTypeName(), "well-known import")
+}
+
+func TestParserImportCycle(t *testing.T) {
+	p := &Parser{Files: map[string]string{
+		"a.proto": `syntax = "proto3"; import "b.proto";`,
+		"b.proto": `syntax = "proto3"; import "a.proto";`,
+	}}
+
+	_, err := p.Parse("a.proto")
+	core.AssertError(t, err, "Parse")
+	core.AssertEqual(t, "a.proto: import cycle: a.proto -> b.proto -> a.proto", err.Error(), "error")
+}
+
+func TestParseProtoCustomOptions(t *testing.T) {
+	p := &Parser{Files: map[string]string{
+		"opts.proto": `syntax = "proto3";
+package opts;
+import "google/protobuf/descriptor.proto";
+message Rule {
+  int32 min = 1;
+  repeated string tags = 2;
+}
+extend google.protobuf.FieldOptions {
+  Rule rule = 50000;
+  string label = 50001;
+}
+extend google.protobuf.MessageOptions {
+  repeated int32 ids = 50002;
+}
+`,
+		"main.proto": `syntax = "proto3";
+package main;
+import "opts.proto";
+message Item {
+  option (opts.ids) = 1;
+  option (opts.ids) = 2;
+  int32 n = 1 [deprecated = true, (opts.rule) = { min: 3 tags: "a" }, (opts.label) = "x"];
+}
+`,
+	}}
+
+	files, err := p.Parse("main.proto")
+	core.AssertMustNoError(t, err, "Parse")
+
+	item := files[0].MessageType[0]
+	core.AssertSliceEqual(t, []optionEntry{
+		{name: "(opts.ids)", value: "1", number: 50002},
+		{name: "(opts.ids)", value: "2", number: 50002},
+	}, optionEntries(item.GetOptions()), "message options")
+	core.AssertSliceEqual(t, []optionEntry{
+		{name: "deprecated", value: "true", number: 3},
+		{name: "(opts.rule).min", value: "3", number: 50000},
+		{name: "(opts.rule).tags", value: `"a"`, number: 50000},
+		{name: "(opts.label)", value: `"x"`, number: 50001},
+	}, optionEntries(item.Field[0].GetOptions()), "field options")
+
+	// option statements point at the interpreted option
+	loc := newSourceInfo(files[0]).Get([]int32{pathFileMessage, 0, pathMessageOptions, 50002, 1})
+	core.AssertNotNil(t, loc, "option location")
+}
+
+func TestParseProtoEditions(t *testing.T) {
+	file, err := ParseProto("ed.proto", `edition = "2023";
+package ed;
+import "google/protobuf/go_features.proto";
+option features.field_presence = IMPLICIT;
+option features.(pb.go).legacy_unmarshal_json_enum = false;
+message Node {
+  string name = 1 [features.field_presence = EXPLICIT];
+  Node child = 2 [features.message_encoding = DELIMITED];
+}
+`)
+	core.AssertMustNoError(t, err, "ParseProto")
+	core.AssertEqual(t, SyntaxEditions, file.GetSyntax(), "syntax")
+	core.AssertEqual(t, descriptorpb.Edition_EDITION_2023, file.GetEdition(), "edition")
+	core.AssertEqual(t, descriptorpb.FeatureSet_IMPLICIT,
+		file.GetOptions().GetFeatures().GetFieldPresence(), "file features")
+
+	node := file.MessageType[0]
+	core.AssertEqual(t, descriptorpb.FeatureSet_EXPLICIT,
+		node.Field[0].GetOptions().GetFeatures().GetFieldPresence(), "field features")
+	core.AssertEqual(t, TypeMessage, node.Field[1].GetType(), "delimited field type")
+}
+
+type parseDefaultTestCase struct {
+	name     string
+	decl     string
+	expected string
+}
+
+func newParseDefaultTestCase(name, decl, expected string) parseDefaultTestCase {
+	return parseDefaultTestCase{
+		name:     name,
+		decl:     decl,
+		expected: expected,
+	}
+}
+
+func (tc parseDefaultTestCase) Name() string {
+	return tc.name
+}
+
+func (tc parseDefaultTestCase) Test(t *testing.T) {
+	t.Helper()
+	file, err := ParseProto("d.proto", `syntax = "proto2";
+message M {
+  enum E { ONE = 1; TWO = 2; }
+  `+tc.decl+`
+}
+`)
+	core.AssertMustNoError(t, err, "ParseProto")
+	field := file.MessageType[0].Field[0]
+	core.AssertEqual(t, tc.expected, field.GetDefaultValue(), "default_value")
+}
+
+func TestParseProtoDefaults(t *testing.T) {
+	testCases := []parseDefaultTestCase{
+		newParseDefaultTestCase("int", "optional int32 f = 1 [default = -0x10];", "-16"),
+		newParseDefaultTestCase("uint", "optional uint64 f = 1 [default = 18446744073709551615];",
+			"18446744073709551615"),
+		newParseDefaultTestCase("bool", "optional bool f = 1 [default = true];", "true"),
+		newParseDefaultTestCase("float", "optional float f = 1 [default = 0.1];", "0.1"),
+		newParseDefaultTestCase("double int", "optional double f = 1 [default = 10];", "10"),
+		newParseDefaultTestCase("inf", "optional double f = 1 [default = -inf];", "-inf"),
+		newParseDefaultTestCase("string", `optional string f = 1 [default = "a\"b" "c"];`, `a"bc`),
+		newParseDefaultTestCase("bytes", `optional bytes f = 1 [default = "a'\001"];`, `a\'\001`),
+		newParseDefaultTestCase("enum", "optional E f = 1 [default = TWO];", "TWO"),
+	}
+
+	core.RunTestCases(t, testCases)
+}
+
+type parseErrorTestCase struct {
+	name     string
+	src      string
+	expected string
+}
+
+func newParseErrorTestCase(name, src, expected string) parseErrorTestCase {
+	return parseErrorTestCase{
+		name:     name,
+		src:      src,
+		expected: expected,
+	}
+}
+
+func (tc parseErrorTestCase) Name() string {
+	return tc.name
+}
+
+func (tc parseErrorTestCase) Test(t *testing.T) {
+	t.Helper()
+	file, err := ParseProto("e.proto", tc.src)
+	core.AssertNil(t, file, "file")
+
+	_, ok := err.(*ParseError)
+	core.AssertMustTrue(t, ok, "ParseError")
+	core.AssertEqual(t, tc.expected, err.Error(), "error")
+}
+
+func TestParseProtoErrors(t *testing.T) {
+	testCases := []parseErrorTestCase{
+		newParseErrorTestCase("missing semicolon",
+			"syntax = \"proto3\";\nmessage A {\n  int32 a = 1\n}\n",
+			`e.proto:4:1: Expected ";".`),
+		newParseErrorTestCase("undefined type",
+			`syntax = "proto3"; message A { B b = 1; }`,
+			`e.proto:1:32: "B" is not defined.`),
+		newParseErrorTestCase("partially resolved",
+			`syntax = "proto3"; message A { message B {} } message C { A.X x = 1; }`,
+			`e.proto:1:59: "A.X" is resolved to "A.X", which is not defined. `+
+				`The innermost scope is searched first in name resolution. `+
+				`Consider using a leading '.'(i.e., ".A.X") to start from the outermost scope.`),
+		newParseErrorTestCase("proto3 default",
+			`syntax = "proto3"; message A { int32 a = 1 [default = 1]; }`,
+			`e.proto:1:45: Explicit default values are not allowed in proto3.`),
+		newParseErrorTestCase("proto3 required",
+			`syntax = "proto3"; message A { required int32 a = 1; }`,
+			`e.proto:1:32: Required fields are not allowed in proto3.`),
+		newParseErrorTestCase("proto2 missing label",
+			`syntax = "proto2"; message A { int32 a = 1; }`,
+			`e.proto:1:32: Expected "required", "optional", or "repeated".`),
+		newParseErrorTestCase("unknown option",
+			`syntax = "proto3"; message A { int32 a = 1 [foo = 1]; }`,
+			`e.proto:1:45: Option "foo" unknown. `+
+				`Ensure that your proto definition file imports the proto which defines the option.`),
+		newParseErrorTestCase("bad default",
+			`syntax = "proto2"; message A { optional int32 a = 1 [default = 1.5]; }`,
+			`e.proto:1:64: Value must be integer for int option "default".`),
+		newParseErrorTestCase("unknown enum default",
+			`syntax = "proto2"; enum E { A = 1; } message M { optional E e = 1 [default = B]; }`,
+			`e.proto:1:78: Enum type "E" has no value named "B".`),
+		newParseErrorTestCase("map_entry",
+			`syntax = "proto3"; message A { option map_entry = true; }`,
+			`e.proto:1:39: map_entry should not be set explicitly. Use map<KeyType, ValueType> instead.`),
+		newParseErrorTestCase("unterminated string",
+			"syntax = \"proto3\";\noption java_package = \"x;\n",
+			`e.proto:2:26: String literals cannot cross line boundaries.`),
+		newParseErrorTestCase("missing import",
+			`syntax = "proto3"; import "missing.proto";`,
+			`missing.proto: file not found`),
+	}
+
+	core.RunTestCases(t, testCases)
+}
+
+func TestParseProtoValidation(t *testing.T) {
+	_, err := ParseProto("e.proto", `syntax = "proto3"; enum E { A = 1; }`)
+
+	pe, ok := err.(*ParseError)
+	core.AssertMustTrue(t, ok, "ParseError")
+	core.AssertEqual(t, "e.proto", pe.File, "file")
+	core.AssertContains(t, pe.Message, "zero number for the first value", "message")
+}
+
+func TestWellKnownFile(t *testing.T) {
+	core.AssertTrue(t, IsWellKnownFile("google/protobuf/any.proto"), "any.proto")
+	core.AssertFalse(t, IsWellKnownFile("example/any.proto"), "example")
+
+	file, ok := WellKnownFile("google/protobuf/duration.proto")
+	core.AssertMustTrue(t, ok, "duration.proto")
+	core.AssertEqual(t, "google.protobuf", file.GetPackage(), "package")
+	core.AssertEqual(t, "Duration", file.MessageType[0].GetName(), "message")
+}
@@ -0,0 +1,100 @@
+package generator
+
+import (
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Field numbers used to build SourceCodeInfo paths.
+// These mirror the field numbers declared in google/protobuf/descriptor.proto.
+const (
+	// FileDescriptorProto
+	pathFilePackage    = 2
+	pathFileDependency = 3
+	pathFileMessage    = 4
+	pathFileEnum       = 5
+	pathFileService    = 6
+	pathFileExtension  = 7
+	pathFileOptions    = 8
+	pathFileSyntax     = 12
+	pathFileEdition    = 14
+
+	// DescriptorProto
+	pathMessageField          = 2
+	pathMessageNested         = 3
+	pathMessageEnum           = 4
+	pathMessageExtensionRange = 5
+	pathMessageExtension      = 6
+	pathMessageOptions        = 7
+	pathMessageOneof          = 8
+	pathMessageReservedRange  = 9
+	pathMessageReservedName   = 10
+
+	// EnumDescriptorProto
+	pathEnumValue         = 2
+	pathEnumOptions       = 3
+	pathEnumReservedRange = 4
+	pathEnumReservedName  = 5
+
+	// ServiceDescriptorProto
+	pathServiceMethod  = 2
+	pathServiceOptions = 3
+
+	// Options of leaf descriptors
+	pathFieldOptions     = 8
+	pathOneofOptions     = 2
+	pathEnumValueOptions = 3
+	pathMethodOptions    = 4
+)
+
+// appendPath returns a new path with the given elements appended,
+// never sharing the backing array with the original.
+func appendPath(path []int32, elems ...int32) []int32 {
+	out := make([]int32, 0, len(path)+len(elems))
+	out = append(out, path...)
+	return append(out, elems...)
+}
+
+// pathKey converts a SourceCodeInfo path into a map key.
+func pathKey(path []int32) string {
+	var sb strings.Builder
+	for i, v := range path {
+		if i > 0 {
+			_ = sb.WriteByte('.')
+		}
+		_, _ = sb.WriteString(strconv.Itoa(int(v)))
+	}
+	return sb.String()
+}
+
+// sourceInfo indexes the locations of a file's SourceCodeInfo by path.
+type sourceInfo map[string]*descriptorpb.SourceCodeInfo_Location
+
+// newSourceInfo builds a location index for the given file.
+// Returns nil if the file has no SourceCodeInfo.
+func newSourceInfo(file *descriptorpb.FileDescriptorProto) sourceInfo {
+	locs := file.GetSourceCodeInfo().GetLocation()
+	if len(locs) == 0 {
+		return nil
+	}
+
+	si := make(sourceInfo, len(locs))
+	for _, loc := range locs {
+		key := pathKey(loc.Path)
+		if _, ok := si[key]; !ok {
+			// first location wins, later ones describe sub-spans
+			si[key] = loc
+		}
+	}
+	return si
+}
+
+// Get returns the location recorded for the given path, if any.
+func (si sourceInfo) Get(path []int32) *descriptorpb.SourceCodeInfo_Location {
+	if si == nil {
+		return nil
+	}
+	return si[pathKey(path)]
+}
@@ -0,0 +1,823 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Syntax names as they appear in FileDescriptorProto.Syntax.
+const (
+	SyntaxProto2   = "proto2"
+	SyntaxProto3   = "proto3"
+	SyntaxEditions = "editions"
+)
+
+// Upper bounds printed as "max" in ranges.
+const (
+	maxFieldNumber = 536870911
+	maxEnumNumber  = 2147483647
+)
+
+// FileSyntax returns the syntax of a file descriptor.
+// An empty Syntax field means proto2, as protoc omits it for proto2 files.
+func FileSyntax(file *descriptorpb.FileDescriptorProto) string {
+	if s := file.GetSyntax(); s != "" {
+		return s
+	}
+	return SyntaxProto2
+}
+
+// PrintFile renders a file descriptor back into .proto source text.
+//
+// The output is deterministic and uses two-space indentation, making it
+// suitable for golden tests. Map fields are reconstructed from their
+// map-entry messages, proto2 groups are printed inline, synthetic oneofs
+// are printed as proto3 optional fields, and comments are restored from
+// SourceCodeInfo when present.
+//
+// Options are printed from the known fields of the options messages.
+// Custom options are only printed when their extension types are linked
+// into the binary, as unresolved extensions are kept as unknown bytes.
+//
+// Type names are shortened only when no symbol of the file or its
+// imports can shadow the shorter form. Imported files are taken from
+// deps, or from the bundled well-known files. When one is missing, names
+// that one of its symbols could shadow are printed fully qualified.
+func PrintFile(file *descriptorpb.FileDescriptorProto, deps ...*descriptorpb.FileDescriptorProto) (string, error) {
+	if file == nil {
+		return "", core.Wrap(core.ErrInvalid, "nil file descriptor")
+	}
+
+	p := newProtoPrinter(file, deps...)
+	p.printFile()
+	return p.buf.String(), nil
+}
+
+// protoPrinter holds the state used while rendering a single file.
+type protoPrinter struct {
+	buf    strings.Builder
+	file   *descriptorpb.FileDescriptorProto
+	si     sourceInfo
+	known  map[string]bool
+	inline map[*descriptorpb.DescriptorProto]bool
+	syntax string
+	pkg    string
+	// partial is set when imported files are missing, so their
+	// symbols are unknown.
+	partial bool
+
+	depth        int
+	pendingBlank bool
+	blockStarted bool
+}
+
+func newProtoPrinter(file *descriptorpb.FileDescriptorProto,
+	deps ...*descriptorpb.FileDescriptorProto) *protoPrinter {
+	p := &protoPrinter{
+		file:   file,
+		si:     newSourceInfo(file),
+		known:  make(map[string]bool),
+		inline: make(map[*descriptorpb.DescriptorProto]bool),
+		syntax: FileSyntax(file),
+		pkg:    file.GetPackage(),
+	}
+	p.collectNames(file)
+	p.collectImports(deps)
+	p.collectInline(p.pkg, file.MessageType, file.Extension)
+	return p
+}
+
+//
+// Output helpers
+//
+
+// line writes a single indented line, preceded by a blank line
+// if one was requested since the last output in the current block.
+func (p *protoPrinter) line(s string) {
+	if p.pendingBlank && p.blockStarted {
+		_ = p.buf.WriteByte('\n')
+	}
+	p.pendingBlank = false
+	p.blockStarted = true
+
+	if s != "" {
+		_, _ = p.buf.WriteString(strings.Repeat("  ", p.depth))
+		_, _ = p.buf.WriteString(s)
+	}
+	_ = p.buf.WriteByte('\n')
+}
+
+// separate requests a blank line before the next output of the current block.
+func (p *protoPrinter) separate() {
+	p.pendingBlank = true
+}
+
+// open writes the header of a block and increases the indentation.
+func (p *protoPrinter) open(path []int32, header string) {
+	trailing := p.comments(path)
+	p.line(header + " {")
+	p.depth++
+	p.blockStarted = false
+	p.pendingBlank = false
+
+	for _, l := range commentLines(trailing) {
+		p.line("//" + l)
+	}
+}
+
+// close ends the current block.
+func (p *protoPrinter) close() {
+	p.depth--
+	p.pendingBlank = false
+	p.line("}")
+}
+
+// stmt writes a single-line statement with its comments.
+func (p *protoPrinter) stmt(path []int32, s string) {
+	trailing := p.comments(path)
+	lines := commentLines(trailing)
+	switch len(lines) {
+	case 0:
+		p.line(s)
+	case 1:
+		p.line(s + " //" + lines[0])
+	default:
+		p.line(s)
+		for _, l := range lines {
+			p.line("//" + l)
+		}
+	}
+}
+
+// comments writes the detached and leading comments recorded for
+// the given path, and returns the trailing comment for the caller.
+func (p *protoPrinter) comments(path []int32) string {
+	loc := p.si.Get(path)
+	if loc == nil || path == nil {
+		return ""
+	}
+
+	for _, detached := range loc.LeadingDetachedComments {
+		for _, l := range commentLines(detached) {
+			p.line("//" + l)
+		}
+		p.separate()
+	}
+	for _, l := range commentLines(loc.GetLeadingComments()) {
+		p.line("//" + l)
+	}
+	return loc.GetTrailingComments()
+}
+
+// commentLines splits a SourceCodeInfo comment into its lines.
+func commentLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+//
+// File
+//
+
+func (p *protoPrinter) printFile() {
+	p.printSyntax()
+	p.printPackage()
+	p.printImports()
+
+	p.separate()
+	p.printOptionStatements(appendPath(nil, pathFileOptions), p.file.Options)
+
+	c := &protoContainer{
+		nested:    p.file.MessageType,
+		path:      nil,
+		nestedTag: pathFileMessage,
+		scope:     p.pkg,
+	}
+	for i, msg := range p.file.MessageType {
+		if !p.inline[msg] {
+			p.separate()
+			p.printMessage(appendPath(nil, pathFileMessage, int32(i)), p.pkg, msg)
+		}
+	}
+	for i, enum := range p.file.EnumType {
+		p.separate()
+		p.printEnum(appendPath(nil, pathFileEnum, int32(i)), enum)
+	}
+	for i, svc := range p.file.Service {
+		p.separate()
+		p.printService(appendPath(nil, pathFileService, int32(i)), svc)
+	}
+
+	p.printExtensions(c, appendPath(nil, pathFileExtension), p.file.Extension)
+}
+
+func (p *protoPrinter) printSyntax() {
+	if p.syntax == SyntaxEditions {
+		edition := strings.TrimPrefix(p.file.GetEdition().String(), "EDITION_")
+		p.stmt(appendPath(nil, pathFileEdition), "edition = "+quoteProtoString(edition)+";")
+		return
+	}
+	p.stmt(appendPath(nil, pathFileSyntax), "syntax = "+quoteProtoString(p.syntax)+";")
+}
+
+func (p *protoPrinter) printPackage() {
+	if p.pkg != "" {
+		p.separate()
+		p.stmt(appendPath(nil, pathFilePackage), "package "+p.pkg+";")
+	}
+}
+
+func (p *protoPrinter) printImports() {
+	public := make(map[int32]bool)
+	for _, i := range p.file.PublicDependency {
+		public[i] = true
+	}
+	weak := make(map[int32]bool)
+	for _, i := range p.file.WeakDependency {
+		weak[i] = true
+	}
+
+	p.separate()
+	for i, dep := range p.file.Dependency {
+		var modifier string
+		switch {
+		case public[int32(i)]:
+			modifier = "public "
+		case weak[int32(i)]:
+			modifier = "weak "
+		}
+		p.stmt(appendPath(nil, pathFileDependency, int32(i)),
+			"import "+modifier+quoteProtoString(dep)+";")
+	}
+}
+
+//
+// Messages
+//
+
+// protoContainer describes a scope that can hold fields referring to
+// nested types, i.e. a message or the file itself for extensions.
+type protoContainer struct {
+	msg       *descriptorpb.DescriptorProto
+	nested    []*descriptorpb.DescriptorProto
+	printed   map[int32]bool
+	path      []int32
+	scope     string
+	nestedTag int32
+}
+
+func (p *protoPrinter) printMessage(path []int32, parentScope string,
+	msg *descriptorpb.DescriptorProto) {
+	p.open(path, "message "+msg.GetName())
+	p.printMessageBody(path, joinName(parentScope, msg.GetName()), msg)
+	p.close()
+}
+
+func (p *protoPrinter) printMessageBody(path []int32, scope string, msg *descriptorpb.DescriptorProto) {
+	c := &protoContainer{
+		msg:       msg,
+		nested:    msg.NestedType,
+		printed:   make(map[int32]bool),
+		path:      path,
+		scope:     scope,
+		nestedTag: pathMessageNested,
+	}
+
+	p.printOptionStatements(appendPath(path, pathMessageOptions), msg.Options)
+	p.separate()
+	p.printMessageFields(c)
+
+	for i, nested := range msg.NestedType {
+		if !p.inline[nested] {
+			p.separate()
+			p.printMessage(appendPath(path, pathMessageNested, int32(i)), scope, nested)
+		}
+	}
+	for i, enum := range msg.EnumType {
+		p.separate()
+		p.printEnum(appendPath(path, pathMessageEnum, int32(i)), enum)
+	}
+
+	p.printExtensions(c, appendPath(path, pathMessageExtension), msg.Extension)
+	p.printExtensionRanges(path, msg.ExtensionRange)
+	p.printReserved(appendPath(path, pathMessageReservedRange),
+		appendPath(path, pathMessageReservedName),
+		messageReservedRanges(msg.ReservedRange), msg.ReservedName, maxFieldNumber)
+}
+
+func (p *protoPrinter) printMessageFields(c *protoContainer) {
+	for i, field := range c.msg.Field {
+		if isRealOneofMember(field) {
+			p.printOneof(c, field.GetOneofIndex())
+			continue
+		}
+		p.printField(c, appendPath(c.path, pathMessageField, int32(i)), field, true)
+	}
+}
+
+func (p *protoPrinter) printOneof(c *protoContainer, index int32) {
+	if c.printed[index] {
+		return
+	}
+	c.printed[index] = true
+
+	var oneof *descriptorpb.OneofDescriptorProto
+	if int(index) < len(c.msg.OneofDecl) {
+		oneof = c.msg.OneofDecl[index]
+	}
+
+	oneofPath := appendPath(c.path, pathMessageOneof, index)
+	p.open(oneofPath, "oneof "+oneof.GetName())
+	p.printOptionStatements(appendPath(oneofPath, pathOneofOptions), oneof.GetOptions())
+	p.separate()
+	for i, field := range c.msg.Field {
+		if isRealOneofMember(field) && field.GetOneofIndex() == index {
+			p.printField(c, appendPath(c.path, pathMessageField, int32(i)), field, false)
+		}
+	}
+	p.close()
+}
+
+// isRealOneofMember tells if the field belongs to a oneof declared
+// in the source, excluding the synthetic oneofs of proto3 optional fields.
+func isRealOneofMember(field *descriptorpb.FieldDescriptorProto) bool {
+	return field.OneofIndex != nil && !field.GetProto3Optional()
+}
+
+// printField prints a field of a message or extend block.
+// withLabel is false for oneof members, which never carry a label.
+func (p *protoPrinter) printField(c *protoContainer, path []int32,
+	field *descriptorpb.FieldDescriptorProto, withLabel bool) {
+	if entry, _ := p.mapEntry(c, field); entry != nil {
+		p.printMapField(c.scope, path, field, entry)
+		return
+	}
+
+	if group, idx := p.groupType(c, field); group != nil {
+		p.printGroupField(c, path, field, idx, withLabel)
+		return
+	}
+
+	var sb strings.Builder
+	if withLabel {
+		_, _ = sb.WriteString(p.fieldLabel(field))
+	}
+	_, _ = sb.WriteString(p.fieldType(c.scope, field))
+	_, _ = fmt.Fprintf(&sb, " %s = %d", field.GetName(), field.GetNumber())
+	_, _ = sb.WriteString(formatOptionList(p.fieldOptions(field)))
+	_ = sb.WriteByte(';')
+
+	p.stmt(path, sb.String())
+}
+
+func (p *protoPrinter) printMapField(scope string, path []int32,
+	field *descriptorpb.FieldDescriptorProto, entry *descriptorpb.DescriptorProto) {
+	var key, value *descriptorpb.FieldDescriptorProto
+	for _, f := range entry.Field {
+		switch f.GetNumber() {
+		case 1:
+			key = f
+		case 2:
+			value = f
+		}
+	}
+
+	entryScope := joinName(scope, entry.GetName())
+	s := fmt.Sprintf("map<%s, %s> %s = %d%s;",
+		p.fieldType(entryScope, key), p.fieldType(entryScope, value),
+		field.GetName(), field.GetNumber(),
+		formatOptionList(p.fieldOptions(field)))
+	p.stmt(path, s)
+}
+
+func (p *protoPrinter) printGroupField(c *protoContainer, path []int32,
+	field *descriptorpb.FieldDescriptorProto, idx int, withLabel bool) {
+	group := c.nested[idx]
+
+	var sb strings.Builder
+	if withLabel {
+		_, _ = sb.WriteString(p.fieldLabel(field))
+	}
+	_, _ = fmt.Fprintf(&sb, "group %s = %d", group.GetName(), field.GetNumber())
+	_, _ = sb.WriteString(formatOptionList(p.fieldOptions(field)))
+
+	p.open(path, sb.String())
+	p.printMessageBody(appendPath(c.path, c.nestedTag, int32(idx)),
+		joinName(c.scope, group.GetName()), group)
+	p.close()
+}
+
+// mapEntry returns the map-entry message a field refers to, if it is a map field.
+func (p *protoPrinter) mapEntry(c *protoContainer,
+	field *descriptorpb.FieldDescriptorProto) (*descriptorpb.DescriptorProto, int) {
+	if !IsRepeatedField(field) || field.GetType() != TypeMessage {
+		return nil, -1
+	}
+
+	nested, idx := findNestedType(c.scope, c.nested, field.GetTypeName())
+	if nested == nil || !nested.GetOptions().GetMapEntry() {
+		return nil, -1
+	}
+	return nested, idx
+}
+
+// groupType returns the nested message declared by a proto2 group field.
+func (p *protoPrinter) groupType(c *protoContainer,
+	field *descriptorpb.FieldDescriptorProto) (*descriptorpb.DescriptorProto, int) {
+	if p.syntax == SyntaxEditions || field.GetType() != TypeGroup {
+		return nil, -1
+	}
+
+	nested, idx := findNestedType(c.scope, c.nested, field.GetTypeName())
+	if nested == nil || strings.ToLower(nested.GetName()) != field.GetName() {
+		return nil, -1
+	}
+	return nested, idx
+}
+
+// findNestedType finds the message a fully-qualified type name refers to
+// among the types declared directly within the given scope.
+func findNestedType(scope string, nested []*descriptorpb.DescriptorProto,
+	typeName string) (*descriptorpb.DescriptorProto, int) {
+	prefix := "." + scope + "."
+	if scope == "" {
+		prefix = "."
+	}
+
+	name, ok := strings.CutPrefix(typeName, prefix)
+	if !ok || strings.Contains(name, ".") {
+		return nil, -1
+	}
+
+	for i, msg := range nested {
+		if msg.GetName() == name {
+			return msg, i
+		}
+	}
+	return nil, -1
+}
+
+// collectInline marks nested types printed inline, map entries and
+// proto2 groups, so they aren't printed again as standalone messages.
+func (p *protoPrinter) collectInline(scope string, nested []*descriptorpb.DescriptorProto,
+	fields []*descriptorpb.FieldDescriptorProto) {
+	c := &protoContainer{nested: nested, scope: scope}
+	for _, field := range fields {
+		if entry, _ := p.mapEntry(c, field); entry != nil {
+			p.inline[entry] = true
+		} else if group, _ := p.groupType(c, field); group != nil {
+			p.inline[group] = true
+		}
+	}
+
+	for _, msg := range nested {
+		fields := append(msg.Field[:len(msg.Field):len(msg.Field)], msg.Extension...)
+		p.collectInline(joinName(scope, msg.GetName()), msg.NestedType, fields)
+	}
+}
+
+func (p *protoPrinter) fieldLabel(field *descriptorpb.FieldDescriptorProto) string {
+	switch {
+	case field.GetLabel() == LabelRepeated:
+		return "repeated "
+	case p.syntax == SyntaxEditions:
+		return ""
+	case field.GetLabel() == LabelRequired:
+		return "required "
+	case p.syntax == SyntaxProto3 && !field.GetProto3Optional():
+		return ""
+	default:
+		return "optional "
+	}
+}
+
+func (p *protoPrinter) fieldType(scope string, field *descriptorpb.FieldDescriptorProto) string {
+	switch field.GetType() {
+	case TypeMessage, TypeEnum, TypeGroup:
+		return p.typeRef(scope, field.GetTypeName())
+	default:
+		if field.Type == nil && field.TypeName != nil {
+			// unresolved reference
+			return p.typeRef(scope, field.GetTypeName())
+		}
+		return scalarTypeName(field.GetType())
+	}
+}
+
+// scalarTypeName returns the .proto keyword of a scalar field type.
+func scalarTypeName(t descriptorpb.FieldDescriptorProto_Type) string {
+	return strings.ToLower(strings.TrimPrefix(t.String(), "TYPE_"))
+}
+
+//
+// Extensions and ranges
+//
+
+// printExtensions prints extension fields grouped in extend blocks
+// by consecutive extendee.
+func (p *protoPrinter) printExtensions(c *protoContainer, path []int32,
+	fields []*descriptorpb.FieldDescriptorProto) {
+	var current string
+	for i, field := range fields {
+		extendee := field.GetExtendee()
+		if i == 0 || extendee != current {
+			if i > 0 {
+				p.close()
+			}
+			current = extendee
+			p.separate()
+			p.open(nil, "extend "+p.typeRef(c.scope, extendee))
+		}
+		p.printField(c, appendPath(path, int32(i)), field, true)
+	}
+	if len(fields) > 0 {
+		p.close()
+	}
+}
+
+func (p *protoPrinter) printExtensionRanges(path []int32,
+	ranges []*descriptorpb.DescriptorProto_ExtensionRange) {
+	p.separate()
+	for i, r := range ranges {
+		s := "extensions " + formatRange(r.GetStart(), r.GetEnd()-1, maxFieldNumber) +
+			formatOptionList(optionEntries(r.GetOptions())) + ";"
+		p.stmt(appendPath(path, pathMessageExtensionRange, int32(i)), s)
+	}
+}
+
+// numberRange is an inclusive range of field or enum numbers.
+type numberRange struct {
+	start, end int32
+}
+
+func messageReservedRanges(ranges []*descriptorpb.DescriptorProto_ReservedRange) []numberRange {
+	out := make([]numberRange, len(ranges))
+	for i, r := range ranges {
+		// end is exclusive for messages
+		out[i] = numberRange{start: r.GetStart(), end: r.GetEnd() - 1}
+	}
+	return out
+}
+
+func enumReservedRanges(ranges []*descriptorpb.EnumDescriptorProto_EnumReservedRange) []numberRange {
+	out := make([]numberRange, len(ranges))
+	for i, r := range ranges {
+		// end is inclusive for enums
+		out[i] = numberRange{start: r.GetStart(), end: r.GetEnd()}
+	}
+	return out
+}
+
+func (p *protoPrinter) printReserved(rangesPath, namesPath []int32,
+	ranges []numberRange, names []string, maxValue int32) {
+	p.separate()
+	if len(ranges) > 0 {
+		parts := make([]string, len(ranges))
+		for i, r := range ranges {
+			parts[i] = formatRange(r.start, r.end, maxValue)
+		}
+		p.stmt(rangesPath, "reserved "+strings.Join(parts, ", ")+";")
+	}
+
+	if len(names) > 0 {
+		parts := make([]string, len(names))
+		for i, name := range names {
+			if p.syntax == SyntaxEditions {
+				parts[i] = name
+			} else {
+				parts[i] = quoteProtoString(name)
+			}
+		}
+		p.stmt(namesPath, "reserved "+strings.Join(parts, ", ")+";")
+	}
+}
+
+func formatRange(start, end, maxValue int32) string {
+	switch {
+	case start == end:
+		return fmt.Sprint(start)
+	case end == maxValue:
+		return fmt.Sprintf("%d to max", start)
+	default:
+		return fmt.Sprintf("%d to %d", start, end)
+	}
+}
+
+//
+// Enums
+//
+
+func (p *protoPrinter) printEnum(path []int32, enum *descriptorpb.EnumDescriptorProto) {
+	p.open(path, "enum "+enum.GetName())
+	p.printOptionStatements(appendPath(path, pathEnumOptions), enum.Options)
+
+	p.separate()
+	for i, v := range enum.Value {
+		s := fmt.Sprintf("%s = %d%s;", v.GetName(), v.GetNumber(),
+			formatOptionList(optionEntries(v.GetOptions())))
+		p.stmt(appendPath(path, pathEnumValue, int32(i)), s)
+	}
+
+	p.printReserved(appendPath(path, pathEnumReservedRange),
+		appendPath(path, pathEnumReservedName),
+		enumReservedRanges(enum.ReservedRange), enum.ReservedName, maxEnumNumber)
+	p.close()
+}
+
+//
+// Services
+//
+
+func (p *protoPrinter) printService(path []int32, svc *descriptorpb.ServiceDescriptorProto) {
+	scope := joinName(p.pkg, svc.GetName())
+
+	p.open(path, "service "+svc.GetName())
+	p.printOptionStatements(appendPath(path, pathServiceOptions), svc.Options)
+
+	p.separate()
+	for i, method := range svc.Method {
+		p.printMethod(appendPath(path, pathServiceMethod, int32(i)), scope, method)
+	}
+	p.close()
+}
+
+func (p *protoPrinter) printMethod(path []int32, scope string, method *descriptorpb.MethodDescriptorProto) {
+	var in, out string
+	if method.GetClientStreaming() {
+		in = "stream "
+	}
+	if method.GetServerStreaming() {
+		out = "stream "
+	}
+
+	s := fmt.Sprintf("rpc %s(%s%s) returns (%s%s)", method.GetName(),
+		in, p.typeRef(scope, method.GetInputType()),
+		out, p.typeRef(scope, method.GetOutputType()))
+
+	entries := optionEntries(method.GetOptions())
+	if len(entries) == 0 {
+		p.stmt(path, s+";")
+		return
+	}
+
+	p.open(path, s)
+	p.printOptionStatements(appendPath(path, pathMethodOptions), method.GetOptions())
+	p.close()
+}
+
+//
+// Names
+//
+
+// joinName joins a scope and a name into a full name without leading dot.
+func joinName(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// parentScope returns the enclosing scope of a full name.
+func parentScope(scope string) string {
+	if i := strings.LastIndexByte(scope, '.'); i >= 0 {
+		return scope[:i]
+	}
+	return ""
+}
+
+// collectNames records every symbol declared by a file, used to
+// decide how much of a type name can be omitted safely.
+func (p *protoPrinter) collectNames(file *descriptorpb.FileDescriptorProto) {
+	pkg := file.GetPackage()
+	for s := pkg; s != ""; s = parentScope(s) {
+		p.known[s] = true
+	}
+
+	for _, msg := range file.MessageType {
+		p.collectMessageNames(pkg, msg)
+	}
+	for _, enum := range file.EnumType {
+		p.collectEnumNames(pkg, enum)
+	}
+	for _, svc := range file.Service {
+		p.known[joinName(pkg, svc.GetName())] = true
+	}
+	for _, ext := range file.Extension {
+		p.known[joinName(pkg, ext.GetName())] = true
+	}
+}
+
+// collectImports records the symbols visible through the imports of
+// the file, directly or via public imports, marking the printer partial
+// when an imported file isn't found.
+func (p *protoPrinter) collectImports(deps []*descriptorpb.FileDescriptorProto) {
+	files := make(map[string]*descriptorpb.FileDescriptorProto, len(deps))
+	for _, dep := range deps {
+		files[dep.GetName()] = dep
+	}
+
+	seen := make(map[string]bool)
+	pending := slices.Clone(p.file.Dependency)
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		dep, ok := files[name]
+		if !ok {
+			dep, ok = WellKnownFile(name)
+		}
+		if !ok {
+			p.partial = true
+			continue
+		}
+
+		p.collectNames(dep)
+		for _, i := range dep.PublicDependency {
+			if i >= 0 && int(i) < len(dep.Dependency) {
+				pending = append(pending, dep.Dependency[i])
+			}
+		}
+	}
+}
+
+func (p *protoPrinter) collectMessageNames(scope string, msg *descriptorpb.DescriptorProto) {
+	name := joinName(scope, msg.GetName())
+	p.known[name] = true
+
+	for _, field := range msg.Field {
+		p.known[joinName(name, field.GetName())] = true
+	}
+	for _, ext := range msg.Extension {
+		p.known[joinName(name, ext.GetName())] = true
+	}
+	for _, oneof := range msg.OneofDecl {
+		p.known[joinName(name, oneof.GetName())] = true
+	}
+	for _, nested := range msg.NestedType {
+		p.collectMessageNames(name, nested)
+	}
+	for _, enum := range msg.EnumType {
+		p.collectEnumNames(name, enum)
+	}
+}
+
+func (p *protoPrinter) collectEnumNames(scope string, enum *descriptorpb.EnumDescriptorProto) {
+	p.known[joinName(scope, enum.GetName())] = true
+	for _, v := range enum.Value {
+		// enum values are siblings of their enum
+		p.known[joinName(scope, v.GetName())] = true
+	}
+}
+
+// typeRef returns the shortest form of a fully-qualified type name
+// that resolves back to the same type when used within scope.
+func (p *protoPrinter) typeRef(scope, typeName string) string {
+	full, ok := strings.CutPrefix(typeName, ".")
+	if !ok {
+		// relative or empty names are printed as given
+		return typeName
+	}
+
+	parts := strings.Split(full, ".")
+	for k := 1; k <= len(parts); k++ {
+		candidate := parts[len(parts)-k:]
+		if p.resolvesTo(scope, candidate, full) {
+			return strings.Join(candidate, ".")
+		}
+	}
+	return typeName
+}
+
+// resolvesTo simulates protoc's scoped name lookup, checking that the
+// candidate name used within scope refers to the given full name.
+func (p *protoPrinter) resolvesTo(scope string, candidate []string, full string) bool {
+	for s := scope; ; s = parentScope(s) {
+		first := joinName(s, candidate[0])
+		if p.known[first] || isNamePrefix(first, full) {
+			return joinName(s, strings.Join(candidate, ".")) == full
+		}
+		if p.partial && s != "" && isNamePrefix(s, p.pkg) {
+			// a missing import may declare it within the package
+			return false
+		}
+		if s == "" {
+			return false
+		}
+	}
+}
+
+// isNamePrefix tells if prefix is the full name itself or one of its scopes.
+func isNamePrefix(prefix, full string) bool {
+	return full == prefix || strings.HasPrefix(full, prefix+".")
+}
@@ -0,0 +1,324 @@
+package generator
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// optionEntry is a single flattened option assignment.
+type optionEntry struct {
+	name   string
+	value  string
+	number int32
+}
+
+// printOptionStatements prints the options message as option statements.
+func (p *protoPrinter) printOptionStatements(path []int32, opts proto.Message) {
+	for _, e := range optionEntries(opts) {
+		var stmtPath []int32
+		if e.number > 0 {
+			stmtPath = appendPath(path, e.number)
+		}
+		p.stmt(stmtPath, "option "+e.name+" = "+e.value+";")
+	}
+}
+
+// fieldOptions returns the bracketed options of a field, including
+// the default value and any non-default json_name.
+func (p *protoPrinter) fieldOptions(field *descriptorpb.FieldDescriptorProto) []optionEntry {
+	var out []optionEntry
+	if field.DefaultValue != nil {
+		out = append(out, optionEntry{
+			name:  "default",
+			value: formatDefaultValue(field),
+		})
+	}
+	if HasCustomJSONName(field) {
+		out = append(out, optionEntry{
+			name:  "json_name",
+			value: quoteProtoString(field.GetJsonName()),
+		})
+	}
+	return append(out, optionEntries(field.GetOptions())...)
+}
+
+// formatDefaultValue formats the default_value of a field as it's
+// written in .proto source.
+func formatDefaultValue(field *descriptorpb.FieldDescriptorProto) string {
+	value := field.GetDefaultValue()
+	switch field.GetType() {
+	case TypeString:
+		return quoteProtoString(value)
+	case TypeBytes:
+		// already C-escaped by protoc
+		return `"` + value + `"`
+	default:
+		return value
+	}
+}
+
+// formatOptionList formats entries as a bracketed field option list.
+func formatOptionList(entries []optionEntry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		parts[i] = e.name + " = " + e.value
+	}
+	return " [" + strings.Join(parts, ", ") + "]"
+}
+
+// optionEntries flattens an options message into option assignments,
+// ordered by field number with extensions last.
+func optionEntries(opts proto.Message) []optionEntry {
+	if opts == nil {
+		return nil
+	}
+	m := opts.ProtoReflect()
+	if !m.IsValid() {
+		return nil
+	}
+
+	var out []optionEntry
+	for _, fd := range sortedFields(m) {
+		if fd.Name() == "uninterpreted_option" {
+			continue
+		}
+		entries := flattenOption(optionName(fd), fd, m.Get(fd))
+		for i := range entries {
+			entries[i].number = int32(fd.Number())
+		}
+		out = append(out, entries...)
+	}
+	return out
+}
+
+// sortedFields returns the populated fields of a message in a stable order.
+func sortedFields(m protoreflect.Message) []protoreflect.FieldDescriptor {
+	var fields []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fields = append(fields, fd)
+		return true
+	})
+
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i], fields[j]
+		if a.IsExtension() != b.IsExtension() {
+			return !a.IsExtension()
+		}
+		if a.Number() != b.Number() {
+			return a.Number() < b.Number()
+		}
+		return a.FullName() < b.FullName()
+	})
+	return fields
+}
+
+func optionName(fd protoreflect.FieldDescriptor) string {
+	if fd.IsExtension() {
+		return "(" + string(fd.FullName()) + ")"
+	}
+	return string(fd.Name())
+}
+
+// flattenOption converts a single option value into assignments.
+// Singular message values are flattened into dotted names.
+func flattenOption(name string, fd protoreflect.FieldDescriptor, v protoreflect.Value) []optionEntry {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		out := make([]optionEntry, 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			out = append(out, optionEntry{name: name, value: formatOptionValue(fd, list.Get(i))})
+		}
+		return out
+	case fd.IsMap():
+		return []optionEntry{{name: name, value: formatAggregate(fd, v)}}
+	case fd.Message() != nil:
+		return flattenMessageOption(name, v.Message())
+	default:
+		return []optionEntry{{name: name, value: formatOptionValue(fd, v)}}
+	}
+}
+
+func flattenMessageOption(name string, m protoreflect.Message) []optionEntry {
+	fields := sortedFields(m)
+	if len(fields) == 0 {
+		return []optionEntry{{name: name, value: "{}"}}
+	}
+
+	var out []optionEntry
+	for _, fd := range fields {
+		out = append(out, flattenOption(name+"."+optionName(fd), fd, m.Get(fd))...)
+	}
+	return out
+}
+
+// formatOptionValue formats a scalar, enum or message value.
+func formatOptionValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return strconv.FormatBool(v.Bool())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return strconv.FormatInt(int64(v.Enum()), 10)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return strconv.FormatInt(v.Int(), 10)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return strconv.FormatUint(v.Uint(), 10)
+	case protoreflect.FloatKind:
+		return formatFloat(v.Float(), 32)
+	case protoreflect.DoubleKind:
+		return formatFloat(v.Float(), 64)
+	case protoreflect.StringKind:
+		return quoteProtoString(v.String())
+	case protoreflect.BytesKind:
+		return quoteProtoBytes(v.Bytes())
+	default:
+		return formatMessageLiteral(v.Message())
+	}
+}
+
+func formatFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, bitSize)
+	}
+}
+
+// formatAggregate formats a map value in text format.
+func formatAggregate(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	m := v.Map()
+	keys := make([]protoreflect.MapKey, 0, m.Len())
+	m.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool {
+		return formatOptionValue(fd.MapKey(), keys[i].Value()) <
+			formatOptionValue(fd.MapKey(), keys[j].Value())
+	})
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = "{ key: " + formatOptionValue(fd.MapKey(), k.Value()) +
+			" value: " + formatOptionValue(fd.MapValue(), m.Get(k)) + " }"
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// formatMessageLiteral formats a message value in text format.
+func formatMessageLiteral(m protoreflect.Message) string {
+	fields := sortedFields(m)
+	if len(fields) == 0 {
+		return "{}"
+	}
+
+	parts := make([]string, 0, len(fields))
+	for _, fd := range fields {
+		name := string(fd.Name())
+		if fd.IsExtension() {
+			name = "[" + string(fd.FullName()) + "]"
+		}
+
+		v := m.Get(fd)
+		switch {
+		case fd.IsList():
+			list := v.List()
+			values := make([]string, list.Len())
+			for i := range values {
+				values[i] = formatOptionValue(fd, list.Get(i))
+			}
+			parts = append(parts, name+": ["+strings.Join(values, ", ")+"]")
+		case fd.IsMap():
+			parts = append(parts, name+": "+formatAggregate(fd, v))
+		default:
+			parts = append(parts, name+": "+formatOptionValue(fd, v))
+		}
+	}
+	return "{ " + strings.Join(parts, " ") + " }"
+}
+
+// quoteProtoString quotes a string using .proto escaping rules.
+// Printable UTF-8 is kept as is, anything else is octal-escaped.
+func quoteProtoString(s string) string {
+	var sb strings.Builder
+	_ = sb.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			writeEscapedByte(&sb, s[i])
+		case r < utf8.RuneSelf:
+			writeEscapedByte(&sb, byte(r))
+		case unicode.IsPrint(r):
+			_, _ = sb.WriteString(s[i : i+size])
+		default:
+			for j := i; j < i+size; j++ {
+				writeOctal(&sb, s[j])
+			}
+		}
+		i += size
+	}
+	_ = sb.WriteByte('"')
+	return sb.String()
+}
+
+// quoteProtoBytes quotes binary data using .proto escaping rules.
+func quoteProtoBytes(b []byte) string {
+	var sb strings.Builder
+	_ = sb.WriteByte('"')
+	for _, c := range b {
+		writeEscapedByte(&sb, c)
+	}
+	_ = sb.WriteByte('"')
+	return sb.String()
+}
+
+func writeEscapedByte(sb *strings.Builder, c byte) {
+	switch c {
+	case '\n':
+		_, _ = sb.WriteString(`\n`)
+	case '\r':
+		_, _ = sb.WriteString(`\r`)
+	case '\t':
+		_, _ = sb.WriteString(`\t`)
+	case '"':
+		_, _ = sb.WriteString(`\"`)
+	case '\\':
+		_, _ = sb.WriteString(`\\`)
+	default:
+		if c < 0x20 || c >= 0x7f {
+			writeOctal(sb, c)
+		} else {
+			_ = sb.WriteByte(c)
+		}
+	}
+}
+
+func writeOctal(sb *strings.Builder, c byte) {
+	_ = sb.WriteByte('\\')
+	_ = sb.WriteByte('0' + (c >> 6))
+	_ = sb.WriteByte('0' + ((c >> 3) & 7))
+	_ = sb.WriteByte('0' + (c & 7))
+}
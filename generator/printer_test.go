@@ -0,0 +1,342 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Compile-time verification that test case types implement TestCase interface
+var _ core.TestCase = printFileTestCase{}
+var _ core.TestCase = typeRefTestCase{}
+
+type printFileTestCase struct {
+	file     *descriptorpb.FileDescriptorProto
+	name     string
+	expected string
+}
+
+func newPrintFileTestCase(name string, file *descriptorpb.FileDescriptorProto,
+	expected string) printFileTestCase {
+	return printFileTestCase{
+		name:     name,
+		file:     file,
+		expected: expected,
+	}
+}
+
+func (tc printFileTestCase) Name() string {
+	return tc.name
+}
+
+func (tc printFileTestCase) Test(t *testing.T) {
+	t.Helper()
+	out, err := PrintFile(tc.file)
+	core.AssertMustNoError(t, err, "PrintFile")
+	core.AssertEqual(t, tc.expected, out, "PrintFile")
+}
+
+func newPrinterProto3File() *descriptorpb.FileDescriptorProto {
+	entry := NewMessage("LabelsEntry",
+		NewField("key", 1, TypeString),
+		NewField("value", 2, TypeInt32))
+	entry.Options = &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)}
+
+	opt := NewField("nickname", 4, TypeString)
+	opt.Proto3Optional = proto.Bool(true)
+	opt.OneofIndex = proto.Int32(1)
+
+	user := NewMessageWithNested("User",
+		[]*descriptorpb.FieldDescriptorProto{
+			NewField("id", 1, TypeInt64),
+			NewRepeatedMessageField(".example.v1.User.LabelsEntry"),
+			NewEnumField("status", 3, ".example.v1.User.Status"),
+			opt,
+			NewOneOfField("email", 5, TypeString, 0),
+			NewOneOfField("phone", 6, TypeString, 0),
+			NewMessageField("created", 7, ".google.protobuf.Timestamp"),
+		},
+		[]*descriptorpb.DescriptorProto{entry},
+		[]*descriptorpb.EnumDescriptorProto{NewEnum("Status", "STATUS_UNSPECIFIED", "STATUS_ACTIVE")})
+	user.Field[1].Name = proto.String("labels")
+	user.Field[1].Number = proto.Int32(2)
+	user.Field[0].JsonName = proto.String("id")
+	user.OneofDecl = []*descriptorpb.OneofDescriptorProto{NewOneOf("contact"), NewOneOf("_nickname")}
+	user.ReservedRange = []*descriptorpb.DescriptorProto_ReservedRange{
+		{Start: proto.Int32(8), End: proto.Int32(9)},
+		{Start: proto.Int32(10), End: proto.Int32(maxFieldNumber + 1)},
+	}
+	user.ReservedName = []string{"legacy"}
+
+	watch := NewMethod("Watch", ".example.v1.User", ".example.v1.User")
+	watch.ServerStreaming = proto.Bool(true)
+
+	file := NewFileWithTypes("example/v1/user.proto", "example.v1",
+		[]*descriptorpb.DescriptorProto{user}, nil,
+		[]*descriptorpb.ServiceDescriptorProto{
+			NewService("UserService", NewMethod("Get", ".example.v1.User", ".example.v1.User"), watch),
+		})
+	file.Syntax = proto.String(SyntaxProto3)
+	file.Dependency = []string{"google/protobuf/timestamp.proto"}
+	file.Options = &descriptorpb.FileOptions{GoPackage: proto.String("example.com/v1;userv1")}
+	return file
+}
+
+const printerProto3Expected = `syntax = "proto3";
+
+package example.v1;
+
+import "google/protobuf/timestamp.proto";
+
+option go_package = "example.com/v1;userv1";
+
+message User {
+  int64 id = 1;
+  map<string, int32> labels = 2;
+  Status status = 3;
+  optional string nickname = 4;
+  oneof contact {
+    string email = 5;
+    string phone = 6;
+  }
+  google.protobuf.Timestamp created = 7;
+
+  enum Status {
+    STATUS_UNSPECIFIED = 0;
+    STATUS_ACTIVE = 1;
+  }
+
+  reserved 8, 10 to max;
+  reserved "legacy";
+}
+
+service UserService {
+  rpc Get(User) returns (User);
+  rpc Watch(User) returns (stream User);
+}
+`
+
+func newPrinterProto2File() *descriptorpb.FileDescriptorProto {
+	result := NewMessage("Result", NewField("url", 1, TypeString))
+
+	group := NewField("result", 1, TypeGroup)
+	group.TypeName = proto.String(".legacy.Search.Result")
+
+	page := NewField("page", 2, TypeInt32)
+	page.DefaultValue = proto.String("10")
+	page.Options = &descriptorpb.FieldOptions{Deprecated: proto.Bool(true)}
+
+	query := NewRequiredField("query", 3, TypeString)
+	query.DefaultValue = proto.String("a\"b")
+	query.JsonName = proto.String("q")
+
+	search := NewMessageWithNested("Search",
+		[]*descriptorpb.FieldDescriptorProto{group, page, query},
+		[]*descriptorpb.DescriptorProto{result}, nil)
+	search.ExtensionRange = []*descriptorpb.DescriptorProto_ExtensionRange{
+		{Start: proto.Int32(100), End: proto.Int32(200)},
+	}
+
+	ext := NewField("tag", 100, TypeString)
+	ext.Extendee = proto.String(".legacy.Search")
+
+	file := NewFileWithTypes("legacy.proto", "legacy",
+		[]*descriptorpb.DescriptorProto{search}, nil, nil)
+	file.Extension = []*descriptorpb.FieldDescriptorProto{ext}
+	return file
+}
+
+const printerProto2Expected = `syntax = "proto2";
+
+package legacy;
+
+message Search {
+  optional group Result = 1 {
+    optional string url = 1;
+  }
+  optional int32 page = 2 [default = 10, deprecated = true];
+  required string query = 3 [default = "a\"b", json_name = "q"];
+
+  extensions 100 to 199;
+}
+
+extend Search {
+  optional string tag = 100;
+}
+`
+
+func newPrinterEditionsFile() *descriptorpb.FileDescriptorProto {
+	msg := NewMessage("Item",
+		NewField("name", 1, TypeString),
+		NewRepeatedField("ids", 2, TypeInt32))
+	msg.Field[0].Options = &descriptorpb.FieldOptions{
+		Features: &descriptorpb.FeatureSet{
+			FieldPresence: descriptorpb.FeatureSet_IMPLICIT.Enum(),
+		},
+	}
+	msg.ReservedName = []string{"old"}
+
+	file := NewFileWithTypes("items.proto", "items",
+		[]*descriptorpb.DescriptorProto{msg},
+		[]*descriptorpb.EnumDescriptorProto{NewEnum("Kind", "KIND_UNKNOWN")}, nil)
+	file.Syntax = proto.String(SyntaxEditions)
+	file.Edition = descriptorpb.Edition_EDITION_2023.Enum()
+	return file
+}
+
+const printerEditionsExpected = `edition = "2023";
+
+package items;
+
+message Item {
+  string name = 1 [features.field_presence = IMPLICIT];
+  repeated int32 ids = 2;
+
+  reserved old;
+}
+
+enum Kind {
+  KIND_UNKNOWN = 0;
+}
+`
+
+func newPrinterCommentsFile() *descriptorpb.FileDescriptorProto {
+	file := NewFileWithTypes("c.proto", "",
+		[]*descriptorpb.DescriptorProto{NewMessage("Ping", NewField("seq", 1, TypeUInt32))}, nil, nil)
+	file.Syntax = proto.String(SyntaxProto3)
+	file.SourceCodeInfo = &descriptorpb.SourceCodeInfo{
+		Location: []*descriptorpb.SourceCodeInfo_Location{
+			{Path: []int32{12}, LeadingDetachedComments: []string{" Licence header\n"}},
+			{Path: []int32{4, 0}, LeadingComments: proto.String(" Ping is a ping.\n Really.\n")},
+			{Path: []int32{4, 0, 2, 0}, TrailingComments: proto.String(" sequence\n")},
+		},
+	}
+	return file
+}
+
+const printerCommentsExpected = `// Licence header
+
+syntax = "proto3";
+
+// Ping is a ping.
+// Really.
+message Ping {
+  uint32 seq = 1; // sequence
+}
+`
+
+func TestPrintFile(t *testing.T) {
+	testCases := []printFileTestCase{
+		newPrintFileTestCase("proto3", newPrinterProto3File(), printerProto3Expected),
+		newPrintFileTestCase("proto2", newPrinterProto2File(), printerProto2Expected),
+		newPrintFileTestCase("editions", newPrinterEditionsFile(), printerEditionsExpected),
+		newPrintFileTestCase("comments", newPrinterCommentsFile(), printerCommentsExpected),
+	}
+
+	core.RunTestCases(t, testCases)
+}
+
+func TestPrintFileNil(t *testing.T) {
+	out, err := PrintFile(nil)
+	core.AssertErrorIs(t, err, core.ErrInvalid, "PrintFile error")
+	core.AssertEqual(t, "", out, "PrintFile output")
+}
+
+func TestPrintFileStable(t *testing.T) {
+	file := newPrinterProto3File()
+	first, err := PrintFile(file)
+	core.AssertMustNoError(t, err, "first PrintFile")
+	for i := 0; i < 5; i++ {
+		again, err := PrintFile(file)
+		core.AssertMustNoError(t, err, "PrintFile")
+		core.AssertEqual(t, first, again, "PrintFile run %d", i)
+	}
+}
+
+type typeRefTestCase struct {
+	name     string
+	scope    string
+	typeName string
+	expected string
+}
+
+func newTypeRefTestCase(name, scope, typeName, expected string) typeRefTestCase {
+	return typeRefTestCase{
+		name:     name,
+		scope:    scope,
+		typeName: typeName,
+		expected: expected,
+	}
+}
+
+func (tc typeRefTestCase) Name() string {
+	return tc.name
+}
+
+func (tc typeRefTestCase) Test(t *testing.T) {
+	t.Helper()
+	outer := NewMessageWithNested("Outer", nil,
+		[]*descriptorpb.DescriptorProto{NewMessage("Inner"), NewMessage("Other")}, nil)
+	other := NewMessage("Other")
+	file := NewFileWithTypes("a.proto", "pkg.sub",
+		[]*descriptorpb.DescriptorProto{outer, other}, nil, nil)
+
+	p := newProtoPrinter(file)
+	core.AssertEqual(t, tc.expected, p.typeRef(tc.scope, tc.typeName), "typeRef")
+}
+
+func TestPrinterTypeRef(t *testing.T) {
+	testCases := []typeRefTestCase{
+		newTypeRefTestCase("same package", "pkg.sub", ".pkg.sub.Other", "Other"),
+		newTypeRefTestCase("nested from parent", "pkg.sub.Outer", ".pkg.sub.Outer.Inner", "Inner"),
+		newTypeRefTestCase("shadowed by nested", "pkg.sub.Outer", ".pkg.sub.Other", "sub.Other"),
+		newTypeRefTestCase("other package", "pkg.sub", ".google.protobuf.Any", "google.protobuf.Any"),
+		newTypeRefTestCase("relative kept", "pkg.sub", "Foo", "Foo"),
+		newTypeRefTestCase("empty", "pkg.sub", "", ""),
+	}
+
+	core.RunTestCases(t, testCases)
+}
+
+func TestPrintFileImportShadowing(t *testing.T) {
+	files := map[string]string{
+		"outer.proto": `syntax = "proto3"; package foo; message Baz {}`,
+		"inner.proto": `syntax = "proto3"; package foo.bar; message Baz {}`,
+		"main.proto": `syntax = "proto3";
+package foo.bar;
+import "outer.proto";
+import "inner.proto";
+message M {
+  foo.Baz outer = 1;
+  Baz inner = 2;
+}
+`,
+	}
+	p := &Parser{Files: files}
+	parsed, err := p.ParseWithImports("main.proto")
+	core.AssertMustNoError(t, err, "Parse")
+	main := parsed[len(parsed)-1]
+	core.AssertEqual(t, ".foo.Baz", main.MessageType[0].Field[0].GetTypeName(), "parsed")
+
+	for name, deps := range map[string][]*descriptorpb.FileDescriptorProto{
+		"with imports":    parsed[:len(parsed)-1],
+		"missing imports": nil,
+	} {
+		out, err := PrintFile(main, deps...)
+		core.AssertMustNoError(t, err, "PrintFile %s", name)
+
+		files["main.proto"] = out
+		again, err := (&Parser{Files: files}).Parse("main.proto")
+		core.AssertMustNoError(t, err, "Parse %s", name)
+		fields := again[0].MessageType[0].Field
+		core.AssertEqual(t, ".foo.Baz", fields[0].GetTypeName(), "%s: outer", name)
+		core.AssertEqual(t, ".foo.bar.Baz", fields[1].GetTypeName(), "%s: inner", name)
+	}
+
+	out, err := PrintFile(main, parsed[:len(parsed)-1]...)
+	core.AssertMustNoError(t, err, "PrintFile")
+	core.AssertContains(t, out, "  foo.Baz outer = 1;", "shortest safe form")
+	core.AssertContains(t, out, "  Baz inner = 2;", "own package")
+}
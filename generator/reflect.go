@@ -0,0 +1,317 @@
+package generator
+
+import (
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// AsReflectMessage attempts to cast the descriptor to a message descriptor.
+// Returns the message descriptor and true if successful, nil and false otherwise.
+func AsReflectMessage(desc protoreflect.Descriptor) (protoreflect.MessageDescriptor, bool) {
+	md, ok := desc.(protoreflect.MessageDescriptor)
+	return md, ok
+}
+
+// IsReflectMessage checks if the descriptor is a message descriptor.
+func IsReflectMessage(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectMessage(desc)
+	return ok
+}
+
+// AsReflectField attempts to cast the descriptor to a field descriptor,
+// including extensions.
+// Returns the field descriptor and true if successful, nil and false otherwise.
+func AsReflectField(desc protoreflect.Descriptor) (protoreflect.FieldDescriptor, bool) {
+	fd, ok := desc.(protoreflect.FieldDescriptor)
+	return fd, ok
+}
+
+// IsReflectField checks if the descriptor is a field descriptor.
+func IsReflectField(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectField(desc)
+	return ok
+}
+
+// AsReflectEnum attempts to cast the descriptor to an enum descriptor.
+// Returns the enum descriptor and true if successful, nil and false otherwise.
+func AsReflectEnum(desc protoreflect.Descriptor) (protoreflect.EnumDescriptor, bool) {
+	ed, ok := desc.(protoreflect.EnumDescriptor)
+	return ed, ok
+}
+
+// IsReflectEnum checks if the descriptor is an enum descriptor.
+func IsReflectEnum(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectEnum(desc)
+	return ok
+}
+
+// AsReflectService attempts to cast the descriptor to a service descriptor.
+// Returns the service descriptor and true if successful, nil and false otherwise.
+func AsReflectService(desc protoreflect.Descriptor) (protoreflect.ServiceDescriptor, bool) {
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	return sd, ok
+}
+
+// IsReflectService checks if the descriptor is a service descriptor.
+func IsReflectService(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectService(desc)
+	return ok
+}
+
+// AsReflectMethod attempts to cast the descriptor to a method descriptor.
+// Returns the method descriptor and true if successful, nil and false otherwise.
+func AsReflectMethod(desc protoreflect.Descriptor) (protoreflect.MethodDescriptor, bool) {
+	md, ok := desc.(protoreflect.MethodDescriptor)
+	return md, ok
+}
+
+// IsReflectMethod checks if the descriptor is a method descriptor.
+func IsReflectMethod(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectMethod(desc)
+	return ok
+}
+
+// AsReflectFile attempts to cast the descriptor to a file descriptor.
+// Returns the file descriptor and true if successful, nil and false otherwise.
+func AsReflectFile(desc protoreflect.Descriptor) (protoreflect.FileDescriptor, bool) {
+	fd, ok := desc.(protoreflect.FileDescriptor)
+	return fd, ok
+}
+
+// IsReflectFile checks if the descriptor is a file descriptor.
+func IsReflectFile(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectFile(desc)
+	return ok
+}
+
+// AsReflectRepeatedField checks if the field is repeated, maps included,
+// and returns it as a field descriptor.
+func AsReflectRepeatedField(desc protoreflect.Descriptor) (protoreflect.FieldDescriptor, bool) {
+	return asReflectFieldIf(desc, func(fd protoreflect.FieldDescriptor) bool {
+		return fd.Cardinality() == protoreflect.Repeated
+	})
+}
+
+// IsReflectRepeatedField checks if the field is repeated, maps included.
+func IsReflectRepeatedField(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectRepeatedField(desc)
+	return ok
+}
+
+// AsReflectMapField checks if the field is a map field and returns it as
+// a field descriptor. Unlike AsMapField, the check is definitive as the
+// entry message is known.
+func AsReflectMapField(desc protoreflect.Descriptor) (protoreflect.FieldDescriptor, bool) {
+	return asReflectFieldIf(desc, protoreflect.FieldDescriptor.IsMap)
+}
+
+// IsReflectMapField checks if the field is a map field.
+func IsReflectMapField(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectMapField(desc)
+	return ok
+}
+
+// AsReflectOneOfField checks if the field is part of a oneof, synthetic
+// oneofs of proto3 optional fields included, and returns it as a field
+// descriptor.
+func AsReflectOneOfField(desc protoreflect.Descriptor) (protoreflect.FieldDescriptor, bool) {
+	return asReflectFieldIf(desc, func(fd protoreflect.FieldDescriptor) bool {
+		return fd.ContainingOneof() != nil
+	})
+}
+
+// IsReflectOneOfField checks if the field is part of a oneof.
+func IsReflectOneOfField(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectOneOfField(desc)
+	return ok
+}
+
+// AsReflectOptionalField checks if the field has optional cardinality
+// and returns it as a field descriptor. As with AsOptionalField this
+// includes proto3 singular fields, use HasPresence to tell those apart.
+func AsReflectOptionalField(desc protoreflect.Descriptor) (protoreflect.FieldDescriptor, bool) {
+	return asReflectFieldIf(desc, func(fd protoreflect.FieldDescriptor) bool {
+		return fd.Cardinality() == protoreflect.Optional
+	})
+}
+
+// IsReflectOptionalField checks if the field has optional cardinality.
+func IsReflectOptionalField(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectOptionalField(desc)
+	return ok
+}
+
+// AsReflectRequiredField checks if the field is required, by proto2 label
+// or LEGACY_REQUIRED presence, and returns it as a field descriptor.
+func AsReflectRequiredField(desc protoreflect.Descriptor) (protoreflect.FieldDescriptor, bool) {
+	return asReflectFieldIf(desc, func(fd protoreflect.FieldDescriptor) bool {
+		return fd.Cardinality() == protoreflect.Required
+	})
+}
+
+// IsReflectRequiredField checks if the field is required.
+func IsReflectRequiredField(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectRequiredField(desc)
+	return ok
+}
+
+// AsReflectScalarField checks if the field is of a scalar type and
+// returns it as a field descriptor.
+func AsReflectScalarField(desc protoreflect.Descriptor) (protoreflect.FieldDescriptor, bool) {
+	return asReflectFieldIf(desc, func(fd protoreflect.FieldDescriptor) bool {
+		switch fd.Kind() {
+		case protoreflect.MessageKind, protoreflect.GroupKind, protoreflect.EnumKind:
+			return false
+		default:
+			return true
+		}
+	})
+}
+
+// IsReflectScalarField checks if the field is of a scalar type.
+func IsReflectScalarField(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectScalarField(desc)
+	return ok
+}
+
+// AsReflectMessageField checks if the field is of message kind, maps
+// included, and returns it as a field descriptor. Fields using the
+// delimited encoding of editions report GroupKind and aren't included.
+func AsReflectMessageField(desc protoreflect.Descriptor) (protoreflect.FieldDescriptor, bool) {
+	return asReflectFieldIf(desc, func(fd protoreflect.FieldDescriptor) bool {
+		return fd.Kind() == protoreflect.MessageKind
+	})
+}
+
+// IsReflectMessageField checks if the field is of message kind.
+func IsReflectMessageField(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectMessageField(desc)
+	return ok
+}
+
+// AsReflectGroupField checks if the field is of group kind and returns
+// it as a field descriptor.
+func AsReflectGroupField(desc protoreflect.Descriptor) (protoreflect.FieldDescriptor, bool) {
+	return asReflectFieldIf(desc, func(fd protoreflect.FieldDescriptor) bool {
+		return fd.Kind() == protoreflect.GroupKind
+	})
+}
+
+// IsReflectGroupField checks if the field is of group kind.
+func IsReflectGroupField(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectGroupField(desc)
+	return ok
+}
+
+// AsReflectEnumField checks if the field is of enum kind and returns
+// it as a field descriptor.
+func AsReflectEnumField(desc protoreflect.Descriptor) (protoreflect.FieldDescriptor, bool) {
+	return asReflectFieldIf(desc, func(fd protoreflect.FieldDescriptor) bool {
+		return fd.Kind() == protoreflect.EnumKind
+	})
+}
+
+// IsReflectEnumField checks if the field is of enum kind.
+func IsReflectEnumField(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectEnumField(desc)
+	return ok
+}
+
+// AsReflectStreamingMethod checks if the method streams requests or
+// responses and returns it as a method descriptor.
+func AsReflectStreamingMethod(desc protoreflect.Descriptor) (protoreflect.MethodDescriptor, bool) {
+	md, ok := AsReflectMethod(desc)
+	if ok && (md.IsStreamingClient() || md.IsStreamingServer()) {
+		return md, true
+	}
+	return nil, false
+}
+
+// IsReflectStreamingMethod checks if the method streams requests or
+// responses.
+func IsReflectStreamingMethod(desc protoreflect.Descriptor) bool {
+	_, ok := AsReflectStreamingMethod(desc)
+	return ok
+}
+
+func asReflectFieldIf(desc protoreflect.Descriptor,
+	cond func(protoreflect.FieldDescriptor) bool) (protoreflect.FieldDescriptor, bool) {
+	fd, ok := AsReflectField(desc)
+	if ok && cond(fd) {
+		return fd, true
+	}
+	return nil, false
+}
+
+// NewReflectFile converts a file into a validated protoreflect descriptor,
+// resolving its imports with r, or the global registry if nil.
+func NewReflectFile(file *descriptorpb.FileDescriptorProto,
+	r protodesc.Resolver) (protoreflect.FileDescriptor, error) {
+	if file == nil {
+		return nil, core.Wrap(core.ErrInvalid, "nil file")
+	}
+	if r == nil {
+		r = protoregistry.GlobalFiles
+	}
+
+	fd, err := protodesc.NewFile(file, r)
+	if err != nil {
+		return nil, core.Wrapf(err, "%q: invalid file", file.GetName())
+	}
+	return fd, nil
+}
+
+// NewReflectFiles converts files into a registry of validated
+// protoreflect descriptors. Imports must be among the given files,
+// except for well-known types which are added when missing.
+func NewReflectFiles(files ...*descriptorpb.FileDescriptorProto) (*protoregistry.Files, error) {
+	all, err := requestFiles(files, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	reg, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: all})
+	if err != nil {
+		return nil, core.Wrap(err, "invalid files")
+	}
+	return reg, nil
+}
+
+// FindReflectDescriptor finds a descriptor by its full name, with or
+// without leading dot.
+func FindReflectDescriptor(r *protoregistry.Files, name string) (protoreflect.Descriptor, error) {
+	desc, err := r.FindDescriptorByName(protoreflect.FullName(registryKey(name)))
+	if err != nil {
+		return nil, core.Wrapf(core.ErrNotExists, "descriptor %q not found", name)
+	}
+	return desc, nil
+}
+
+// ReflectToProto converts a protoreflect descriptor back into its
+// descriptorpb representation.
+func ReflectToProto(desc protoreflect.Descriptor) (proto.Message, error) {
+	switch d := desc.(type) {
+	case protoreflect.FileDescriptor:
+		return protodesc.ToFileDescriptorProto(d), nil
+	case protoreflect.MessageDescriptor:
+		return protodesc.ToDescriptorProto(d), nil
+	case protoreflect.FieldDescriptor:
+		return protodesc.ToFieldDescriptorProto(d), nil
+	case protoreflect.OneofDescriptor:
+		return protodesc.ToOneofDescriptorProto(d), nil
+	case protoreflect.EnumDescriptor:
+		return protodesc.ToEnumDescriptorProto(d), nil
+	case protoreflect.EnumValueDescriptor:
+		return protodesc.ToEnumValueDescriptorProto(d), nil
+	case protoreflect.ServiceDescriptor:
+		return protodesc.ToServiceDescriptorProto(d), nil
+	case protoreflect.MethodDescriptor:
+		return protodesc.ToMethodDescriptorProto(d), nil
+	default:
+		return nil, core.Wrapf(core.ErrInvalid, "unsupported descriptor %T", desc)
+	}
+}
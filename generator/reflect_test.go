@@ -0,0 +1,180 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const reflectTestProto = `syntax = "proto2";
+package test;
+
+import "google/protobuf/timestamp.proto";
+
+message Item {
+  required string id = 1;
+  optional int32 count = 2;
+  repeated string tags = 3;
+  map<string, Item> children = 4;
+  optional group Extra = 5 {
+    optional bool flag = 6;
+  }
+  oneof choice {
+    Kind kind = 7;
+    google.protobuf.Timestamp at = 8;
+  }
+  enum Kind {
+    KIND_A = 0;
+  }
+}
+
+service Items {
+  rpc Get(Item) returns (Item);
+  rpc Watch(Item) returns (stream Item);
+  rpc Upload(stream Item) returns (Item);
+}
+`
+
+func newReflectTestFiles(t *testing.T) (*descriptorpb.FileDescriptorProto, *protoregistry.Files) {
+	t.Helper()
+	p := &Parser{Files: map[string]string{"item.proto": reflectTestProto}, SkipSourceCodeInfo: true}
+	files, err := p.Parse("item.proto")
+	core.AssertMustNoError(t, err, "Parse")
+
+	reg, err := NewReflectFiles(files...)
+	core.AssertMustNoError(t, err, "NewReflectFiles")
+	return files[0], reg
+}
+
+type reflectPredicateTestCase struct {
+	predicate func(protoreflect.Descriptor) bool
+	name      string
+	want      []string
+}
+
+var _ core.TestCase = reflectPredicateTestCase{}
+
+func newReflectPredicateTestCase(name string, predicate func(protoreflect.Descriptor) bool,
+	want ...string) reflectPredicateTestCase {
+	return reflectPredicateTestCase{name: name, predicate: predicate, want: want}
+}
+
+func (tc reflectPredicateTestCase) Name() string {
+	return tc.name
+}
+
+func (tc reflectPredicateTestCase) Test(t *testing.T) {
+	t.Helper()
+	_, reg := newReflectTestFiles(t)
+	fd, err := reg.FindFileByPath("item.proto")
+	core.AssertMustNoError(t, err, "FindFileByPath")
+
+	var got []string
+	for _, d := range reflectTestDescriptors(fd) {
+		if tc.predicate(d) {
+			got = append(got, string(d.Name()))
+		}
+	}
+	core.AssertSliceEqual(t, tc.want, got, "matches")
+}
+
+// reflectTestDescriptors lists the file, the Item message and its
+// fields, the service and its methods.
+func reflectTestDescriptors(fd protoreflect.FileDescriptor) []protoreflect.Descriptor {
+	item := fd.Messages().ByName("Item")
+	out := []protoreflect.Descriptor{fd, item, item.Enums().Get(0)}
+	for i := 0; i < item.Fields().Len(); i++ {
+		out = append(out, item.Fields().Get(i))
+	}
+	svc := fd.Services().Get(0)
+	out = append(out, svc)
+	for i := 0; i < svc.Methods().Len(); i++ {
+		out = append(out, svc.Methods().Get(i))
+	}
+	return out
+}
+
+func reflectPredicateTestCases() []reflectPredicateTestCase {
+	return []reflectPredicateTestCase{
+		// the name of a file descriptor is the last component of its package
+		newReflectPredicateTestCase("file", IsReflectFile, "test"),
+		newReflectPredicateTestCase("message", IsReflectMessage, "Item"),
+		newReflectPredicateTestCase("enum", IsReflectEnum, "Kind"),
+		newReflectPredicateTestCase("service", IsReflectService, "Items"),
+		newReflectPredicateTestCase("method", IsReflectMethod, "Get", "Watch", "Upload"),
+		newReflectPredicateTestCase("field", IsReflectField,
+			"id", "count", "tags", "children", "extra", "kind", "at"),
+		newReflectPredicateTestCase("repeated", IsReflectRepeatedField, "tags", "children"),
+		newReflectPredicateTestCase("map", IsReflectMapField, "children"),
+		newReflectPredicateTestCase("oneof", IsReflectOneOfField, "kind", "at"),
+		newReflectPredicateTestCase("optional", IsReflectOptionalField, "count", "extra", "kind", "at"),
+		newReflectPredicateTestCase("required", IsReflectRequiredField, "id"),
+		newReflectPredicateTestCase("scalar", IsReflectScalarField, "id", "count", "tags"),
+		newReflectPredicateTestCase("message field", IsReflectMessageField, "children", "at"),
+		newReflectPredicateTestCase("group", IsReflectGroupField, "extra"),
+		newReflectPredicateTestCase("enum field", IsReflectEnumField, "kind"),
+		newReflectPredicateTestCase("streaming", IsReflectStreamingMethod, "Watch", "Upload"),
+	}
+}
+
+func TestReflectPredicates(t *testing.T) {
+	core.RunTestCases(t, reflectPredicateTestCases())
+}
+
+func TestReflectPredicatesNil(t *testing.T) {
+	core.AssertFalse(t, IsReflectMessage(nil), "message")
+	core.AssertFalse(t, IsReflectMapField(nil), "map")
+	core.AssertFalse(t, IsReflectStreamingMethod(nil), "streaming")
+}
+
+func TestNewReflectFile(t *testing.T) {
+	file, _ := newReflectTestFiles(t)
+	fd, err := NewReflectFile(file, nil)
+	core.AssertMustNoError(t, err, "NewReflectFile")
+	core.AssertEqual(t, protoreflect.FullName("test"), fd.Package(), "package")
+
+	_, err = NewReflectFile(nil, nil)
+	core.AssertErrorIs(t, err, core.ErrInvalid, "nil file")
+
+	bad := cloneFile(file)
+	bad.MessageType[0].Field[0].Number = proto.Int32(2)
+	_, err = NewReflectFile(bad, nil)
+	core.AssertError(t, err, "duplicate number")
+	core.AssertContains(t, err.Error(), `"item.proto"`, "file name")
+}
+
+func TestNewReflectFilesMissingImport(t *testing.T) {
+	file := File("a.proto").Package("a").Import("b.proto").Build()
+	_, err := NewReflectFiles(file)
+	core.AssertErrorIs(t, err, core.ErrNotExists, "missing import")
+}
+
+func TestReflectToProto(t *testing.T) {
+	file, reg := newReflectTestFiles(t)
+
+	desc, err := FindReflectDescriptor(reg, ".test.Item")
+	core.AssertMustNoError(t, err, "FindReflectDescriptor")
+	msg, err := ReflectToProto(desc)
+	core.AssertMustNoError(t, err, "ReflectToProto")
+	core.AssertTrue(t, proto.Equal(file.MessageType[0], msg), "message round-trip")
+
+	fd, err := reg.FindFileByPath("item.proto")
+	core.AssertMustNoError(t, err, "FindFileByPath")
+	out, err := ReflectToProto(fd)
+	core.AssertMustNoError(t, err, "ReflectToProto")
+	core.AssertTrue(t, proto.Equal(file, out), "file round-trip")
+
+	method, err := ReflectToProto(fd.Services().Get(0).Methods().Get(1))
+	core.AssertMustNoError(t, err, "ReflectToProto")
+	core.AssertTrue(t, IsMethodType(method), "method")
+
+	_, err = FindReflectDescriptor(reg, "test.Missing")
+	core.AssertErrorIs(t, err, core.ErrNotExists, "missing")
+
+	_, err = ReflectToProto(nil)
+	core.AssertErrorIs(t, err, core.ErrInvalid, "nil")
+}
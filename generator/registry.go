@@ -0,0 +1,151 @@
+package generator
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Registry indexes the types declared by a set of files by their
+// fully-qualified names. Names are accepted with or without the leading
+// dot used by FieldDescriptorProto.type_name.
+type Registry struct {
+	files      map[string]*descriptorpb.FileDescriptorProto
+	messages   map[string]*descriptorpb.DescriptorProto
+	enums      map[string]*descriptorpb.EnumDescriptorProto
+	services   map[string]*descriptorpb.ServiceDescriptorProto
+	extensions map[string]*descriptorpb.FieldDescriptorProto
+	owners     map[string]string
+	names      []string
+}
+
+// NewRegistry indexes the given files. nil files are ignored, and when
+// a name is declared more than once the first declaration wins.
+func NewRegistry(files ...*descriptorpb.FileDescriptorProto) *Registry {
+	r := &Registry{
+		files:      make(map[string]*descriptorpb.FileDescriptorProto),
+		messages:   make(map[string]*descriptorpb.DescriptorProto),
+		enums:      make(map[string]*descriptorpb.EnumDescriptorProto),
+		services:   make(map[string]*descriptorpb.ServiceDescriptorProto),
+		extensions: make(map[string]*descriptorpb.FieldDescriptorProto),
+		owners:     make(map[string]string),
+	}
+
+	for _, file := range files {
+		r.addFile(file)
+	}
+	return r
+}
+
+func (r *Registry) addFile(file *descriptorpb.FileDescriptorProto) {
+	if file == nil {
+		return
+	}
+	name := file.GetName()
+	if _, ok := r.files[name]; ok {
+		return
+	}
+	r.files[name] = file
+
+	pkg := file.GetPackage()
+	r.addMessages(name, pkg, file.MessageType)
+	r.addEnums(name, pkg, file.EnumType)
+	r.addExtensions(name, pkg, file.Extension)
+	for _, svc := range file.Service {
+		full := joinName(pkg, svc.GetName())
+		if r.claim(name, full) {
+			r.services[full] = svc
+		}
+	}
+}
+
+func (r *Registry) addMessages(file, scope string, msgs []*descriptorpb.DescriptorProto) {
+	for _, msg := range msgs {
+		full := joinName(scope, msg.GetName())
+		if r.claim(file, full) {
+			r.messages[full] = msg
+			r.names = append(r.names, full)
+		}
+		r.addMessages(file, full, msg.NestedType)
+		r.addEnums(file, full, msg.EnumType)
+		r.addExtensions(file, full, msg.Extension)
+	}
+}
+
+func (r *Registry) addEnums(file, scope string, enums []*descriptorpb.EnumDescriptorProto) {
+	for _, enum := range enums {
+		full := joinName(scope, enum.GetName())
+		if r.claim(file, full) {
+			r.enums[full] = enum
+		}
+	}
+}
+
+func (r *Registry) addExtensions(file, scope string, fields []*descriptorpb.FieldDescriptorProto) {
+	for _, field := range fields {
+		full := joinName(scope, field.GetName())
+		if r.claim(file, full) {
+			r.extensions[full] = field
+		}
+	}
+}
+
+// claim records the file declaring a name, unless already declared.
+func (r *Registry) claim(file, full string) bool {
+	if _, ok := r.owners[full]; ok {
+		return false
+	}
+	r.owners[full] = file
+	return true
+}
+
+// File returns the file with the given name.
+func (r *Registry) File(name string) (*descriptorpb.FileDescriptorProto, bool) {
+	file, ok := r.files[name]
+	return file, ok
+}
+
+// Message returns the message with the given fully-qualified name.
+func (r *Registry) Message(name string) (*descriptorpb.DescriptorProto, bool) {
+	msg, ok := r.messages[registryKey(name)]
+	return msg, ok
+}
+
+// Enum returns the enum with the given fully-qualified name.
+func (r *Registry) Enum(name string) (*descriptorpb.EnumDescriptorProto, bool) {
+	enum, ok := r.enums[registryKey(name)]
+	return enum, ok
+}
+
+// Service returns the service with the given fully-qualified name.
+func (r *Registry) Service(name string) (*descriptorpb.ServiceDescriptorProto, bool) {
+	svc, ok := r.services[registryKey(name)]
+	return svc, ok
+}
+
+// Extension returns the extension field with the given fully-qualified name.
+func (r *Registry) Extension(name string) (*descriptorpb.FieldDescriptorProto, bool) {
+	field, ok := r.extensions[registryKey(name)]
+	return field, ok
+}
+
+// MessageNames returns the fully-qualified names of all messages,
+// with leading dot, in declaration order.
+func (r *Registry) MessageNames() []string {
+	out := make([]string, len(r.names))
+	for i, name := range r.names {
+		out[i] = "." + name
+	}
+	return out
+}
+
+// FileOf returns the name of the file declaring the given message,
+// enum, service or extension.
+func (r *Registry) FileOf(name string) (string, bool) {
+	file, ok := r.owners[registryKey(name)]
+	return file, ok
+}
+
+func registryKey(name string) string {
+	return strings.TrimPrefix(name, ".")
+}
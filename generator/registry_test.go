@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func newRegistryTestFiles(t *testing.T) []*descriptorpb.FileDescriptorProto {
+	t.Helper()
+	p := &Parser{Files: map[string]string{
+		"a.proto": `syntax = "proto2";
+package pkg;
+import "google/protobuf/descriptor.proto";
+message Outer {
+  message Inner {}
+  enum Kind { KIND_A = 0; }
+  extend google.protobuf.FieldOptions { optional string tag = 50000; }
+}
+service Svc { rpc Do(Outer) returns (Outer); }
+`,
+		"b.proto": `syntax = "proto3";
+package pkg;
+message Other {}
+`,
+	}}
+	files, err := p.Parse("a.proto", "b.proto")
+	core.AssertMustNoError(t, err, "Parse")
+	return files
+}
+
+func TestRegistry(t *testing.T) {
+	files := newRegistryTestFiles(t)
+	r := NewRegistry(nil, files[0], files[1])
+
+	msg, ok := r.Message(".pkg.Outer.Inner")
+	core.AssertTrue(t, ok, "nested message")
+	core.AssertEqual(t, "Inner", msg.GetName(), "nested message name")
+
+	_, ok = r.Message("pkg.Other")
+	core.AssertTrue(t, ok, "message without leading dot")
+
+	enum, ok := r.Enum(".pkg.Outer.Kind")
+	core.AssertTrue(t, ok, "enum")
+	core.AssertEqual(t, "Kind", enum.GetName(), "enum name")
+
+	_, ok = r.Service(".pkg.Svc")
+	core.AssertTrue(t, ok, "service")
+
+	ext, ok := r.Extension(".pkg.Outer.tag")
+	core.AssertTrue(t, ok, "extension")
+	core.AssertEqual(t, int32(50000), ext.GetNumber(), "extension number")
+
+	file, ok := r.FileOf(".pkg.Other")
+	core.AssertTrue(t, ok, "FileOf")
+	core.AssertEqual(t, "b.proto", file, "FileOf")
+
+	_, ok = r.Message(".pkg.Outer.Kind")
+	core.AssertFalse(t, ok, "enum as message")
+	_, ok = r.FileOf(".pkg.Missing")
+	core.AssertFalse(t, ok, "missing")
+
+	f, ok := r.File("a.proto")
+	core.AssertTrue(t, ok, "File")
+	core.AssertEqual(t, files[0], f, "File")
+}
+
+func TestRegistryFirstWins(t *testing.T) {
+	first := NewFileWithTypes("a.proto", "pkg",
+		[]*descriptorpb.DescriptorProto{NewMessage("M", NewField("a", 1, TypeString))}, nil, nil)
+	second := NewFileWithTypes("b.proto", "pkg",
+		[]*descriptorpb.DescriptorProto{NewMessage("M")}, nil, nil)
+
+	r := NewRegistry(first, second)
+	msg, ok := r.Message("pkg.M")
+	core.AssertMustTrue(t, ok, "message")
+	core.AssertEqual(t, 1, len(msg.Field), "first declaration")
+
+	file, _ := r.FileOf("pkg.M")
+	core.AssertEqual(t, "a.proto", file, "owner")
+}
@@ -0,0 +1,187 @@
+package generator
+
+import (
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// RequestOption customises a CodeGeneratorRequest built by
+// NewCodeGeneratorRequest.
+type RequestOption func(*requestConfig)
+
+type requestConfig struct {
+	version   *pluginpb.Version
+	deps      []*descriptorpb.FileDescriptorProto
+	parameter string
+}
+
+// WithParameter sets the parameter passed to the plugin, as given
+// by --<plugin>_opt.
+func WithParameter(parameter string) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.parameter = parameter
+	}
+}
+
+// WithCompilerVersion sets the compiler version of the request, nil
+// leaves it unset.
+func WithCompilerVersion(version *pluginpb.Version) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.version = version
+	}
+}
+
+// WithDependencies provides files imported by those to generate.
+// Well-known types don't need to be provided.
+func WithDependencies(files ...*descriptorpb.FileDescriptorProto) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.deps = append(cfg.deps, files...)
+	}
+}
+
+// DefaultCompilerVersion returns the compiler version set on requests
+// unless WithCompilerVersion is used, that of protoc 29.3.
+func DefaultCompilerVersion() *pluginpb.Version {
+	return &pluginpb.Version{
+		Major:  proto.Int32(5),
+		Minor:  proto.Int32(29),
+		Patch:  proto.Int32(3),
+		Suffix: proto.String(""),
+	}
+}
+
+// NewCodeGeneratorRequest builds the request protoc would send to a
+// plugin asked to generate the given files, for testing plugins
+// end-to-end.
+//
+// proto_file lists the files and their transitive dependencies in
+// topological order, including the well-known types they import, with
+// source-retention options stripped as protoc does. Dependencies none
+// of them imports, directly or indirectly, are left out. The unstripped
+// files to generate are set in source_file_descriptors. The given
+// descriptors are copied, never modified.
+func NewCodeGeneratorRequest(files []*descriptorpb.FileDescriptorProto,
+	opts ...RequestOption) (*pluginpb.CodeGeneratorRequest, error) {
+	cfg := &requestConfig{version: DefaultCompilerVersion()}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	all, err := requestFiles(files, cfg.deps)
+	if err != nil {
+		return nil, err
+	}
+	g, err := NewFileGraph(all)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &pluginpb.CodeGeneratorRequest{CompilerVersion: cfg.version}
+	if cfg.parameter != "" {
+		req.Parameter = proto.String(cfg.parameter)
+	}
+	needed := make(map[string]bool)
+	for _, file := range files {
+		req.FileToGenerate = append(req.FileToGenerate, file.GetName())
+		req.SourceFileDescriptors = append(req.SourceFileDescriptors, cloneFile(file))
+
+		needed[file.GetName()] = true
+		for _, dep := range g.TransitiveImports(file.GetName()) {
+			needed[dep] = true
+		}
+	}
+	for _, file := range g.Files() {
+		if needed[file.GetName()] {
+			req.ProtoFile = append(req.ProtoFile, stripSourceRetention(cloneFile(file)))
+		}
+	}
+	return req, nil
+}
+
+// requestFiles collects the files to generate, the given dependencies,
+// and the well-known files any of them imports.
+func requestFiles(files, deps []*descriptorpb.FileDescriptorProto) ([]*descriptorpb.FileDescriptorProto, error) {
+	if len(files) == 0 {
+		return nil, core.Wrap(core.ErrInvalid, "no files to generate")
+	}
+
+	seen := make(map[string]bool)
+	var out []*descriptorpb.FileDescriptorProto
+	add := func(file *descriptorpb.FileDescriptorProto) {
+		if !seen[file.GetName()] {
+			seen[file.GetName()] = true
+			out = append(out, file)
+		}
+	}
+
+	for _, file := range append(core.SliceCopy(files), deps...) {
+		if file == nil {
+			return nil, core.Wrap(core.ErrInvalid, "nil file")
+		}
+		add(file)
+	}
+
+	// out grows while iterating, adding the imports of well-known files
+	for i := 0; i < len(out); i++ {
+		for _, dep := range out[i].Dependency {
+			if seen[dep] {
+				continue
+			}
+			wkt, ok := WellKnownFile(dep)
+			if !ok {
+				return nil, core.Wrapf(core.ErrNotExists, "%q imports unknown file %q", out[i].GetName(), dep)
+			}
+			add(wkt)
+		}
+	}
+	return out, nil
+}
+
+func cloneFile(file *descriptorpb.FileDescriptorProto) *descriptorpb.FileDescriptorProto {
+	out, _ := proto.Clone(file).(*descriptorpb.FileDescriptorProto)
+	return out
+}
+
+// stripSourceRetention clears the options with source retention,
+// which protoc doesn't pass to plugins in proto_file.
+func stripSourceRetention(file *descriptorpb.FileDescriptorProto) *descriptorpb.FileDescriptorProto {
+	stripMessage(file.ProtoReflect())
+	return file
+}
+
+func stripMessage(m protoreflect.Message) {
+	var strip []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if isSourceRetention(fd) {
+			strip = append(strip, fd)
+			return true
+		}
+		stripValue(fd, v)
+		return true
+	})
+
+	for _, fd := range strip {
+		m.Clear(fd)
+	}
+}
+
+func stripValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) {
+	switch {
+	case fd.Message() == nil, fd.IsMap():
+	case fd.IsList():
+		list := v.List()
+		for i := 0; i < list.Len(); i++ {
+			stripMessage(list.Get(i).Message())
+		}
+	default:
+		stripMessage(v.Message())
+	}
+}
+
+func isSourceRetention(fd protoreflect.FieldDescriptor) bool {
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	return ok && opts.GetRetention() == descriptorpb.FieldOptions_RETENTION_SOURCE
+}
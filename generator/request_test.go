@@ -0,0 +1,135 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func requestFileNames(files []*descriptorpb.FileDescriptorProto) []string {
+	out := make([]string, len(files))
+	for i, file := range files {
+		out[i] = file.GetName()
+	}
+	return out
+}
+
+func TestNewCodeGeneratorRequest(t *testing.T) {
+	p := &Parser{Files: map[string]string{
+		"a.proto": `syntax = "proto3";
+package a;
+import "google/protobuf/timestamp.proto";
+message A { google.protobuf.Timestamp at = 1; }
+`,
+		"b.proto": `syntax = "proto3";
+package b;
+import "a.proto";
+import "google/protobuf/struct.proto";
+message B { a.A a = 1; google.protobuf.Struct s = 2; }
+`,
+	}}
+	files, err := p.Parse("b.proto", "a.proto")
+	core.AssertMustNoError(t, err, "Parse")
+
+	req, err := NewCodeGeneratorRequest(files[:1],
+		WithDependencies(files[1]), WithParameter("paths=source_relative"))
+	core.AssertMustNoError(t, err, "NewCodeGeneratorRequest")
+
+	core.AssertSliceEqual(t, []string{"b.proto"}, req.FileToGenerate, "file_to_generate")
+	core.AssertEqual(t, "paths=source_relative", req.GetParameter(), "parameter")
+	core.AssertEqual(t, int32(29), req.GetCompilerVersion().GetMinor(), "compiler_version")
+	core.AssertSliceEqual(t, []string{
+		"google/protobuf/timestamp.proto",
+		"a.proto",
+		"google/protobuf/struct.proto",
+		"b.proto",
+	}, requestFileNames(req.ProtoFile), "proto_file")
+	core.AssertSliceEqual(t, []string{"b.proto"}, requestFileNames(req.SourceFileDescriptors),
+		"source_file_descriptors")
+	core.AssertTrue(t, req.ProtoFile[3] != files[0], "copied")
+	core.AssertTrue(t, proto.Equal(files[0], req.ProtoFile[3]), "same content")
+}
+
+func TestNewCodeGeneratorRequestBuilder(t *testing.T) {
+	file := File("m.proto").Package("m").Import("google/protobuf/any.proto").
+		Message("M").Ref("payload", ".google.protobuf.Any").
+		Build()
+
+	req, err := NewCodeGeneratorRequest([]*descriptorpb.FileDescriptorProto{file},
+		WithCompilerVersion(nil))
+	core.AssertMustNoError(t, err, "NewCodeGeneratorRequest")
+	core.AssertSliceEqual(t, []string{"google/protobuf/any.proto", "m.proto"},
+		requestFileNames(req.ProtoFile), "proto_file")
+	core.AssertNil(t, req.CompilerVersion, "compiler_version")
+	core.AssertNil(t, req.Parameter, "parameter")
+}
+
+func TestNewCodeGeneratorRequestUnrelatedDependency(t *testing.T) {
+	p := &Parser{Files: map[string]string{
+		"a.proto":     `syntax = "proto3"; package a; message A {}`,
+		"b.proto":     `syntax = "proto3"; package b; import "a.proto"; message B { a.A a = 1; }`,
+		"other.proto": `syntax = "proto3"; package other; import "google/protobuf/empty.proto"; message O {}`,
+	}}
+	files, err := p.Parse("b.proto", "a.proto", "other.proto")
+	core.AssertMustNoError(t, err, "Parse")
+
+	req, err := NewCodeGeneratorRequest(files[:1], WithDependencies(files[1:]...))
+	core.AssertMustNoError(t, err, "NewCodeGeneratorRequest")
+	core.AssertSliceEqual(t, []string{"a.proto", "b.proto"}, requestFileNames(req.ProtoFile), "proto_file")
+}
+
+func TestNewCodeGeneratorRequestSourceRetention(t *testing.T) {
+	p := &Parser{Files: map[string]string{
+		"opts.proto": `syntax = "proto3";
+package opts;
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.MessageOptions {
+  string source_only = 50001 [retention = RETENTION_SOURCE];
+  string runtime = 50002;
+}
+`,
+		"m.proto": `edition = "2023";
+package m;
+import "opts.proto";
+message M {
+  option (opts.source_only) = "dropped";
+  option (opts.runtime) = "kept";
+}
+`,
+	}}
+	files, err := p.Parse("m.proto", "opts.proto")
+	core.AssertMustNoError(t, err, "Parse")
+
+	req, err := NewCodeGeneratorRequest(files[:1], WithDependencies(files[1]))
+	core.AssertMustNoError(t, err, "NewCodeGeneratorRequest")
+
+	stripped := req.ProtoFile[len(req.ProtoFile)-1]
+	core.AssertEqual(t, "m.proto", stripped.GetName(), "last file")
+	source := req.SourceFileDescriptors[0]
+
+	core.AssertTrue(t, proto.Equal(files[0], source), "source file unchanged")
+	core.AssertFalse(t, proto.Equal(source, stripped), "proto_file stripped")
+
+	keep, err := PrintFile(stripped)
+	core.AssertMustNoError(t, err, "PrintFile")
+	core.AssertContains(t, keep, `(opts.runtime) = "kept"`, "runtime option")
+	core.AssertFalse(t, strings.Contains(keep, "dropped"), "source option")
+}
+
+func TestNewCodeGeneratorRequestErrors(t *testing.T) {
+	_, err := NewCodeGeneratorRequest(nil)
+	core.AssertErrorIs(t, err, core.ErrInvalid, "no files")
+
+	_, err = NewCodeGeneratorRequest([]*descriptorpb.FileDescriptorProto{nil})
+	core.AssertErrorIs(t, err, core.ErrInvalid, "nil file")
+
+	file := NewFile("x.proto", "x")
+	file.Dependency = []string{"missing.proto"}
+	_, err = NewCodeGeneratorRequest([]*descriptorpb.FileDescriptorProto{file})
+	core.AssertErrorIs(t, err, core.ErrNotExists, "unknown import")
+
+	core.AssertEqual(t, int32(5), DefaultCompilerVersion().GetMajor(), "major")
+}
@@ -0,0 +1,489 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Selector is a compiled descriptor query, in a syntax modelled on CSS.
+//
+// A selector is a comma-separated list of alternatives, each a sequence
+// of compound selectors joined by whitespace (descendant) or '>'
+// (child). A compound selector is a kind (file, message, field, oneof,
+// extension, enum, value, service, method, or '*' for any) followed by
+// any number of conditions:
+//
+//   - [name], [name=value], [name!=value], [name^=value], [name$=value]
+//     and [name*=value] check an attribute, its presence, equality,
+//     prefix, suffix or substring. Attributes are the fields of the
+//     descriptor proto, such as name or number, plus full_name, package,
+//     file, syntax and option.<name> or option.(<extension>). Types and
+//     labels are lowercase without prefix, and type names have no
+//     leading dot.
+//   - :map, :repeated, :optional, :required, :oneof, :scalar, :message,
+//     :enum and :group classify fields, :map-entry messages, and
+//     :streaming, :client-streaming, :server-streaming and :unary
+//     methods. :deprecated matches any deprecated descriptor.
+//   - :has(selector) matches descriptors with a matching descendant, or
+//     child if the argument starts with '>', :not(selector) those not
+//     matching, and :input(selector) and :output(selector) methods whose
+//     request or response message matches.
+//
+// For example:
+//
+//	service[name=Foo] > method:streaming
+//	message field:map
+//	service[option.deprecated=true] method:input(:has(field:repeated))
+type Selector struct {
+	source string
+	groups []selComplex
+}
+
+// SelectorMatch is a descriptor matched by a selector.
+type SelectorMatch struct {
+	// File is the file declaring the descriptor.
+	File *descriptorpb.FileDescriptorProto
+	// Descriptor is the matched descriptor proto.
+	Descriptor proto.Message
+	// FullName is the fully-qualified name without leading dot, or the
+	// file name for files.
+	FullName string
+	// Kind is the kind of the descriptor.
+	Kind SelectorKind
+	// Path is the SourceCodeInfo path of the descriptor within File.
+	Path []int32
+}
+
+// CompileSelector parses a selector.
+func CompileSelector(s string) (*Selector, error) {
+	p := &selParser{src: s}
+	sel, err := p.parseSelector()
+	switch {
+	case err != nil:
+		return nil, err
+	case !p.eof():
+		return nil, p.fail("unexpected %q", p.src[p.pos:])
+	default:
+		return sel, nil
+	}
+}
+
+// MustCompileSelector is like CompileSelector but panics if the
+// selector is invalid.
+func MustCompileSelector(s string) *Selector {
+	sel, err := CompileSelector(s)
+	if err != nil {
+		panic(err)
+	}
+	return sel
+}
+
+// Select compiles a selector and evaluates it against a set of files.
+func Select(set *descriptorpb.FileDescriptorSet, selector string) ([]SelectorMatch, error) {
+	sel, err := CompileSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return sel.Select(set), nil
+}
+
+// String returns the source of the selector.
+func (s *Selector) String() string {
+	return s.source
+}
+
+// Select returns the descriptors of the set matching the selector, in
+// declaration order.
+func (s *Selector) Select(set *descriptorpb.FileDescriptorSet) []SelectorMatch {
+	return s.SelectFiles(set.GetFile()...)
+}
+
+// SelectFiles returns the descriptors of the files matching the
+// selector, in declaration order. Method types are resolved among the
+// given files.
+func (s *Selector) SelectFiles(files ...*descriptorpb.FileDescriptorProto) []SelectorMatch {
+	t := newSelTree(files)
+
+	var out []SelectorMatch
+	for _, root := range t.roots {
+		root.walk(func(n *selNode) {
+			if s.matches(t, n) {
+				out = append(out, SelectorMatch{
+					File:       n.file,
+					Descriptor: n.desc,
+					FullName:   n.fullName,
+					Kind:       n.kind,
+					Path:       core.SliceCopy(n.path),
+				})
+			}
+		})
+	}
+	return out
+}
+
+func (s *Selector) matches(t *selTree, n *selNode) bool {
+	for _, c := range s.groups {
+		if c.matchAt(t, n, len(c.parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAt tells if the node matches the i-th compound and its
+// ancestors the preceding ones.
+func (c selComplex) matchAt(t *selTree, n *selNode, i int) bool {
+	switch {
+	case !c.parts[i].matches(t, n):
+		return false
+	case i == 0:
+		return true
+	case c.combs[i-1] == '>':
+		for _, p := range n.parents() {
+			if c.matchAt(t, p, i-1) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the ancestors of a oneof member are its oneof and those of it
+	start := n.parent
+	if n.oneof != nil {
+		start = n.oneof
+	}
+	for a := start; a != nil; a = a.parent {
+		if c.matchAt(t, a, i-1) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c selCompound) matches(t *selTree, n *selNode) bool {
+	if c.kind != "" && c.kind != n.kind {
+		return false
+	}
+	for _, a := range c.attrs {
+		if !a.matches(t, n) {
+			return false
+		}
+	}
+	for _, ps := range c.pseudos {
+		if !ps.matches(t, n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a selAttr) matches(t *selTree, n *selNode) bool {
+	v, ok := attrValue(t, n, a.name)
+	switch {
+	case !ok:
+		return a.op == "!="
+	case a.op == "":
+		return true
+	case a.op == "=":
+		return v == a.value
+	case a.op == "!=":
+		return v != a.value
+	case a.op == "^=":
+		return strings.HasPrefix(v, a.value)
+	case a.op == "$=":
+		return strings.HasSuffix(v, a.value)
+	default:
+		return strings.Contains(v, a.value)
+	}
+}
+
+func (ps selPseudo) matches(t *selTree, n *selNode) bool {
+	switch ps.name {
+	case "has":
+		return ps.hasMatch(t, n)
+	case "not":
+		return !ps.arg.matches(t, n)
+	case "input", "output":
+		return ps.typeMatch(t, n)
+	default:
+		return pseudoMatches(t, n, ps.name)
+	}
+}
+
+func (ps selPseudo) hasMatch(t *selTree, n *selNode) bool {
+	for _, list := range [][]*selNode{n.children, n.members} {
+		for _, c := range list {
+			if ps.arg.matches(t, c) || (!ps.child && ps.hasMatch(t, c)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (ps selPseudo) typeMatch(t *selTree, n *selNode) bool {
+	method, ok := n.desc.(*descriptorpb.MethodDescriptorProto)
+	if !ok {
+		return false
+	}
+
+	typeName := method.GetInputType()
+	if ps.name == "output" {
+		typeName = method.GetOutputType()
+	}
+	msg, ok := t.message(typeName)
+	return ok && ps.arg.matches(t, msg)
+}
+
+func pseudoMatches(t *selTree, n *selNode, name string) bool {
+	switch n.desc.(type) {
+	case *descriptorpb.FieldDescriptorProto:
+		return fieldPseudo(t, n, name)
+	case *descriptorpb.MethodDescriptorProto:
+		return methodPseudo(n, name)
+	case *descriptorpb.DescriptorProto:
+		if name == "map-entry" {
+			return optionBool(n.desc, "map_entry")
+		}
+	}
+	return name == "deprecated" && IsDeprecated(n.desc)
+}
+
+func fieldPseudo(t *selTree, n *selNode, name string) bool {
+	field, _ := n.desc.(*descriptorpb.FieldDescriptorProto)
+	isMap := isSelectorMap(t, field)
+	switch name {
+	case "map":
+		return isMap
+	case "repeated":
+		return IsRepeatedField(field) && !isMap
+	case "optional":
+		return isExplicitOptional(n.file, field)
+	case "required":
+		return IsRequiredField(field)
+	case "oneof":
+		return isRealOneofMember(field)
+	case "scalar":
+		return IsScalarField(field)
+	case "message":
+		return IsMessageField(field) && !isMap
+	case "enum":
+		return IsEnumField(field)
+	case "group":
+		return IsGroupField(field)
+	case "deprecated":
+		return IsDeprecated(field)
+	}
+	return false
+}
+
+// isSelectorMap tells if a field is a map, checking the entry message
+// when it's known.
+func isSelectorMap(t *selTree, field *descriptorpb.FieldDescriptorProto) bool {
+	if entry, ok := t.message(field.GetTypeName()); ok {
+		return IsMapFieldWithMessage(field, entry.desc)
+	}
+	return IsMapField(field)
+}
+
+// isExplicitOptional tells if a singular field tracks presence without
+// being a required field or a member of a real oneof.
+func isExplicitOptional(file *descriptorpb.FileDescriptorProto, field *descriptorpb.FieldDescriptorProto) bool {
+	if !IsOptionalField(field) || isRealOneofMember(field) {
+		return false
+	}
+	if field.GetProto3Optional() {
+		return true
+	}
+	presence := FieldFeatures(file, field).GetFieldPresence()
+	return presence == descriptorpb.FeatureSet_EXPLICIT
+}
+
+func methodPseudo(n *selNode, name string) bool {
+	method, _ := n.desc.(*descriptorpb.MethodDescriptorProto)
+	client, server := method.GetClientStreaming(), method.GetServerStreaming()
+	switch name {
+	case "streaming":
+		return client || server
+	case "client-streaming":
+		return client
+	case "server-streaming":
+		return server
+	case "unary":
+		return !client && !server
+	case "deprecated":
+		return IsDeprecated(method)
+	}
+	return false
+}
+
+// attrValue returns the value of an attribute of a node as text.
+func attrValue(t *selTree, n *selNode, name string) (string, bool) {
+	switch name {
+	case "name":
+		return n.name, true
+	case "full_name":
+		return n.fullName, true
+	case "package":
+		return n.file.GetPackage(), true
+	case "file":
+		return n.file.GetName(), true
+	case "syntax":
+		return FileSyntax(n.file), true
+	case "json_name":
+		if field, ok := n.desc.(*descriptorpb.FieldDescriptorProto); ok {
+			return FieldJSONName(field), true
+		}
+		return "", false
+	}
+
+	if opt, ok := strings.CutPrefix(name, "option."); ok {
+		return selectorOption(t, n.desc, opt)
+	}
+	return descriptorValue(n.desc, name)
+}
+
+// descriptorValue returns the value of a field of a descriptor proto,
+// with enum prefixes and leading dots of type names removed.
+func descriptorValue(desc proto.Message, name string) (string, bool) {
+	m := desc.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil || !m.Has(fd) {
+		return "", false
+	}
+
+	v := formatSelectorValue(fd, m.Get(fd))
+	switch name {
+	case "type":
+		v = strings.ToLower(strings.TrimPrefix(v, "TYPE_"))
+	case "label":
+		v = strings.ToLower(strings.TrimPrefix(v, "LABEL_"))
+	case "type_name", "extendee", "input_type", "output_type":
+		v = registryKey(v)
+	}
+	return v, true
+}
+
+// selectorOption returns the value of an option of a descriptor, name
+// being a field of the options message or a parenthesised extension.
+// Extensions not known to the options message are decoded from its
+// unknown fields when declared in the files being queried.
+func selectorOption(t *selTree, desc proto.Message, name string) (string, bool) {
+	opts, ok := descriptorOptions(desc)
+	if !ok {
+		return "", false
+	}
+
+	ext, isExt := strings.CutPrefix(name, "(")
+	if !isExt {
+		return descriptorValue(opts.Interface(), name)
+	}
+
+	ext = registryKey(strings.TrimSuffix(ext, ")"))
+	if v, ok := knownExtension(opts, ext); ok {
+		return v, true
+	}
+	if field, ok := t.extension(ext); ok {
+		return unknownExtension(opts.GetUnknown(), protowire.Number(field.GetNumber()))
+	}
+	return "", false
+}
+
+// descriptorOptions returns the options message of a descriptor, if set.
+func descriptorOptions(desc proto.Message) (protoreflect.Message, bool) {
+	m := desc.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName("options")
+	if fd == nil || !m.Has(fd) {
+		return nil, false
+	}
+	return m.Get(fd).Message(), true
+}
+
+func knownExtension(opts protoreflect.Message, name string) (string, bool) {
+	var out string
+	var found bool
+	opts.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.IsExtension() && string(fd.FullName()) == name {
+			out, found = formatSelectorValue(fd, v), true
+			return false
+		}
+		return true
+	})
+	return out, found
+}
+
+// unknownExtension decodes the last value of a field in unknown bytes,
+// varints as unsigned integers and length-delimited values as text.
+func unknownExtension(b []byte, number protowire.Number) (string, bool) {
+	var out string
+	var found bool
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			break
+		}
+		b = b[n:]
+
+		v, m := decodeUnknown(b, typ)
+		if m < 0 {
+			break
+		}
+		b = b[m:]
+		if num == number {
+			out, found = v, true
+		}
+	}
+	return out, found
+}
+
+func decodeUnknown(b []byte, typ protowire.Type) (string, int) {
+	switch typ {
+	case protowire.VarintType:
+		v, n := protowire.ConsumeVarint(b)
+		return strconv.FormatUint(v, 10), n
+	case protowire.Fixed32Type:
+		v, n := protowire.ConsumeFixed32(b)
+		return strconv.FormatUint(uint64(v), 10), n
+	case protowire.Fixed64Type:
+		v, n := protowire.ConsumeFixed64(b)
+		return strconv.FormatUint(v, 10), n
+	case protowire.BytesType:
+		v, n := protowire.ConsumeBytes(b)
+		return string(v), n
+	default:
+		return "", protowire.ConsumeFieldValue(0, typ, b)
+	}
+}
+
+// optionBool tells if a boolean option of a descriptor is set to true.
+func optionBool(desc proto.Message, name string) bool {
+	opts, ok := descriptorOptions(desc)
+	if !ok {
+		return false
+	}
+	v, ok := descriptorValue(opts.Interface(), name)
+	return ok && v == "true"
+}
+
+func formatSelectorValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch {
+	case fd.IsList():
+		return strconv.Itoa(v.List().Len())
+	case fd.IsMap(), fd.Message() != nil:
+		return ""
+	case fd.Enum() != nil:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return strconv.Itoa(int(v.Enum()))
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
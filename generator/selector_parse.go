@@ -0,0 +1,303 @@
+package generator
+
+import (
+	"strings"
+
+	"darvaza.org/core"
+)
+
+// selComplex is a sequence of compound selectors joined by
+// combinators, ' ' for descendants and '>' for children.
+type selComplex struct {
+	parts []selCompound
+	combs []byte
+}
+
+// selCompound is a kind with attribute and pseudo-class conditions.
+type selCompound struct {
+	kind    SelectorKind
+	attrs   []selAttr
+	pseudos []selPseudo
+}
+
+// selAttr is an [name op value] condition, op is empty when only
+// presence is checked.
+type selAttr struct {
+	name  string
+	op    string
+	value string
+}
+
+// selPseudo is a :name or :name(selector) condition. child is set
+// when the argument of :has() starts with '>'.
+type selPseudo struct {
+	arg   *Selector
+	name  string
+	child bool
+}
+
+// selectorKinds are the valid kinds of compound selectors.
+var selectorKinds = []SelectorKind{
+	KindFile, KindMessage, KindField, KindOneof, KindExtension,
+	KindEnum, KindValue, KindService, KindMethod,
+}
+
+// selectorPseudos tells the known pseudo-classes and whether they
+// take a selector argument.
+var selectorPseudos = map[string]bool{
+	"has": true, "not": true, "input": true, "output": true,
+	"map": false, "repeated": false, "optional": false, "required": false,
+	"oneof": false, "scalar": false, "message": false, "enum": false,
+	"group": false, "map-entry": false, "deprecated": false,
+	"streaming": false, "client-streaming": false, "server-streaming": false,
+	"unary": false,
+}
+
+// selectorOps are the attribute operators, longest first.
+var selectorOps = []string{"!=", "^=", "$=", "*=", "="}
+
+type selParser struct {
+	src string
+	pos int
+}
+
+func (p *selParser) fail(format string, args ...any) error {
+	args = append([]any{p.src, p.pos}, args...)
+	return core.Wrapf(core.ErrInvalid, "selector %q:%d: "+format, args...)
+}
+
+func (p *selParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *selParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+// skipSpace skips whitespace, telling if there was any.
+func (p *selParser) skipSpace() bool {
+	start := p.pos
+	for !p.eof() && isSelectorSpace(p.peek()) {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *selParser) accept(c byte) bool {
+	if p.peek() == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// parseSelector parses a comma-separated list of complex selectors,
+// stopping at the end of the input or at an unbalanced ')'.
+func (p *selParser) parseSelector() (*Selector, error) {
+	start := p.pos
+	s := &Selector{}
+	for {
+		p.skipSpace()
+		c, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+		s.groups = append(s.groups, c)
+
+		p.skipSpace()
+		if !p.accept(',') {
+			break
+		}
+	}
+	s.source = strings.TrimSpace(p.src[start:p.pos])
+	return s, nil
+}
+
+func (p *selParser) parseComplex() (selComplex, error) {
+	var c selComplex
+	for {
+		part, err := p.parseCompound()
+		if err != nil {
+			return c, err
+		}
+		c.parts = append(c.parts, part)
+
+		comb, ok := p.parseCombinator()
+		if !ok {
+			return c, nil
+		}
+		c.combs = append(c.combs, comb)
+	}
+}
+
+// parseCombinator consumes the combinator before the next compound
+// selector, if any.
+func (p *selParser) parseCombinator() (byte, bool) {
+	space := p.skipSpace()
+	switch {
+	case p.eof(), p.peek() == ',', p.peek() == ')':
+		return 0, false
+	case p.accept('>'):
+		p.skipSpace()
+		return '>', true
+	default:
+		return ' ', space
+	}
+}
+
+func (p *selParser) parseCompound() (selCompound, error) {
+	var c selCompound
+	start := p.pos
+
+	if !p.accept('*') {
+		if name := p.ident(); name != "" {
+			kind := SelectorKind(name)
+			if !core.SliceContains(selectorKinds, kind) {
+				p.pos = start
+				return c, p.fail("unknown kind %q", name)
+			}
+			c.kind = kind
+		}
+	}
+
+	for {
+		var err error
+		switch {
+		case p.accept('['):
+			err = p.parseAttr(&c)
+		case p.accept(':'):
+			err = p.parsePseudo(&c)
+		case p.pos == start:
+			return c, p.fail("selector expected")
+		default:
+			return c, nil
+		}
+		if err != nil {
+			return c, err
+		}
+	}
+}
+
+func (p *selParser) parseAttr(c *selCompound) error {
+	p.skipSpace()
+	var a selAttr
+	a.name = p.attrName()
+	if a.name == "" {
+		return p.fail("attribute name expected")
+	}
+
+	p.skipSpace()
+	for _, op := range selectorOps {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			a.op = op
+			p.pos += len(op)
+			break
+		}
+	}
+
+	if a.op != "" {
+		p.skipSpace()
+		value, err := p.attrValue()
+		if err != nil {
+			return err
+		}
+		a.value = value
+		p.skipSpace()
+	}
+
+	if !p.accept(']') {
+		return p.fail("']' expected")
+	}
+	c.attrs = append(c.attrs, a)
+	return nil
+}
+
+func (p *selParser) parsePseudo(c *selCompound) error {
+	start := p.pos
+	name := p.ident()
+	takesArg, ok := selectorPseudos[name]
+	switch {
+	case !ok:
+		p.pos = start
+		return p.fail("unknown pseudo-class %q", name)
+	case !takesArg:
+		c.pseudos = append(c.pseudos, selPseudo{name: name})
+		return nil
+	case !p.accept('('):
+		return p.fail("':%s' requires a selector argument", name)
+	}
+
+	p.skipSpace()
+	child := name == "has" && p.accept('>')
+	arg, err := p.parseSelector()
+	switch {
+	case err != nil:
+		return err
+	case !p.accept(')'):
+		return p.fail("')' expected")
+	}
+	c.pseudos = append(c.pseudos, selPseudo{name: name, arg: arg, child: child})
+	return nil
+}
+
+// ident reads a kind or pseudo-class name.
+func (p *selParser) ident() string {
+	start := p.pos
+	for !p.eof() && isSelectorIdent(p.peek()) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// attrName reads an attribute name, which may include dots and the
+// parenthesised name of an extension as in option.(foo.bar).
+func (p *selParser) attrName() string {
+	start := p.pos
+	depth := 0
+	for !p.eof() {
+		c := p.peek()
+		switch {
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case depth == 0 && !isSelectorIdent(c) && c != '.':
+			return p.src[start:p.pos]
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// attrValue reads a quoted string or a bare word.
+func (p *selParser) attrValue() (string, error) {
+	quote := p.peek()
+	if quote != '"' && quote != '\'' {
+		start := p.pos
+		for !p.eof() && p.peek() != ']' && !isSelectorSpace(p.peek()) {
+			p.pos++
+		}
+		return p.src[start:p.pos], nil
+	}
+
+	p.pos++
+	end := strings.IndexByte(p.src[p.pos:], quote)
+	if end < 0 {
+		return "", p.fail("unterminated string")
+	}
+	value := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+	return value, nil
+}
+
+func isSelectorIdent(c byte) bool {
+	return c == '_' || c == '-' || (c >= '0' && c <= '9') ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isSelectorSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,210 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const selectorOptionsProto = `syntax = "proto3";
+package test;
+
+import "google/protobuf/descriptor.proto";
+
+extend google.protobuf.ServiceOptions {
+  bool internal = 50001;
+}
+`
+
+const selectorAPIProto = `syntax = "proto3";
+package test;
+
+import "options.proto";
+
+message Request {
+  repeated string ids = 1;
+  map<string, int32> counts = 2;
+  optional int32 limit = 3;
+  oneof filter {
+    string prefix = 4;
+    Kind kind = 5;
+  }
+  enum Kind {
+    KIND_UNSPECIFIED = 0;
+    KIND_ALL = 1;
+  }
+}
+
+message Response {
+  Request echo = 1;
+  int32 total = 2 [deprecated = true];
+}
+
+service Foo {
+  option (internal) = true;
+  rpc Get(Request) returns (Response);
+  rpc Watch(Response) returns (stream Response);
+  rpc Upload(stream Request) returns (Response) {
+    option deprecated = true;
+  }
+}
+
+service Bar {
+  rpc List(Response) returns (Response);
+}
+`
+
+func newSelectorTestFiles(t *testing.T) []*descriptorpb.FileDescriptorProto {
+	t.Helper()
+	p := &Parser{Files: map[string]string{
+		"options.proto": selectorOptionsProto,
+		"api.proto":     selectorAPIProto,
+	}}
+	files, err := p.Parse("options.proto", "api.proto")
+	core.AssertMustNoError(t, err, "Parse")
+	return files
+}
+
+type selectorTestCase struct {
+	name     string
+	selector string
+	want     []string
+}
+
+var _ core.TestCase = selectorTestCase{}
+
+func newSelectorTestCase(name, selector string, want ...string) selectorTestCase {
+	return selectorTestCase{name: name, selector: selector, want: want}
+}
+
+func (tc selectorTestCase) Name() string {
+	return tc.name
+}
+
+func (tc selectorTestCase) Test(t *testing.T) {
+	t.Helper()
+	sel, err := CompileSelector(tc.selector)
+	core.AssertMustNoError(t, err, "CompileSelector")
+
+	var got []string
+	for _, m := range sel.SelectFiles(newSelectorTestFiles(t)...) {
+		got = append(got, m.FullName)
+	}
+	core.AssertSliceEqual(t, tc.want, got, "matches")
+}
+
+func selectorTestCases() []selectorTestCase {
+	return []selectorTestCase{
+		newSelectorTestCase("child", "service[name=Foo] > method:streaming",
+			"test.Foo.Watch", "test.Foo.Upload"),
+		newSelectorTestCase("map fields", "message field:map", "test.Request.counts"),
+		newSelectorTestCase("repeated excludes maps", "field:repeated", "test.Request.ids"),
+		newSelectorTestCase("optional", "field:optional", "test.Request.limit"),
+		newSelectorTestCase("oneof members", "field:oneof", "test.Request.prefix", "test.Request.kind"),
+		newSelectorTestCase("enum fields", "field:enum", "test.Request.kind"),
+		newSelectorTestCase("map entries", "message:map-entry", "test.Request.CountsEntry"),
+		newSelectorTestCase("values", "enum > value[number=1]", "test.Request.KIND_ALL"),
+		newSelectorTestCase("unary", "method:unary", "test.Foo.Get", "test.Bar.List"),
+		newSelectorTestCase("deprecated", ":deprecated", "test.Response.total", "test.Foo.Upload"),
+		newSelectorTestCase("alternatives", "service[name=Bar], message[name^=Resp]",
+			"test.Response", "test.Bar"),
+		newSelectorTestCase("type attribute", "field[type=message]",
+			"test.Request.counts", "test.Response.echo"),
+		newSelectorTestCase("type name", "field[type_name$=Kind]", "test.Request.kind"),
+		newSelectorTestCase("label", "field[label=repeated]", "test.Request.ids", "test.Request.counts"),
+		newSelectorTestCase("not", "service:not([name=Foo])", "test.Bar"),
+		newSelectorTestCase("has", "message:has(> oneof)", "test.Request"),
+		newSelectorTestCase("oneof children", "oneof[name=filter] > field", "test.Request.prefix", "test.Request.kind"),
+		newSelectorTestCase("oneof descendants", "message oneof field:enum", "test.Request.kind"),
+		newSelectorTestCase("no synthetic members", "oneof > field", "test.Request.prefix", "test.Request.kind"),
+		newSelectorTestCase("oneof has", "oneof:has(> field[name=prefix])", "test.Request.filter"),
+		newSelectorTestCase("message children", "message[name=Request] > field:oneof",
+			"test.Request.prefix", "test.Request.kind"),
+		newSelectorTestCase("input", "method:input(:has(field:repeated))", "test.Foo.Get", "test.Foo.Upload"),
+		newSelectorTestCase("output", "method:output([name=Response]):client-streaming", "test.Foo.Upload"),
+		newSelectorTestCase("custom option", "service[option.(test.internal)=true] method",
+			"test.Foo.Get", "test.Foo.Watch", "test.Foo.Upload"),
+		newSelectorTestCase("option field", "method[option.deprecated]", "test.Foo.Upload"),
+		newSelectorTestCase("files", "file[package=test]:has(service)", "api.proto"),
+		newSelectorTestCase("extensions", "file > extension[extendee=google.protobuf.ServiceOptions]",
+			"test.internal"),
+		newSelectorTestCase("no match", "service[name=Baz]"),
+	}
+}
+
+func TestSelector(t *testing.T) {
+	core.RunTestCases(t, selectorTestCases())
+}
+
+func TestSelectorPaths(t *testing.T) {
+	files := newSelectorTestFiles(t)
+	set := &descriptorpb.FileDescriptorSet{File: files}
+
+	matches, err := Select(set, "service[name=Foo] method[name=Watch]")
+	core.AssertMustNoError(t, err, "Select")
+	core.AssertMustEqual(t, 1, len(matches), "matches")
+
+	m := matches[0]
+	core.AssertSliceEqual(t, []int32{pathFileService, 0, pathServiceMethod, 1}, m.Path, "path")
+	core.AssertEqual(t, KindMethod, m.Kind, "kind")
+	core.AssertTrue(t, m.File == files[1], "file")
+	core.AssertTrue(t, m.Descriptor == files[1].Service[0].Method[1], "descriptor")
+}
+
+func TestSelectorUnknownOptions(t *testing.T) {
+	// after a round-trip custom options are kept as unknown fields
+	var files []*descriptorpb.FileDescriptorProto
+	for _, file := range newSelectorTestFiles(t) {
+		b, err := proto.Marshal(file)
+		core.AssertMustNoError(t, err, "Marshal")
+		out := &descriptorpb.FileDescriptorProto{}
+		core.AssertMustNoError(t, proto.Unmarshal(b, out), "Unmarshal")
+		files = append(files, out)
+	}
+
+	matches := MustCompileSelector("service[option.(test.internal)=1]").SelectFiles(files...)
+	core.AssertMustEqual(t, 1, len(matches), "matches")
+	core.AssertEqual(t, "test.Foo", matches[0].FullName, "service")
+}
+
+type compileSelectorErrorTestCase struct {
+	name     string
+	selector string
+}
+
+var _ core.TestCase = compileSelectorErrorTestCase{}
+
+func (tc compileSelectorErrorTestCase) Name() string {
+	return tc.name
+}
+
+func (tc compileSelectorErrorTestCase) Test(t *testing.T) {
+	t.Helper()
+	_, err := CompileSelector(tc.selector)
+	core.AssertErrorIs(t, err, core.ErrInvalid, "error")
+}
+
+func compileSelectorErrorTestCases() []compileSelectorErrorTestCase {
+	return []compileSelectorErrorTestCase{
+		{"empty", ""},
+		{"unknown kind", "widget"},
+		{"unknown pseudo", "field:bogus"},
+		{"missing argument", "method:input"},
+		{"unclosed argument", "message:has(field"},
+		{"unclosed attribute", "field[name=a"},
+		{"unterminated string", `field[name="a]`},
+		{"trailing combinator", "message >"},
+		{"unbalanced paren", "message)"},
+	}
+}
+
+func TestCompileSelectorErrors(t *testing.T) {
+	core.RunTestCases(t, compileSelectorErrorTestCases())
+}
+
+func TestSelectorString(t *testing.T) {
+	sel := MustCompileSelector("  message field:map ")
+	core.AssertEqual(t, "message field:map", sel.String(), "String")
+}
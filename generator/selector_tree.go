@@ -0,0 +1,179 @@
+package generator
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// SelectorKind identifies the kind of descriptor a selector matches.
+type SelectorKind string
+
+// Descriptor kinds usable as selector types.
+const (
+	KindFile      SelectorKind = "file"
+	KindMessage   SelectorKind = "message"
+	KindField     SelectorKind = "field"
+	KindOneof     SelectorKind = "oneof"
+	KindExtension SelectorKind = "extension"
+	KindEnum      SelectorKind = "enum"
+	KindValue     SelectorKind = "value"
+	KindService   SelectorKind = "service"
+	KindMethod    SelectorKind = "method"
+)
+
+// selNode is a descriptor in the tree selectors are evaluated on.
+type selNode struct {
+	desc     proto.Message
+	file     *descriptorpb.FileDescriptorProto
+	parent   *selNode
+	name     string
+	fullName string
+	kind     SelectorKind
+	path     []int32
+	children []*selNode
+	// oneof is the oneof of a field member of one, a second parent.
+	oneof *selNode
+	// members are the fields of a oneof, also children of its message.
+	members []*selNode
+}
+
+// selTree holds the descriptors of a set of files, indexing messages
+// by full name to follow method types, and extensions to decode custom
+// options kept as unknown fields.
+type selTree struct {
+	messages   map[string]*selNode
+	extensions map[string]*selNode
+	roots      []*selNode
+}
+
+func newSelTree(files []*descriptorpb.FileDescriptorProto) *selTree {
+	t := &selTree{
+		messages:   make(map[string]*selNode),
+		extensions: make(map[string]*selNode),
+	}
+	for _, file := range files {
+		t.roots = append(t.roots, t.addFile(file))
+	}
+	return t
+}
+
+func (t *selTree) add(parent *selNode, kind SelectorKind, desc proto.Message,
+	name string, path []int32) *selNode {
+	n := &selNode{desc: desc, parent: parent, kind: kind, path: path, name: name, fullName: name}
+	if parent != nil {
+		n.file = parent.file
+		n.fullName = joinName(parent.scope(), name)
+		parent.children = append(parent.children, n)
+	}
+	if kind == KindExtension {
+		t.extensions[n.fullName] = n
+	}
+	return n
+}
+
+func (t *selTree) addFile(file *descriptorpb.FileDescriptorProto) *selNode {
+	n := t.add(nil, KindFile, file, file.GetName(), nil)
+	n.file = file
+
+	for i, msg := range file.MessageType {
+		t.addMessage(n, msg, appendPath(nil, pathFileMessage, int32(i)))
+	}
+	for i, enum := range file.EnumType {
+		t.addEnum(n, enum, appendPath(nil, pathFileEnum, int32(i)))
+	}
+	for i, field := range file.Extension {
+		t.add(n, KindExtension, field, field.GetName(), appendPath(nil, pathFileExtension, int32(i)))
+	}
+	for i, svc := range file.Service {
+		t.addService(n, svc, appendPath(nil, pathFileService, int32(i)))
+	}
+	return n
+}
+
+func (t *selTree) addMessage(parent *selNode, msg *descriptorpb.DescriptorProto, path []int32) {
+	n := t.add(parent, KindMessage, msg, msg.GetName(), path)
+	t.messages[n.fullName] = n
+
+	fields := make([]*selNode, len(msg.Field))
+	for i, field := range msg.Field {
+		fields[i] = t.add(n, KindField, field, field.GetName(), appendPath(path, pathMessageField, int32(i)))
+	}
+	oneofs := make([]*selNode, len(msg.OneofDecl))
+	for i, oneof := range msg.OneofDecl {
+		oneofs[i] = t.add(n, KindOneof, oneof, oneof.GetName(), appendPath(path, pathMessageOneof, int32(i)))
+	}
+	for i, field := range msg.Field {
+		if index := int(field.GetOneofIndex()); isRealOneofMember(field) && index < len(oneofs) {
+			fields[i].oneof = oneofs[index]
+			oneofs[index].members = append(oneofs[index].members, fields[i])
+		}
+	}
+	for i, nested := range msg.NestedType {
+		t.addMessage(n, nested, appendPath(path, pathMessageNested, int32(i)))
+	}
+	for i, enum := range msg.EnumType {
+		t.addEnum(n, enum, appendPath(path, pathMessageEnum, int32(i)))
+	}
+	for i, field := range msg.Extension {
+		t.add(n, KindExtension, field, field.GetName(), appendPath(path, pathMessageExtension, int32(i)))
+	}
+}
+
+func (t *selTree) addEnum(parent *selNode, enum *descriptorpb.EnumDescriptorProto, path []int32) {
+	n := t.add(parent, KindEnum, enum, enum.GetName(), path)
+	for i, value := range enum.Value {
+		// enum values are scoped as siblings of their enum
+		v := t.add(n, KindValue, value, value.GetName(), appendPath(path, pathEnumValue, int32(i)))
+		v.fullName = joinName(parent.scope(), value.GetName())
+	}
+}
+
+func (t *selTree) addService(parent *selNode, svc *descriptorpb.ServiceDescriptorProto, path []int32) {
+	n := t.add(parent, KindService, svc, svc.GetName(), path)
+	for i, method := range svc.Method {
+		t.add(n, KindMethod, method, method.GetName(), appendPath(path, pathServiceMethod, int32(i)))
+	}
+}
+
+// scope returns the full name children of the node are declared in.
+func (n *selNode) scope() string {
+	if n.kind == KindFile {
+		return n.file.GetPackage()
+	}
+	return n.fullName
+}
+
+// parents returns the parent of the node, and its oneof when it has one.
+func (n *selNode) parents() []*selNode {
+	switch {
+	case n.parent == nil:
+		return nil
+	case n.oneof != nil:
+		return []*selNode{n.oneof, n.parent}
+	default:
+		return []*selNode{n.parent}
+	}
+}
+
+// walk calls fn for the node and its descendants in declaration order.
+func (n *selNode) walk(fn func(*selNode)) {
+	fn(n)
+	for _, c := range n.children {
+		c.walk(fn)
+	}
+}
+
+// message returns the message node of a type name, if known.
+func (t *selTree) message(typeName string) (*selNode, bool) {
+	n, ok := t.messages[registryKey(typeName)]
+	return n, ok
+}
+
+// extension returns the field declaring an extension, if known.
+func (t *selTree) extension(name string) (*descriptorpb.FieldDescriptorProto, bool) {
+	if n, ok := t.extensions[registryKey(name)]; ok {
+		field, ok := n.desc.(*descriptorpb.FieldDescriptorProto)
+		return field, ok
+	}
+	return nil, false
+}
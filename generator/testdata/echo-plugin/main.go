@@ -0,0 +1,77 @@
+// Package main is a protoc plugin used to test PluginRunner. It lists
+// the messages of each file to generate, and misbehaves on request
+// through the parameter.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func main() {
+	in, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fatal(err)
+	}
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(in, req); err != nil {
+		fatal(err)
+	}
+
+	switch req.GetParameter() {
+	case "fail":
+		fmt.Fprintln(os.Stderr, "boom")
+		os.Exit(3)
+	case "sleep":
+		time.Sleep(time.Minute)
+	case "garbage":
+		_, _ = os.Stdout.WriteString("not a response")
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "noise")
+	out, err := proto.Marshal(generate(req))
+	if err != nil {
+		fatal(err)
+	}
+	_, _ = os.Stdout.Write(out)
+}
+
+func generate(req *pluginpb.CodeGeneratorRequest) *pluginpb.CodeGeneratorResponse {
+	resp := &pluginpb.CodeGeneratorResponse{}
+	for _, file := range req.ProtoFile {
+		if !contains(req.FileToGenerate, file.GetName()) {
+			continue
+		}
+
+		var sb strings.Builder
+		for _, msg := range file.MessageType {
+			_, _ = sb.WriteString(msg.GetName() + "\n")
+		}
+		resp.File = append(resp.File, &pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String(strings.TrimSuffix(file.GetName(), ".proto") + ".txt"),
+			Content: proto.String(sb.String()),
+		})
+	}
+	return resp
+}
+
+func contains(names []string, name string) bool {
+	for _, s := range names {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -77,7 +77,8 @@ func NewEnumField(name string, number int32, typeName string) *descriptorpb.Fiel
 
 // NewMapField creates a map field descriptor.
 // Note: This creates a repeated message field with the given entry type name.
-// The actual map entry message with map_entry option should be defined separately.
+// The actual map entry message with map_entry option should be defined separately,
+// or use MessageBuilder.Map to create both.
 func NewMapField(name string, number int32, entryTypeName string) *descriptorpb.FieldDescriptorProto {
 	label := LabelRepeated
 	msgType := TypeMessage
@@ -105,6 +106,74 @@ func NewOneOfField(name string, number int32,
 	}
 }
 
+// NewExtensionField creates an extension field descriptor.
+// Returns a FieldDescriptorProto with LABEL_OPTIONAL extending the given message.
+func NewExtensionField(name string, number int32,
+	fieldType descriptorpb.FieldDescriptorProto_Type, extendee string) *descriptorpb.FieldDescriptorProto {
+	field := NewField(name, number, fieldType)
+	field.Extendee = proto.String(extendee)
+	return field
+}
+
+// NewMessageExtensionField creates a message type extension field descriptor.
+// Returns a FieldDescriptorProto with TYPE_MESSAGE extending the given message.
+func NewMessageExtensionField(name string, number int32,
+	typeName, extendee string) *descriptorpb.FieldDescriptorProto {
+	field := NewMessageField(name, number, typeName)
+	field.Extendee = proto.String(extendee)
+	return field
+}
+
+// NewExtensionRange creates an extension range from start to end, exclusive.
+// Returns a DescriptorProto_ExtensionRange as stored in descriptors.
+func NewExtensionRange(start, end int32) *descriptorpb.DescriptorProto_ExtensionRange {
+	return &descriptorpb.DescriptorProto_ExtensionRange{
+		Start: proto.Int32(start),
+		End:   proto.Int32(end),
+	}
+}
+
+// NewMessageWithExtensionRanges creates an extendable message descriptor.
+// Returns a DescriptorProto with the provided extension ranges and fields.
+func NewMessageWithExtensionRanges(name string, ranges []*descriptorpb.DescriptorProto_ExtensionRange,
+	fields ...*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	return &descriptorpb.DescriptorProto{
+		Name:           proto.String(name),
+		Field:          fields,
+		ExtensionRange: ranges,
+	}
+}
+
+// NewReservedRange creates a message reserved range from start to end, exclusive.
+// Returns a DescriptorProto_ReservedRange as stored in descriptors.
+func NewReservedRange(start, end int32) *descriptorpb.DescriptorProto_ReservedRange {
+	return &descriptorpb.DescriptorProto_ReservedRange{
+		Start: proto.Int32(start),
+		End:   proto.Int32(end),
+	}
+}
+
+// NewEnumReservedRange creates an enum reserved range from start to end, inclusive.
+// Returns an EnumDescriptorProto_EnumReservedRange as stored in descriptors.
+func NewEnumReservedRange(start, end int32) *descriptorpb.EnumDescriptorProto_EnumReservedRange {
+	return &descriptorpb.EnumDescriptorProto_EnumReservedRange{
+		Start: proto.Int32(start),
+		End:   proto.Int32(end),
+	}
+}
+
+// NewMessageWithReserved creates a message descriptor with reserved numbers and names.
+// Returns a DescriptorProto with the provided reserved ranges, names and fields.
+func NewMessageWithReserved(name string, ranges []*descriptorpb.DescriptorProto_ReservedRange,
+	names []string, fields ...*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	return &descriptorpb.DescriptorProto{
+		Name:          proto.String(name),
+		Field:         fields,
+		ReservedRange: ranges,
+		ReservedName:  names,
+	}
+}
+
 // NewMessage creates a message descriptor with the given name.
 // Returns a DescriptorProto with the provided fields.
 func NewMessage(name string, fields ...*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
@@ -419,3 +419,61 @@ func TestNewRepeatedMessageField(t *testing.T) {
 	core.AssertNotNil(t, mapField.TypeName, "map field type name")
 	core.AssertEqual(t, ".MapEntry", *mapField.TypeName, "map field type name value")
 }
+
+// Test NewExtensionField function
+func TestNewExtensionField(t *testing.T) {
+	field := NewExtensionField("ext", 100, TypeInt32, ".example.Base")
+
+	core.AssertNotNil(t, field, "field")
+	core.AssertEqual(t, "ext", field.GetName(), "field name")
+	core.AssertEqual(t, int32(100), field.GetNumber(), "field number")
+	core.AssertEqual(t, descriptorpb.FieldDescriptorProto_TYPE_INT32, field.GetType(), "field type")
+	core.AssertEqual(t, ".example.Base", field.GetExtendee(), "field extendee")
+}
+
+// Test NewMessageExtensionField function
+func TestNewMessageExtensionField(t *testing.T) {
+	field := NewMessageExtensionField("ext", 101, ".example.Value", ".example.Base")
+
+	core.AssertNotNil(t, field, "field")
+	core.AssertEqual(t, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, field.GetType(), "field type")
+	core.AssertEqual(t, ".example.Value", field.GetTypeName(), "field type name")
+	core.AssertEqual(t, ".example.Base", field.GetExtendee(), "field extendee")
+}
+
+// Test NewMessageWithExtensionRanges function
+func TestNewMessageWithExtensionRanges(t *testing.T) {
+	msg := NewMessageWithExtensionRanges("Base",
+		[]*descriptorpb.DescriptorProto_ExtensionRange{NewExtensionRange(100, 200)},
+		NewField("id", 1, TypeString))
+
+	core.AssertNotNil(t, msg, "message")
+	core.AssertEqual(t, "Base", msg.GetName(), "message name")
+	core.AssertEqual(t, 1, len(msg.Field), "field count")
+	core.AssertEqual(t, 1, len(msg.ExtensionRange), "range count")
+	core.AssertEqual(t, int32(100), msg.ExtensionRange[0].GetStart(), "range start")
+	core.AssertEqual(t, int32(200), msg.ExtensionRange[0].GetEnd(), "range end")
+}
+
+// Test NewMessageWithReserved function
+func TestNewMessageWithReserved(t *testing.T) {
+	msg := NewMessageWithReserved("Msg",
+		[]*descriptorpb.DescriptorProto_ReservedRange{NewReservedRange(5, 10)},
+		[]string{"old"}, NewField("id", 1, TypeString))
+
+	core.AssertNotNil(t, msg, "message")
+	core.AssertEqual(t, "Msg", msg.GetName(), "message name")
+	core.AssertEqual(t, 1, len(msg.Field), "field count")
+	core.AssertEqual(t, int32(5), msg.ReservedRange[0].GetStart(), "range start")
+	core.AssertEqual(t, int32(10), msg.ReservedRange[0].GetEnd(), "range end")
+	core.AssertSliceEqual(t, []string{"old"}, msg.ReservedName, "reserved names")
+}
+
+// Test NewEnumReservedRange function
+func TestNewEnumReservedRange(t *testing.T) {
+	r := NewEnumReservedRange(3, 7)
+
+	core.AssertNotNil(t, r, "range")
+	core.AssertEqual(t, int32(3), r.GetStart(), "range start")
+	core.AssertEqual(t, int32(7), r.GetEnd(), "range end")
+}
@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
@@ -39,3 +40,106 @@ const (
 	LabelRequired = descriptorpb.FieldDescriptorProto_LABEL_REQUIRED
 	LabelRepeated = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
 )
+
+// Wire type aliases for the encoding of fields on the wire
+const (
+	WireVarint     = protowire.VarintType
+	WireFixed32    = protowire.Fixed32Type
+	WireFixed64    = protowire.Fixed64Type
+	WireBytes      = protowire.BytesType
+	WireStartGroup = protowire.StartGroupType
+	WireEndGroup   = protowire.EndGroupType
+)
+
+// Encoding classifies how the values of a field type are encoded.
+type Encoding int
+
+// Encoding categories of field types
+const (
+	// EncodingVarint is a plain varint, used by int32, int64, uint32,
+	// uint64, bool and enum fields.
+	EncodingVarint Encoding = iota + 1
+	// EncodingZigZag is a zigzag-encoded varint, used by sint32 and sint64.
+	EncodingZigZag
+	// EncodingFixed32 is a little-endian 32-bit value, used by fixed32,
+	// sfixed32 and float.
+	EncodingFixed32
+	// EncodingFixed64 is a little-endian 64-bit value, used by fixed64,
+	// sfixed64 and double.
+	EncodingFixed64
+	// EncodingBytes is a length-prefixed value, used by string, bytes
+	// and message fields.
+	EncodingBytes
+	// EncodingDelimited is a value enclosed by start and end group tags,
+	// used by groups and delimited messages.
+	EncodingDelimited
+)
+
+// String returns the name of the encoding.
+func (e Encoding) String() string {
+	switch e {
+	case EncodingVarint:
+		return "varint"
+	case EncodingZigZag:
+		return "zigzag"
+	case EncodingFixed32:
+		return "fixed32"
+	case EncodingFixed64:
+		return "fixed64"
+	case EncodingBytes:
+		return "bytes"
+	case EncodingDelimited:
+		return "delimited"
+	default:
+		return "invalid"
+	}
+}
+
+// WireType returns the wire type of a single value with this encoding.
+func (e Encoding) WireType() protowire.Type {
+	switch e {
+	case EncodingVarint, EncodingZigZag:
+		return WireVarint
+	case EncodingFixed32:
+		return WireFixed32
+	case EncodingFixed64:
+		return WireFixed64
+	case EncodingDelimited:
+		return WireStartGroup
+	default:
+		return WireBytes
+	}
+}
+
+// TypeEncoding returns the encoding of a field type, ignoring the
+// features that turn messages into delimited ones. Unknown types
+// return zero.
+func TypeEncoding(t descriptorpb.FieldDescriptorProto_Type) Encoding {
+	switch t {
+	case TypeInt32, TypeInt64, TypeUInt32, TypeUInt64, TypeBool, TypeEnum:
+		return EncodingVarint
+	case TypeSInt32, TypeSInt64:
+		return EncodingZigZag
+	case TypeFixed32, TypeSFixed32, TypeFloat:
+		return EncodingFixed32
+	case TypeFixed64, TypeSFixed64, TypeDouble:
+		return EncodingFixed64
+	case TypeString, TypeBytes, TypeMessage:
+		return EncodingBytes
+	case TypeGroup:
+		return EncodingDelimited
+	default:
+		return 0
+	}
+}
+
+// IsPackable tells if repeated fields of a type can use the packed
+// encoding, which is every scalar type but strings and bytes.
+func IsPackable(t descriptorpb.FieldDescriptorProto_Type) bool {
+	switch TypeEncoding(t) {
+	case EncodingVarint, EncodingZigZag, EncodingFixed32, EncodingFixed64:
+		return true
+	default:
+		return false
+	}
+}
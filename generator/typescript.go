@@ -0,0 +1,267 @@
+package generator
+
+import (
+	"strconv"
+	"strings"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// TSInt64Mode selects the TypeScript type of 64-bit integer fields.
+type TSInt64Mode int
+
+const (
+	// TSInt64BigInt maps 64-bit integers to bigint.
+	TSInt64BigInt TSInt64Mode = iota
+	// TSInt64String maps 64-bit integers to string, as in the proto3
+	// JSON mapping.
+	TSInt64String
+)
+
+// TSEnumStyle selects how enums are declared in TypeScript.
+type TSEnumStyle int
+
+const (
+	// TSEnumStyleEnum declares a TypeScript enum of the value numbers.
+	TSEnumStyleEnum TSEnumStyle = iota
+	// TSEnumStyleConstEnum declares a const enum, inlined by the compiler.
+	TSEnumStyleConstEnum
+	// TSEnumStyleUnion declares a union of the value names, as in the
+	// proto3 JSON mapping.
+	TSEnumStyleUnion
+	// TSEnumStyleObject declares a const object of the value numbers and
+	// a type of its values.
+	TSEnumStyleObject
+)
+
+// TSOptions controls the TypeScript generated from descriptors.
+type TSOptions struct {
+	// Int64 is the type of 64-bit integer fields.
+	Int64 TSInt64Mode
+	// EnumStyle is how enums are declared.
+	EnumStyle TSEnumStyle
+	// TrimEnumPrefix removes the conventional prefix from the member
+	// names of enums whose values all have it.
+	TrimEnumPrefix bool
+	// ModuleSuffix is appended to the base name of generated modules,
+	// as in "_pb".
+	ModuleSuffix string
+	// ImportExtension is appended to import paths, as the ".js" ES
+	// modules require.
+	ImportExtension string
+}
+
+// tsReserved are the words that can't be used as TypeScript type or
+// value identifiers, including the predefined type names.
+var tsReserved = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true,
+	"continue": true, "debugger": true, "default": true, "delete": true,
+	"do": true, "else": true, "enum": true, "export": true, "extends": true,
+	"false": true, "finally": true, "for": true, "function": true, "if": true,
+	"import": true, "in": true, "instanceof": true, "new": true, "null": true,
+	"return": true, "super": true, "switch": true, "this": true, "throw": true,
+	"true": true, "try": true, "typeof": true, "var": true, "void": true,
+	"while": true, "with": true,
+
+	// strict mode
+	"implements": true, "interface": true, "let": true, "package": true,
+	"private": true, "protected": true, "public": true, "static": true,
+	"yield": true, "await": true, "arguments": true, "eval": true,
+
+	// predefined types
+	"any": true, "bigint": true, "boolean": true, "never": true,
+	"number": true, "object": true, "string": true, "symbol": true,
+	"undefined": true, "unknown": true,
+}
+
+// IsTSReserved tells if a name can't be used as a TypeScript identifier.
+func IsTSReserved(name string) bool {
+	return tsReserved[name]
+}
+
+// TSIdentifier returns a name usable as a TypeScript identifier,
+// appending an underscore to reserved words.
+func TSIdentifier(name string) string {
+	if IsTSReserved(name) {
+		return name + "_"
+	}
+	return name
+}
+
+// TSTypeName returns the TypeScript name of a message or enum given its
+// full name, with or without leading dot, and the package declaring it.
+// Nested types are joined to their parents with underscores, as in
+// Outer_Inner, the names TypeScript generators commonly use. Names that
+// already contain underscores can collide this way, A.B_C and A_B.C
+// both giving A_B_C, and callers mixing such names must tell them
+// apart.
+func TSTypeName(pkg, fullName string) string {
+	name := registryKey(fullName)
+	if pkg != "" {
+		name = strings.TrimPrefix(name, pkg+".")
+	}
+	return TSIdentifier(strings.ReplaceAll(name, ".", "_"))
+}
+
+// TSFieldName returns the lowerCamelCase property name of a field.
+func TSFieldName(field *descriptorpb.FieldDescriptorProto) string {
+	return JSONName(field.GetName())
+}
+
+// ScalarType returns the TypeScript type of a scalar field type, false
+// for enums, messages and groups.
+func (o TSOptions) ScalarType(t descriptorpb.FieldDescriptorProto_Type) (string, bool) {
+	switch t {
+	case TypeDouble, TypeFloat, TypeInt32, TypeUInt32, TypeSInt32, TypeFixed32, TypeSFixed32:
+		return "number", true
+	case TypeInt64, TypeUInt64, TypeSInt64, TypeFixed64, TypeSFixed64:
+		if o.Int64 == TSInt64String {
+			return "string", true
+		}
+		return "bigint", true
+	case TypeBool:
+		return "boolean", true
+	case TypeString:
+		return "string", true
+	case TypeBytes:
+		return "Uint8Array", true
+	default:
+		return "", false
+	}
+}
+
+// FieldType returns the TypeScript type of a field, resolving message
+// and enum types in the registry. Repeated fields are arrays and map
+// fields objects keyed by string.
+func (o TSOptions) FieldType(r *Registry, field *descriptorpb.FieldDescriptorProto) (string, error) {
+	if field.GetLabel() == LabelRepeated && field.GetType() == TypeMessage {
+		entry, ok := r.Message(field.GetTypeName())
+		if ok && entry.GetOptions().GetMapEntry() && len(entry.Field) == 2 {
+			v, err := o.FieldType(r, entry.Field[1])
+			if err != nil {
+				return "", err
+			}
+			return "{ [key: string]: " + v + " }", nil
+		}
+	}
+
+	t, err := o.singleType(r, field)
+	if err != nil {
+		return "", err
+	}
+	if field.GetLabel() == LabelRepeated {
+		return t + "[]", nil
+	}
+	return t, nil
+}
+
+func (o TSOptions) singleType(r *Registry, field *descriptorpb.FieldDescriptorProto) (string, error) {
+	if t, ok := o.ScalarType(field.GetType()); ok {
+		return t, nil
+	}
+
+	name := registryKey(field.GetTypeName())
+	var found bool
+	switch field.GetType() {
+	case TypeEnum:
+		_, found = r.Enum(name)
+	default:
+		_, found = r.Message(name)
+	}
+	if !found {
+		return "", core.Wrapf(core.ErrNotExists, "field %q: type %q not found", field.GetName(), name)
+	}
+
+	fileName, _ := r.FileOf(name)
+	file, _ := r.File(fileName)
+	return TSTypeName(file.GetPackage(), name), nil
+}
+
+// Enum returns the TypeScript declaration of an enum declared in the
+// given file, in the selected style.
+func (o TSOptions) Enum(file *descriptorpb.FileDescriptorProto,
+	enum *descriptorpb.EnumDescriptorProto) (string, error) {
+	info, err := NewEnumInfo(file, enum)
+	if err != nil {
+		return "", err
+	}
+
+	fullName := file.GetPackage()
+	for _, msg := range enumScope(file, enum) {
+		fullName = joinName(fullName, msg.GetName())
+	}
+	name := TSTypeName(file.GetPackage(), joinName(fullName, enum.GetName()))
+
+	var sb strings.Builder
+	switch o.EnumStyle {
+	case TSEnumStyleUnion:
+		o.writeEnumUnion(&sb, name, info)
+	case TSEnumStyleObject:
+		o.writeEnumMembers(&sb, "export const "+name+" = {", "} as const;", ": ", info)
+		_, _ = sb.WriteString("\nexport type " + name + " = (typeof " + name + ")[keyof typeof " + name + "];\n")
+	case TSEnumStyleConstEnum:
+		o.writeEnumMembers(&sb, "export const enum "+name+" {", "}", " = ", info)
+	default:
+		o.writeEnumMembers(&sb, "export enum "+name+" {", "}", " = ", info)
+	}
+	return sb.String(), nil
+}
+
+func (o TSOptions) enumMember(info *EnumInfo, name string) string {
+	if o.TrimEnumPrefix {
+		return info.TrimPrefix(name)
+	}
+	return name
+}
+
+func (o TSOptions) writeEnumMembers(sb *strings.Builder, open, closing, sep string, info *EnumInfo) {
+	_, _ = sb.WriteString(open + "\n")
+	for _, v := range info.Enum.Value {
+		member := o.enumMember(info, v.GetName())
+		_, _ = sb.WriteString("  " + member + sep + strconv.Itoa(int(v.GetNumber())) + ",\n")
+	}
+	_, _ = sb.WriteString(closing + "\n")
+}
+
+func (o TSOptions) writeEnumUnion(sb *strings.Builder, name string, info *EnumInfo) {
+	members := make([]string, 0, len(info.Enum.Value))
+	for _, v := range info.Enum.Value {
+		members = append(members, strconv.Quote(o.enumMember(info, v.GetName())))
+	}
+	if len(members) == 0 {
+		members = append(members, "never")
+	}
+	_, _ = sb.WriteString("export type " + name + " = " + strings.Join(members, " | ") + ";\n")
+}
+
+// ModulePath returns the path of the TypeScript module generated for a
+// .proto file.
+func (o TSOptions) ModulePath(protoFile string) string {
+	return strings.TrimSuffix(protoFile, ".proto") + o.ModuleSuffix + ".ts"
+}
+
+// ImportPath returns the relative path the module generated for the
+// from .proto file uses to import the one generated for the to file.
+func (o TSOptions) ImportPath(from, to string) string {
+	fromDir := strings.Split(from, "/")
+	fromDir = fromDir[:len(fromDir)-1]
+	toParts := strings.Split(strings.TrimSuffix(to, ".proto")+o.ModuleSuffix, "/")
+	toDir, base := toParts[:len(toParts)-1], toParts[len(toParts)-1]
+
+	common := 0
+	for common < len(fromDir) && common < len(toDir) && fromDir[common] == toDir[common] {
+		common++
+	}
+
+	parts := make([]string, 0, len(fromDir)+len(toDir)+2)
+	if common == len(fromDir) {
+		parts = append(parts, ".")
+	}
+	for range len(fromDir) - common {
+		parts = append(parts, "..")
+	}
+	parts = append(parts, toDir[common:]...)
+	parts = append(parts, base)
+	return strings.Join(parts, "/") + o.ImportExtension
+}
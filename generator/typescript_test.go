@@ -0,0 +1,183 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestTSIdentifier(t *testing.T) {
+	for _, tc := range []struct{ name, expected string }{
+		{"Item", "Item"},
+		{"delete", "delete_"},
+		{"string", "string_"},
+		{"await", "await_"},
+		{"String", "String"},
+	} {
+		core.AssertEqual(t, tc.expected, TSIdentifier(tc.name), tc.name)
+	}
+	core.AssertTrue(t, IsTSReserved("class"), "class")
+	core.AssertFalse(t, IsTSReserved("Class"), "Class")
+}
+
+func TestTSTypeName(t *testing.T) {
+	for _, tc := range []struct{ pkg, name, expected string }{
+		{"pkg", ".pkg.Item", "Item"},
+		{"pkg", "pkg.Outer.Inner", "Outer_Inner"},
+		{"a.b", ".a.b.Outer.Mid.Inner", "Outer_Mid_Inner"},
+		{"", ".Item", "Item"},
+		{"pkg", ".pkg.object", "object_"},
+	} {
+		core.AssertEqual(t, tc.expected, TSTypeName(tc.pkg, tc.name), tc.name)
+	}
+	core.AssertEqual(t, "userId", TSFieldName(NewField("user_id", 1, TypeString)), "field name")
+}
+
+func TestTSScalarType(t *testing.T) {
+	big, str := TSOptions{}, TSOptions{Int64: TSInt64String}
+	for _, tc := range []struct {
+		t             descriptorpb.FieldDescriptorProto_Type
+		bigint, strTS string
+	}{
+		{TypeDouble, "number", "number"},
+		{TypeFloat, "number", "number"},
+		{TypeInt32, "number", "number"},
+		{TypeUInt32, "number", "number"},
+		{TypeSInt32, "number", "number"},
+		{TypeFixed32, "number", "number"},
+		{TypeSFixed32, "number", "number"},
+		{TypeInt64, "bigint", "string"},
+		{TypeUInt64, "bigint", "string"},
+		{TypeSInt64, "bigint", "string"},
+		{TypeFixed64, "bigint", "string"},
+		{TypeSFixed64, "bigint", "string"},
+		{TypeBool, "boolean", "boolean"},
+		{TypeString, "string", "string"},
+		{TypeBytes, "Uint8Array", "Uint8Array"},
+	} {
+		got, ok := big.ScalarType(tc.t)
+		core.AssertTrue(t, ok, tc.t.String())
+		core.AssertEqual(t, tc.bigint, got, tc.t.String())
+		got, _ = str.ScalarType(tc.t)
+		core.AssertEqual(t, tc.strTS, got, tc.t.String()+" as string")
+	}
+
+	for _, typ := range []descriptorpb.FieldDescriptorProto_Type{TypeEnum, TypeMessage, TypeGroup} {
+		_, ok := big.ScalarType(typ)
+		core.AssertFalse(t, ok, typ.String())
+	}
+}
+
+const tsTestProto = `syntax = "proto3";
+
+package shop.v1;
+
+message Order {
+  message Line {
+    enum Kind {
+      KIND_UNSPECIFIED = 0;
+      KIND_ITEM = 1;
+    }
+    Kind kind = 1;
+  }
+  repeated Line lines = 1;
+  map<string, int64> totals = 2;
+  map<int32, Line> by_number = 3;
+  Status status = 4;
+  uint64 id = 5;
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_OPEN = 1;
+  STATUS_CLOSED = 2;
+}
+`
+
+func TestTSFieldType(t *testing.T) {
+	file, err := ParseProto("shop/v1/order.proto", tsTestProto)
+	core.AssertMustNoError(t, err, "ParseProto")
+	r := NewRegistry(file)
+	order, _ := r.Message("shop.v1.Order")
+	line, _ := r.Message("shop.v1.Order.Line")
+
+	opts := TSOptions{}
+	for _, tc := range []struct {
+		field    *descriptorpb.FieldDescriptorProto
+		expected string
+	}{
+		{order.Field[0], "Order_Line[]"},
+		{order.Field[1], "{ [key: string]: bigint }"},
+		{order.Field[2], "{ [key: string]: Order_Line }"},
+		{order.Field[3], "Status"},
+		{order.Field[4], "bigint"},
+		{line.Field[0], "Order_Line_Kind"},
+	} {
+		got, err := opts.FieldType(r, tc.field)
+		core.AssertNoError(t, err, tc.field.GetName())
+		core.AssertEqual(t, tc.expected, got, tc.field.GetName())
+	}
+
+	got, err := TSOptions{Int64: TSInt64String}.FieldType(r, order.Field[1])
+	core.AssertNoError(t, err, "string int64")
+	core.AssertEqual(t, "{ [key: string]: string }", got, "string int64")
+
+	_, err = opts.FieldType(r, NewMessageField("missing", 1, ".shop.v1.Missing"))
+	core.AssertErrorIs(t, err, core.ErrNotExists, "unknown type")
+}
+
+func TestTSEnum(t *testing.T) {
+	file, err := ParseProto("shop/v1/order.proto", tsTestProto)
+	core.AssertMustNoError(t, err, "ParseProto")
+	status := file.EnumType[0]
+
+	for _, tc := range []struct {
+		name     string
+		opts     TSOptions
+		expected string
+	}{
+		{"enum", TSOptions{}, "export enum Status {\n" +
+			"  STATUS_UNSPECIFIED = 0,\n  STATUS_OPEN = 1,\n  STATUS_CLOSED = 2,\n}\n"},
+		{"const", TSOptions{EnumStyle: TSEnumStyleConstEnum, TrimEnumPrefix: true}, "export const enum Status {\n" +
+			"  UNSPECIFIED = 0,\n  OPEN = 1,\n  CLOSED = 2,\n}\n"},
+		{"union", TSOptions{EnumStyle: TSEnumStyleUnion},
+			`export type Status = "STATUS_UNSPECIFIED" | "STATUS_OPEN" | "STATUS_CLOSED";` + "\n"},
+		{"object", TSOptions{EnumStyle: TSEnumStyleObject, TrimEnumPrefix: true}, "export const Status = {\n" +
+			"  UNSPECIFIED: 0,\n  OPEN: 1,\n  CLOSED: 2,\n} as const;\n" +
+			"\nexport type Status = (typeof Status)[keyof typeof Status];\n"},
+	} {
+		got, err := tc.opts.Enum(file, status)
+		core.AssertNoError(t, err, tc.name)
+		core.AssertEqual(t, tc.expected, got, tc.name)
+	}
+
+	kind := file.MessageType[0].NestedType[0].EnumType[0]
+	got, err := TSOptions{EnumStyle: TSEnumStyleUnion}.Enum(file, kind)
+	core.AssertNoError(t, err, "nested")
+	core.AssertEqual(t, `export type Order_Line_Kind = "KIND_UNSPECIFIED" | "KIND_ITEM";`+"\n", got, "nested")
+
+	_, err = TSOptions{}.Enum(file, nil)
+	core.AssertErrorIs(t, err, core.ErrInvalid, "nil enum")
+}
+
+func TestTSImportPath(t *testing.T) {
+	plain := TSOptions{}
+	esm := TSOptions{ModuleSuffix: "_pb", ImportExtension: ".js"}
+	for _, tc := range []struct {
+		from, to, plain, esm string
+	}{
+		{"a.proto", "b.proto", "./b", "./b_pb.js"},
+		{"shop/v1/order.proto", "shop/v1/item.proto", "./item", "./item_pb.js"},
+		{"shop/v1/order.proto", "shop/common.proto", "../common", "../common_pb.js"},
+		{"shop/v1/order.proto", "google/protobuf/timestamp.proto",
+			"../../google/protobuf/timestamp", "../../google/protobuf/timestamp_pb.js"},
+		{"order.proto", "shop/v1/item.proto", "./shop/v1/item", "./shop/v1/item_pb.js"},
+	} {
+		core.AssertEqual(t, tc.plain, plain.ImportPath(tc.from, tc.to), tc.from+" -> "+tc.to)
+		core.AssertEqual(t, tc.esm, esm.ImportPath(tc.from, tc.to), tc.from+" -> "+tc.to+" esm")
+	}
+
+	core.AssertEqual(t, "shop/v1/order.ts", plain.ModulePath("shop/v1/order.proto"), "module")
+	core.AssertEqual(t, "shop/v1/order_pb.ts", esm.ModulePath("shop/v1/order.proto"), "module suffix")
+}
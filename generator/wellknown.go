@@ -0,0 +1,60 @@
+package generator
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	// Register the well-known types bundled with the parser
+	_ "google.golang.org/protobuf/types/gofeaturespb"
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/apipb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+	_ "google.golang.org/protobuf/types/known/sourcecontextpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/typepb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+	_ "google.golang.org/protobuf/types/pluginpb"
+)
+
+// wellKnownPrefix is the import path prefix of the bundled files.
+const wellKnownPrefix = "google/protobuf/"
+
+// IsWellKnownFile tells if the import path refers to one of the
+// well-known .proto files bundled with this package.
+func IsWellKnownFile(name string) bool {
+	_, ok := wellKnownFile(name)
+	return ok
+}
+
+// WellKnownFile returns the descriptor of a bundled well-known .proto file,
+// such as "google/protobuf/timestamp.proto".
+func WellKnownFile(name string) (*descriptorpb.FileDescriptorProto, bool) {
+	fd, ok := wellKnownFile(name)
+	if !ok {
+		return nil, false
+	}
+	return wellKnownFileProto(fd), true
+}
+
+func wellKnownFile(name string) (protoreflect.FileDescriptor, bool) {
+	if !strings.HasPrefix(name, wellKnownPrefix) {
+		return nil, false
+	}
+
+	fd, err := protoregistry.GlobalFiles.FindFileByPath(name)
+	if err != nil {
+		return nil, false
+	}
+	return fd, true
+}
+
+func wellKnownFileProto(fd protoreflect.FileDescriptor) *descriptorpb.FileDescriptorProto {
+	return protodesc.ToFileDescriptorProto(fd)
+}
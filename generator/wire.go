@@ -0,0 +1,108 @@
+package generator
+
+import (
+	"strings"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// FieldWire is the wire-format metadata of a field, for hand-written
+// encoders.
+type FieldWire struct {
+	// Tag is the encoded tag preceding each value, or the whole
+	// record when packed.
+	Tag []byte
+	// EndTag is the encoded end-group tag closing delimited values.
+	EndTag []byte
+	// Encoding is the encoding of each value.
+	Encoding Encoding
+	// Number is the field number.
+	Number protowire.Number
+	// WireType is the wire type of Tag, WireBytes when packed.
+	WireType protowire.Type
+	// Packed is set on repeated scalar fields encoded as a single
+	// length-prefixed record.
+	Packed bool
+}
+
+// IsDelimited tells if the values are enclosed by group tags.
+func (w FieldWire) IsDelimited() bool {
+	return w.Encoding == EncodingDelimited
+}
+
+// NewFieldWire computes the wire-format metadata of a field or
+// extension declared in the given file, using its syntax or resolved
+// edition features to decide on packed and delimited encodings.
+func NewFieldWire(file *descriptorpb.FileDescriptorProto,
+	field *descriptorpb.FieldDescriptorProto) (FieldWire, error) {
+	if field == nil {
+		return FieldWire{}, core.Wrap(core.ErrInvalid, "nil field")
+	}
+
+	num := protowire.Number(field.GetNumber())
+	if !num.IsValid() {
+		return FieldWire{}, core.Wrapf(core.ErrInvalid, "field %q: invalid number %d", field.GetName(), num)
+	}
+
+	enc := TypeEncoding(field.GetType())
+	if field.Type == nil || enc == 0 {
+		return FieldWire{}, core.Wrapf(core.ErrInvalid, "field %q: invalid type %v", field.GetName(), field.GetType())
+	}
+
+	fs := FieldFeatures(file, field)
+	if field.GetType() == TypeMessage && !isMapField(file, field) &&
+		fs.GetMessageEncoding() == descriptorpb.FeatureSet_DELIMITED {
+		enc = EncodingDelimited
+	}
+
+	w := FieldWire{
+		Encoding: enc,
+		Number:   num,
+		WireType: enc.WireType(),
+		Packed: field.GetLabel() == LabelRepeated && IsPackable(field.GetType()) &&
+			fs.GetRepeatedFieldEncoding() == descriptorpb.FeatureSet_PACKED,
+	}
+	if w.Packed {
+		w.WireType = WireBytes
+	}
+
+	w.Tag = protowire.AppendTag(nil, num, w.WireType)
+	if w.IsDelimited() {
+		w.EndTag = protowire.AppendTag(nil, num, WireEndGroup)
+	}
+	return w, nil
+}
+
+// isMapField tells if a field refers to a map entry nested in the
+// message declaring it. Map fields are always length-prefixed.
+func isMapField(file *descriptorpb.FileDescriptorProto, field *descriptorpb.FieldDescriptorProto) bool {
+	parents, _ := fieldScope(file, field)
+	if field.GetLabel() != LabelRepeated || len(parents) == 0 {
+		return false
+	}
+	for _, nested := range parents[len(parents)-1].NestedType {
+		if nested.GetOptions().GetMapEntry() &&
+			strings.HasSuffix(field.GetTypeName(), "."+nested.GetName()) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPacked tells if a repeated scalar field declared in the given file
+// uses the packed encoding, by the proto2 packed option, the proto3
+// default, or the editions repeated_field_encoding feature.
+func IsPacked(file *descriptorpb.FileDescriptorProto, field *descriptorpb.FieldDescriptorProto) bool {
+	w, err := NewFieldWire(file, field)
+	return err == nil && w.Packed
+}
+
+// IsDelimited tells if a message field declared in the given file uses
+// the group encoding, either as a proto2 group or by the editions
+// message_encoding feature.
+func IsDelimited(file *descriptorpb.FileDescriptorProto, field *descriptorpb.FieldDescriptorProto) bool {
+	w, err := NewFieldWire(file, field)
+	return err == nil && w.IsDelimited()
+}
@@ -0,0 +1,205 @@
+package generator
+
+import (
+	"testing"
+
+	"darvaza.org/core"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const wireTestProto2 = `syntax = "proto2";
+package w;
+message M {
+  optional int32 a = 1;
+  repeated int32 b = 2;
+  repeated sint64 c = 3 [packed = true];
+  repeated string d = 4 [packed = true];
+  optional group G = 5 { optional int32 x = 1; }
+  optional fixed32 e = 6;
+  optional double f = 7;
+  required bytes h = 16;
+}
+`
+
+const wireTestProto3 = `syntax = "proto3";
+package w;
+message M {
+  repeated uint64 a = 1;
+  repeated float b = 2 [packed = false];
+  repeated M c = 3;
+  map<string, M> d = 4;
+  sfixed64 e = 2048;
+}
+`
+
+const wireTestEditions = `edition = "2023";
+package w;
+option features.message_encoding = DELIMITED;
+message M {
+  repeated bool a = 1;
+  repeated sint32 b = 2 [features.repeated_field_encoding = EXPANDED];
+  M c = 3;
+  M d = 4 [features.message_encoding = LENGTH_PREFIXED];
+  map<int32, M> e = 5;
+  message N {
+    option features.repeated_field_encoding = EXPANDED;
+    repeated int64 f = 1;
+  }
+}
+`
+
+type fieldWireTestCase struct {
+	name     string
+	src      string
+	path     []string
+	tag      []byte
+	encoding Encoding
+	wireType protowire.Type
+	packed   bool
+}
+
+func newFieldWireTestCase(name, src string, path []string, encoding Encoding,
+	wireType protowire.Type, packed bool, tag ...byte) fieldWireTestCase {
+	return fieldWireTestCase{
+		name:     name,
+		src:      src,
+		path:     path,
+		tag:      tag,
+		encoding: encoding,
+		wireType: wireType,
+		packed:   packed,
+	}
+}
+
+func (tc fieldWireTestCase) Name() string {
+	return tc.name
+}
+
+func (tc fieldWireTestCase) Test(t *testing.T) {
+	t.Helper()
+	file, err := ParseProto("wire.proto", tc.src)
+	core.AssertMustNoError(t, err, "ParseProto")
+
+	field := findTestField(file.MessageType, tc.path)
+	core.AssertMustNotNil(t, field, "field")
+
+	w, err := NewFieldWire(file, field)
+	core.AssertMustNoError(t, err, "NewFieldWire")
+	core.AssertEqual(t, tc.encoding, w.Encoding, "encoding")
+	core.AssertEqual(t, tc.wireType, w.WireType, "wire type")
+	core.AssertEqual(t, tc.packed, w.Packed, "packed")
+	core.AssertSliceEqual(t, tc.tag, w.Tag, "tag")
+	core.AssertEqual(t, tc.packed, IsPacked(file, field), "IsPacked")
+	core.AssertEqual(t, w.IsDelimited(), IsDelimited(file, field), "IsDelimited")
+}
+
+var _ core.TestCase = fieldWireTestCase{}
+
+// findTestField finds a field by the names of its enclosing messages
+// followed by its own.
+func findTestField(msgs []*descriptorpb.DescriptorProto, path []string) *descriptorpb.FieldDescriptorProto {
+	for _, msg := range msgs {
+		if msg.GetName() != path[0] {
+			continue
+		}
+		if len(path) > 2 {
+			return findTestField(msg.NestedType, path[1:])
+		}
+		for _, field := range msg.Field {
+			if field.GetName() == path[1] {
+				return field
+			}
+		}
+	}
+	return nil
+}
+
+func fieldWireTestCases() []fieldWireTestCase {
+	return []fieldWireTestCase{
+		newFieldWireTestCase("proto2 varint", wireTestProto2, []string{"M", "a"},
+			EncodingVarint, WireVarint, false, 0x08),
+		newFieldWireTestCase("proto2 expanded", wireTestProto2, []string{"M", "b"},
+			EncodingVarint, WireVarint, false, 0x10),
+		newFieldWireTestCase("proto2 packed zigzag", wireTestProto2, []string{"M", "c"},
+			EncodingZigZag, WireBytes, true, 0x1a),
+		newFieldWireTestCase("proto2 string not packable", wireTestProto2, []string{"M", "d"},
+			EncodingBytes, WireBytes, false, 0x22),
+		newFieldWireTestCase("proto2 group", wireTestProto2, []string{"M", "g"},
+			EncodingDelimited, WireStartGroup, false, 0x2b),
+		newFieldWireTestCase("proto2 fixed32", wireTestProto2, []string{"M", "e"},
+			EncodingFixed32, WireFixed32, false, 0x35),
+		newFieldWireTestCase("proto2 double", wireTestProto2, []string{"M", "f"},
+			EncodingFixed64, WireFixed64, false, 0x39),
+		newFieldWireTestCase("proto2 two byte tag", wireTestProto2, []string{"M", "h"},
+			EncodingBytes, WireBytes, false, 0x82, 0x01),
+		newFieldWireTestCase("proto3 packed default", wireTestProto3, []string{"M", "a"},
+			EncodingVarint, WireBytes, true, 0x0a),
+		newFieldWireTestCase("proto3 packed false", wireTestProto3, []string{"M", "b"},
+			EncodingFixed32, WireFixed32, false, 0x15),
+		newFieldWireTestCase("proto3 repeated message", wireTestProto3, []string{"M", "c"},
+			EncodingBytes, WireBytes, false, 0x1a),
+		newFieldWireTestCase("proto3 map", wireTestProto3, []string{"M", "d"},
+			EncodingBytes, WireBytes, false, 0x22),
+		newFieldWireTestCase("proto3 sfixed64", wireTestProto3, []string{"M", "e"},
+			EncodingFixed64, WireFixed64, false, 0x81, 0x80, 0x01),
+		newFieldWireTestCase("editions packed default", wireTestEditions, []string{"M", "a"},
+			EncodingVarint, WireBytes, true, 0x0a),
+		newFieldWireTestCase("editions expanded", wireTestEditions, []string{"M", "b"},
+			EncodingZigZag, WireVarint, false, 0x10),
+		newFieldWireTestCase("editions delimited", wireTestEditions, []string{"M", "c"},
+			EncodingDelimited, WireStartGroup, false, 0x1b),
+		newFieldWireTestCase("editions length-prefixed", wireTestEditions, []string{"M", "d"},
+			EncodingBytes, WireBytes, false, 0x22),
+		newFieldWireTestCase("editions map", wireTestEditions, []string{"M", "e"},
+			EncodingBytes, WireBytes, false, 0x2a),
+		newFieldWireTestCase("editions message feature", wireTestEditions, []string{"M", "N", "f"},
+			EncodingVarint, WireVarint, false, 0x08),
+	}
+}
+
+func TestNewFieldWire(t *testing.T) {
+	core.RunTestCases(t, fieldWireTestCases())
+}
+
+func TestFieldWireEndTag(t *testing.T) {
+	file, err := ParseProto("wire.proto", wireTestProto2)
+	core.AssertMustNoError(t, err, "ParseProto")
+
+	w, err := NewFieldWire(file, findTestField(file.MessageType, []string{"M", "g"}))
+	core.AssertMustNoError(t, err, "NewFieldWire")
+	core.AssertSliceEqual(t, []byte{0x2c}, w.EndTag, "end tag")
+}
+
+func TestNewFieldWireErrors(t *testing.T) {
+	_, err := NewFieldWire(nil, nil)
+	core.AssertErrorIs(t, err, core.ErrInvalid, "nil field")
+
+	_, err = NewFieldWire(nil, &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String("x"),
+		Number: proto.Int32(0),
+		Type:   TypeInt32.Enum(),
+	})
+	core.AssertErrorIs(t, err, core.ErrInvalid, "invalid number")
+
+	_, err = NewFieldWire(nil, &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String("x"),
+		Number: proto.Int32(1),
+	})
+	core.AssertErrorIs(t, err, core.ErrInvalid, "missing type")
+}
+
+func TestTypeEncoding(t *testing.T) {
+	core.AssertEqual(t, EncodingVarint, TypeEncoding(TypeEnum), "enum")
+	core.AssertEqual(t, EncodingZigZag, TypeEncoding(TypeSInt32), "sint32")
+	core.AssertEqual(t, EncodingFixed32, TypeEncoding(TypeFloat), "float")
+	core.AssertEqual(t, EncodingFixed64, TypeEncoding(TypeSFixed64), "sfixed64")
+	core.AssertEqual(t, EncodingBytes, TypeEncoding(TypeMessage), "message")
+	core.AssertEqual(t, EncodingDelimited, TypeEncoding(TypeGroup), "group")
+	core.AssertEqual(t, Encoding(0), TypeEncoding(0), "unknown")
+
+	core.AssertTrue(t, IsPackable(TypeBool), "bool packable")
+	core.AssertFalse(t, IsPackable(TypeBytes), "bytes packable")
+	core.AssertEqual(t, "zigzag", EncodingZigZag.String(), "String")
+}